@@ -0,0 +1,151 @@
+package main
+
+import (
+	reader "alibazlamit/feed-provider/feed-reader"
+	"alibazlamit/feed-provider/logging"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DATA_QUALITY_DAYS = 30
+
+// startArchiveCrawl resumes the archive crawl from its last checkpoint in the background,
+// ?restart=true starts over from the newest page
+func startArchiveCrawl(w http.ResponseWriter, r *http.Request) {
+	restart := r.URL.Query().Get("restart") == "true"
+	checkpoint, err := feedReader.ArchiveStatus(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving archive crawl", err)
+		return
+	}
+	if checkpoint.Running {
+		handleError(w, r, http.StatusConflict, "Archive crawl already in progress", reader.ErrCrawlInProgress)
+		return
+	}
+	// nothing left to crawl or retry until the crawl is restarted
+	if checkpoint.Completed && len(checkpoint.FailedArticleIDs) == 0 && !restart {
+		handleSuccess(w, http.StatusOK, models.CrawlCheckpointResponse{
+			Status: string(models.Success),
+			Data:   *checkpoint,
+		})
+		return
+	}
+
+	// the crawl outlives the request but keeps its request ID in the logs
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		if err := feedReader.CrawlArchive(ctx, restart); err != nil {
+			logger.ErrorContext(ctx, "error crawling archive", "error", err)
+		}
+	}()
+
+	checkpoint.Running = true
+	responseObj := models.CrawlCheckpointResponse{
+		Status: string(models.Success),
+		Data:   *checkpoint,
+	}
+	handleSuccess(w, http.StatusAccepted, responseObj)
+}
+
+// getArchiveCrawl returns the archive crawl's checkpoint
+func getArchiveCrawl(w http.ResponseWriter, r *http.Request) {
+	checkpoint, err := feedReader.ArchiveStatus(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving archive crawl", err)
+		return
+	}
+	responseObj := models.CrawlCheckpointResponse{
+		Status: string(models.Success),
+		Data:   *checkpoint,
+	}
+	handleSuccess(w, http.StatusOK, responseObj)
+}
+
+// getDataQuality summarises the validation violations per feed and day,
+// ?days= sets how far back the report goes
+func getDataQuality(w http.ResponseWriter, r *http.Request) {
+	days := DATA_QUALITY_DAYS
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			handleError(w, r, http.StatusBadRequest, "Invalid days", fmt.Errorf("invalid days %q", value))
+			return
+		}
+		days = parsed
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	buckets, err := qualityRepository.GetViolationBuckets(r.Context(), since)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving violations", err)
+		return
+	}
+	responseObj := models.DataQualityResponse{
+		Status: string(models.Success),
+		Data:   buildDataQualityReport(since, buckets),
+	}
+	handleSuccess(w, http.StatusOK, responseObj)
+}
+
+// buildDataQualityReport folds the buckets, sorted by feed and day, into per feed totals
+func buildDataQualityReport(since time.Time, buckets []models.ViolationBucket) models.DataQualityReport {
+	report := models.DataQualityReport{Since: since, Feeds: []models.DataQualityFeed{}}
+	for _, bucket := range buckets {
+		if len(report.Feeds) == 0 || report.Feeds[len(report.Feeds)-1].Feed != bucket.Feed {
+			report.Feeds = append(report.Feeds, models.DataQualityFeed{
+				Feed:     bucket.Feed,
+				ByRule:   map[string]int{},
+				ByAction: map[models.ValidationPolicy]int{},
+				Days:     []models.DataQualityDay{},
+			})
+		}
+		feed := &report.Feeds[len(report.Feeds)-1]
+		feed.Total += bucket.Count
+		feed.ByRule[bucket.Rule] += bucket.Count
+		feed.ByAction[bucket.Action] += bucket.Count
+
+		if len(feed.Days) == 0 || feed.Days[len(feed.Days)-1].Day != bucket.Day {
+			feed.Days = append(feed.Days, models.DataQualityDay{Day: bucket.Day, ByRule: map[string]int{}})
+		}
+		day := &feed.Days[len(feed.Days)-1]
+		day.Total += bucket.Count
+		day.ByRule[bucket.Rule] += bucket.Count
+	}
+	return report
+}
+
+// getLogLevel returns the level the service currently logs at
+func getLogLevel(w http.ResponseWriter, r *http.Request) {
+	handleSuccess(w, http.StatusOK, logLevelResponse())
+}
+
+// setLogLevel changes the log level until the next restart, LOG_LEVEL sets it at startup
+func setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var request models.LogLevel
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid log level request", err)
+		return
+	}
+	level, err := logging.ParseLevel(request.Level)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "level must be one of debug, info, warn and error", err)
+		return
+	}
+	previous := logLevel.Level()
+	logLevel.Set(level)
+	logger.InfoContext(r.Context(), "log level changed", "from", previous.String(), "to", level.String())
+	handleSuccess(w, http.StatusOK, logLevelResponse())
+}
+
+func logLevelResponse() models.LogLevelResponse {
+	return models.LogLevelResponse{
+		Status: string(models.Success),
+		Data:   models.LogLevel{Level: strings.ToLower(logLevel.Level().String())},
+	}
+}
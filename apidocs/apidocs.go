@@ -0,0 +1,52 @@
+// Package apidocs serves the OpenAPI document of the HTTP API and a docs page that
+// renders it. Both are embedded so the docs work without network access.
+package apidocs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	//go:embed openapi.json
+	spec []byte
+	//go:embed index.html
+	page []byte
+)
+
+// SpecHandler serves the OpenAPI document
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// DocsHandler serves the page rendering the OpenAPI document
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Operations returns the methods documented for each path, e.g. "/articles/{id}": ["GET"]
+func Operations() (map[string][]string, error) {
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &document); err != nil {
+		return nil, err
+	}
+	operations := map[string][]string{}
+	for path, item := range document.Paths {
+		for method := range item {
+			// path items can also hold shared parameters and descriptions
+			switch method {
+			case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+				operations[path] = append(operations[path], strings.ToUpper(method))
+			}
+		}
+		sort.Strings(operations[path])
+	}
+	return operations, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"alibazlamit/feed-provider/auth"
+	"alibazlamit/feed-provider/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const API_KEY_ROTATION_GRACE = 24 * time.Hour
+
+// createAPIKey issues a key with the requested scopes, the plain key is only returned here
+func createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request models.APIKeyRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid API key request", err)
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		handleError(w, r, http.StatusBadRequest, "name is required", fmt.Errorf("API key without a name"))
+		return
+	}
+	if !auth.ValidScopes(request.Scopes) {
+		handleError(w, r, http.StatusBadRequest, fmt.Sprintf("scopes must be one or more of %v", models.Scopes), fmt.Errorf("invalid scopes %v", request.Scopes))
+		return
+	}
+
+	keyID, secret, err := auth.GenerateKey()
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error generating API key", err)
+		return
+	}
+	key := models.APIKey{
+		KeyID:     keyID,
+		Name:      request.Name,
+		Hash:      auth.HashSecret(secret),
+		Scopes:    request.Scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = apiKeyRepository.AddAPIKey(r.Context(), &key)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error saving API key", err)
+		return
+	}
+
+	responseObj := models.APIKeyResponse{
+		Status: string(models.Success),
+		Data:   key,
+		Key:    auth.FormatKey(keyID, secret),
+	}
+	handleSuccess(w, http.StatusCreated, responseObj)
+}
+
+func getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := apiKeyRepository.GetAPIKeys(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving API keys", err)
+		return
+	}
+	responseObj := models.APIKeysResponse{
+		Status: string(models.Success),
+		Data:   keys,
+	}
+	handleSuccess(w, http.StatusOK, responseObj)
+}
+
+// rotateAPIKey gives the key a new secret, the old one keeps working for ?grace=,
+// 24h by default, so the client can switch without downtime
+func rotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	grace := API_KEY_ROTATION_GRACE
+	if value := r.URL.Query().Get("grace"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			handleError(w, r, http.StatusBadRequest, "Invalid grace period", fmt.Errorf("invalid grace %q", value))
+			return
+		}
+		grace = parsed
+	}
+
+	key, ok := findAPIKey(w, r, mux.Vars(r)["keyId"])
+	if !ok {
+		return
+	}
+	if key.Revoked() {
+		handleError(w, r, http.StatusConflict, "API key is revoked", fmt.Errorf("API key %s is revoked", key.KeyID))
+		return
+	}
+	secret, err := auth.GenerateSecret()
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error generating API key", err)
+		return
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(grace)
+	key.PreviousHash = key.Hash
+	key.PreviousExpiresAt = &expiresAt
+	key.Hash = auth.HashSecret(secret)
+	key.RotatedAt = &now
+	if _, err = apiKeyRepository.UpdateAPIKey(r.Context(), key); err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error saving API key", err)
+		return
+	}
+
+	responseObj := models.APIKeyResponse{
+		Status: string(models.Success),
+		Data:   *key,
+		Key:    auth.FormatKey(key.KeyID, secret),
+	}
+	handleSuccess(w, http.StatusOK, responseObj)
+}
+
+// revokeAPIKey disables the key at once, it stays listed so past requests can still be attributed
+func revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := findAPIKey(w, r, mux.Vars(r)["keyId"])
+	if !ok {
+		return
+	}
+	if !key.Revoked() {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+		key.PreviousHash = ""
+		key.PreviousExpiresAt = nil
+		if _, err := apiKeyRepository.UpdateAPIKey(r.Context(), key); err != nil {
+			handleError(w, r, http.StatusInternalServerError, "Error saving API key", err)
+			return
+		}
+	}
+	responseObj := models.APIKeyResponse{
+		Status: string(models.Success),
+		Data:   *key,
+	}
+	handleSuccess(w, http.StatusOK, responseObj)
+}
+
+// findAPIKey writes the error response itself when the key can't be used
+func findAPIKey(w http.ResponseWriter, r *http.Request, keyID string) (*models.APIKey, bool) {
+	key, err := apiKeyRepository.GetAPIKey(r.Context(), keyID)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving API key", err)
+		return nil, false
+	}
+	if key == nil {
+		handleError(w, r, http.StatusNotFound, "API key not found", fmt.Errorf("API key %s not found", keyID))
+		return nil, false
+	}
+	return key, true
+}
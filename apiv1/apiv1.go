@@ -0,0 +1,148 @@
+// Package apiv1 is the JSON contract of the /v1 article routes and of the webhook
+// payloads, the shape the API had before it was versioned. Fields can be added but
+// never renamed or removed, clients depend on them.
+package apiv1
+
+import (
+	"alibazlamit/feed-provider/models"
+	"time"
+)
+
+type Article struct {
+	ClubName          string    `json:"clubName"`
+	ArticleURL        string    `json:"url"`
+	NewsArticleID     int       `json:"newsArticleId"`
+	Slug              string    `json:"slug"`
+	PublishDate       time.Time `json:"published"`
+	TeaserText        string    `json:"teaser"`
+	ThumbnailImageURL string    `json:"imageUrl"`
+	Title             string    `json:"title"`
+	BodyText          string    `json:"content"`
+	GalleryImageURLs  string    `json:"galleryUrls"`
+	VideoURL          string    `json:"videoUrl"`
+	OptaMatchID       string    `json:"optaMatchId"`
+	Warnings          []string  `json:"warnings,omitempty"`
+	ID                string    `json:"id"`
+}
+
+type ArticleResponse struct {
+	Status   string  `json:"status"`
+	Data     Article `json:"data"`
+	Metadata struct {
+		CreatedAt string `json:"createdAt"`
+	} `json:"metadata"`
+	Error string `json:"error,omitempty"`
+}
+
+type ArticlesResponse struct {
+	Status   string    `json:"status"`
+	Data     []Article `json:"data"`
+	Metadata struct {
+		CreatedAt  string `json:"createdAt"`
+		TotalItems int    `json:"totalItems"`
+		Sort       string `json:"sort"`
+	} `json:"metadata"`
+	Error string `json:"error,omitempty"`
+}
+
+type Revision struct {
+	Revision    int       `json:"revision"`
+	ContentHash string    `json:"contentHash"`
+	CapturedAt  time.Time `json:"capturedAt"`
+	Article     Article   `json:"article"`
+}
+
+type RevisionResponse struct {
+	Status string   `json:"status"`
+	Data   Revision `json:"data"`
+	Error  string   `json:"error,omitempty"`
+}
+
+type RevisionsResponse struct {
+	Status string     `json:"status"`
+	Data   []Revision `json:"data"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type Diff struct {
+	FromRevision int         `json:"fromRevision"`
+	ToRevision   int         `json:"toRevision"`
+	Changes      []FieldDiff `json:"changes"`
+}
+
+type DiffResponse struct {
+	Status string `json:"status"`
+	Data   Diff   `json:"data"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Event is sent on the /v1 stream and to webhooks
+type Event struct {
+	ID         uint64             `json:"id"`
+	Type       models.SyncOutcome `json:"type"`
+	OccurredAt time.Time          `json:"occurredAt"`
+	Article    Article            `json:"article"`
+}
+
+func FromArticle(a *models.NewsArticleInformationMongoDB) Article {
+	return Article{
+		ClubName:          a.ClubName,
+		ArticleURL:        a.ArticleURL,
+		NewsArticleID:     a.NewsArticleID,
+		Slug:              a.Slug,
+		PublishDate:       a.PublishDate,
+		TeaserText:        a.TeaserText,
+		ThumbnailImageURL: a.ThumbnailImageURL,
+		Title:             a.Title,
+		BodyText:          a.BodyText,
+		GalleryImageURLs:  a.GalleryImageURLs,
+		VideoURL:          a.VideoURL,
+		OptaMatchID:       a.OptaMatchID,
+		Warnings:          a.Warnings,
+		ID:                a.ID.Hex(),
+	}
+}
+
+func FromArticles(articles []models.NewsArticleInformationMongoDB) []Article {
+	converted := make([]Article, len(articles))
+	for i := range articles {
+		converted[i] = FromArticle(&articles[i])
+	}
+	return converted
+}
+
+func FromRevision(r *models.NewsArticleRevision) Revision {
+	return Revision{
+		Revision:    r.Revision,
+		ContentHash: r.ContentHash,
+		CapturedAt:  r.CapturedAt,
+		Article:     FromArticle(&r.Article),
+	}
+}
+
+func FromRevisions(revisions []models.NewsArticleRevision) []Revision {
+	converted := make([]Revision, len(revisions))
+	for i := range revisions {
+		converted[i] = FromRevision(&revisions[i])
+	}
+	return converted
+}
+
+// FromDiff keeps the Go field names the diff was first published with
+func FromDiff(d models.NewsArticleDiff) Diff {
+	diff := Diff{FromRevision: d.FromRevision, ToRevision: d.ToRevision, Changes: []FieldDiff{}}
+	for _, change := range d.Changes {
+		diff.Changes = append(diff.Changes, FieldDiff{Field: change.Field, From: change.From, To: change.To})
+	}
+	return diff
+}
+
+func FromEvent(e *models.ArticleEvent) Event {
+	return Event{ID: e.ID, Type: e.Type, OccurredAt: e.OccurredAt, Article: FromArticle(&e.Article)}
+}
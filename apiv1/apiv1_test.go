@@ -0,0 +1,45 @@
+package apiv1
+
+import (
+	"alibazlamit/feed-provider/models"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the v1 contract, clients parse exactly these fields
+const articleJSON = `{"clubName":"TEST CITY","url":"https://www.example.com/news/2023/july/test","newsArticleId":611120,"slug":"test","published":"2023-07-27T02:00:28Z","teaser":"Teaser","imageUrl":"https://www.example.com/thumb.jpg","title":"Test","content":"<p>Body</p>","galleryUrls":"","videoUrl":"","optaMatchId":"","id":"64c1d2f0a1b2c3d4e5f60718"}`
+
+func TestArticleContract(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("64c1d2f0a1b2c3d4e5f60718")
+	article := models.NewsArticleInformationMongoDB{
+		ID:                id,
+		ClubName:          "TEST CITY",
+		ClubWebsiteURL:    "https://www.example.com",
+		ArticleURL:        "https://www.example.com/news/2023/july/test",
+		NewsArticleID:     611120,
+		Slug:              "test",
+		PublishDate:       time.Date(2023, 7, 27, 2, 0, 28, 0, time.UTC),
+		LastUpdateDate:    time.Date(2023, 7, 28, 2, 0, 28, 0, time.UTC),
+		Taxonomies:        "First Team",
+		TeaserText:        "Teaser",
+		Subtitle:          "Subtitle",
+		ThumbnailImageURL: "https://www.example.com/thumb.jpg",
+		Title:             "Test",
+		BodyText:          "<p>Body</p>",
+		IsPublished:       true,
+		ContentHash:       "hash",
+	}
+
+	encoded, err := json.Marshal(FromArticle(&article))
+	assert.NoError(t, err)
+	assert.JSONEq(t, articleJSON, string(encoded))
+}
+
+func TestFromDiffKeepsFieldNames(t *testing.T) {
+	diff := FromDiff(models.NewsArticleDiff{FromRevision: 1, ToRevision: 2, Changes: []models.FieldDiff{{Field: "BodyText", From: "a", To: "b"}}})
+	assert.Equal(t, []FieldDiff{{Field: "BodyText", From: "a", To: "b"}}, diff.Changes)
+}
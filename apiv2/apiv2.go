@@ -0,0 +1,192 @@
+// Package apiv2 is the JSON contract of the /v2 article routes. Every response is an
+// Envelope with the same meta block, and errors carry their status in the body.
+package apiv2
+
+import (
+	"alibazlamit/feed-provider/models"
+	"strings"
+	"time"
+)
+
+const VERSION = "2"
+
+type Club struct {
+	Name    string `json:"name"`
+	Website string `json:"website,omitempty"`
+}
+
+type Media struct {
+	Thumbnail string   `json:"thumbnail,omitempty"`
+	Gallery   []string `json:"gallery"`
+	Video     string   `json:"video,omitempty"`
+}
+
+type Article struct {
+	ID          string    `json:"id"`
+	UpstreamID  int       `json:"upstreamId"`
+	Slug        string    `json:"slug"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Subtitle    string    `json:"subtitle,omitempty"`
+	Teaser      string    `json:"teaser"`
+	Content     string    `json:"content"`
+	Published   time.Time `json:"published"`
+	Updated     time.Time `json:"updated"`
+	Club        Club      `json:"club"`
+	Tags        []string  `json:"tags"`
+	Media       Media     `json:"media"`
+	OptaMatchID string    `json:"optaMatchId,omitempty"`
+	Warnings    []string  `json:"warnings,omitempty"`
+}
+
+type Revision struct {
+	Revision    int       `json:"revision"`
+	ContentHash string    `json:"contentHash"`
+	CapturedAt  time.Time `json:"capturedAt"`
+	Article     Article   `json:"article"`
+}
+
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type Diff struct {
+	FromRevision int         `json:"fromRevision"`
+	ToRevision   int         `json:"toRevision"`
+	Changes      []FieldDiff `json:"changes"`
+}
+
+type Event struct {
+	ID         uint64             `json:"id"`
+	Type       models.SyncOutcome `json:"type"`
+	OccurredAt time.Time          `json:"occurredAt"`
+	Article    Article            `json:"article"`
+}
+
+type Meta struct {
+	APIVersion  string    `json:"apiVersion"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Count is the number of items in a list response
+	Count *int `json:"count,omitempty"`
+}
+
+type Error struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+type Envelope[T any] struct {
+	Data  *T     `json:"data,omitempty"`
+	Meta  Meta   `json:"meta"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// NewEnvelope wraps data, counting the items of a list
+func NewEnvelope[T any](data T, count ...int) Envelope[T] {
+	envelope := Envelope[T]{Data: &data, Meta: newMeta()}
+	if len(count) > 0 {
+		envelope.Meta.Count = &count[0]
+	}
+	return envelope
+}
+
+func NewError(status int, message string) Envelope[struct{}] {
+	return Envelope[struct{}]{Meta: newMeta(), Error: &Error{Status: status, Message: message}}
+}
+
+func newMeta() Meta {
+	return Meta{APIVersion: VERSION, GeneratedAt: time.Now().UTC()}
+}
+
+func FromArticle(a *models.NewsArticleInformationMongoDB) Article {
+	return Article{
+		ID:          a.ID.Hex(),
+		UpstreamID:  a.NewsArticleID,
+		Slug:        a.Slug,
+		URL:         a.ArticleURL,
+		Title:       a.Title,
+		Subtitle:    a.Subtitle,
+		Teaser:      a.TeaserText,
+		Content:     a.BodyText,
+		Published:   a.PublishDate,
+		Updated:     a.LastUpdateDate,
+		Club:        Club{Name: a.ClubName, Website: a.ClubWebsiteURL},
+		Tags:        a.TaxonomyList(),
+		Media:       Media{Thumbnail: a.ThumbnailImageURL, Gallery: splitList(a.GalleryImageURLs), Video: a.VideoURL},
+		OptaMatchID: a.OptaMatchID,
+		Warnings:    a.Warnings,
+	}
+}
+
+func FromArticles(articles []models.NewsArticleInformationMongoDB) []Article {
+	converted := make([]Article, len(articles))
+	for i := range articles {
+		converted[i] = FromArticle(&articles[i])
+	}
+	return converted
+}
+
+func FromRevision(r *models.NewsArticleRevision) Revision {
+	return Revision{
+		Revision:    r.Revision,
+		ContentHash: r.ContentHash,
+		CapturedAt:  r.CapturedAt,
+		Article:     FromArticle(&r.Article),
+	}
+}
+
+func FromRevisions(revisions []models.NewsArticleRevision) []Revision {
+	converted := make([]Revision, len(revisions))
+	for i := range revisions {
+		converted[i] = FromRevision(&revisions[i])
+	}
+	return converted
+}
+
+// fieldNames maps the storage fields compared by a diff to their path in Article
+var fieldNames = map[string]string{
+	"ClubName":          "club.name",
+	"ClubWebsiteURL":    "club.website",
+	"ArticleURL":        "url",
+	"NewsArticleID":     "upstreamId",
+	"PublishDate":       "published",
+	"Taxonomies":        "tags",
+	"TeaserText":        "teaser",
+	"Subtitle":          "subtitle",
+	"ThumbnailImageURL": "media.thumbnail",
+	"Title":             "title",
+	"BodyText":          "content",
+	"GalleryImageURLs":  "media.gallery",
+	"VideoURL":          "media.video",
+	"OptaMatchID":       "optaMatchId",
+	"IsPublished":       "isPublished",
+}
+
+// FromDiff names the changed fields the way Article does
+func FromDiff(d models.NewsArticleDiff) Diff {
+	diff := Diff{FromRevision: d.FromRevision, ToRevision: d.ToRevision, Changes: []FieldDiff{}}
+	for _, change := range d.Changes {
+		field, ok := fieldNames[change.Field]
+		if !ok {
+			field = change.Field
+		}
+		diff.Changes = append(diff.Changes, FieldDiff{Field: field, From: change.From, To: change.To})
+	}
+	return diff
+}
+
+func FromEvent(e *models.ArticleEvent) Event {
+	return Event{ID: e.ID, Type: e.Type, OccurredAt: e.OccurredAt, Article: FromArticle(&e.Article)}
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,63 @@
+package apiv2
+
+import (
+	"alibazlamit/feed-provider/models"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromArticle(t *testing.T) {
+	article := FromArticle(&models.NewsArticleInformationMongoDB{
+		ClubName:          "TEST CITY",
+		ClubWebsiteURL:    "https://www.example.com",
+		NewsArticleID:     611120,
+		Taxonomies:        "First Team, ,Academy",
+		ThumbnailImageURL: "https://www.example.com/thumb.jpg",
+		GalleryImageURLs:  "https://www.example.com/1.jpg, https://www.example.com/2.jpg",
+	})
+
+	assert.Equal(t, 611120, article.UpstreamID)
+	assert.Equal(t, Club{Name: "TEST CITY", Website: "https://www.example.com"}, article.Club)
+	assert.Equal(t, []string{"First Team", "Academy"}, article.Tags)
+	assert.Equal(t, Media{
+		Thumbnail: "https://www.example.com/thumb.jpg",
+		Gallery:   []string{"https://www.example.com/1.jpg", "https://www.example.com/2.jpg"},
+	}, article.Media)
+
+	// empty lists are sent as [] rather than null
+	encoded, _ := json.Marshal(FromArticle(&models.NewsArticleInformationMongoDB{}))
+	assert.Contains(t, string(encoded), `"tags":[]`)
+	assert.Contains(t, string(encoded), `"gallery":[]`)
+}
+
+func TestFromDiffUsesArticlePaths(t *testing.T) {
+	diff := FromDiff(models.NewsArticleDiff{Changes: []models.FieldDiff{
+		{Field: "BodyText", From: "a", To: "b"},
+		{Field: "GalleryImageURLs", From: "", To: "x"},
+	}})
+	assert.Equal(t, "content", diff.Changes[0].Field)
+	assert.Equal(t, "media.gallery", diff.Changes[1].Field)
+}
+
+func TestEnvelope(t *testing.T) {
+	encoded, _ := json.Marshal(NewEnvelope([]string{"a"}, 1))
+	var decoded map[string]map[string]interface{}
+	json.Unmarshal(encoded, &decoded)
+	assert.Equal(t, VERSION, decoded["meta"]["apiVersion"])
+	assert.Equal(t, float64(1), decoded["meta"]["count"])
+	assert.NotContains(t, decoded, "error")
+
+	encoded, _ = json.Marshal(NewError(404, "Article not found"))
+	assert.JSONEq(t, `{"status":404,"message":"Article not found"}`, string(mustField(t, encoded, "error")))
+	assert.NotContains(t, string(encoded), `"data"`)
+}
+
+func mustField(t *testing.T, encoded []byte, field string) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatal(err)
+	}
+	return fields[field]
+}
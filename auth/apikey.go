@@ -0,0 +1,74 @@
+// Package auth guards the HTTP API with API keys. A key looks like
+// fp_<key id>_<secret>, the key id is public and the secret is only stored hashed.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	KEY_PREFIX    = "fp_"
+	KEY_ID_BYTES  = 6
+	SECRET_BYTES  = 32
+	BOOTSTRAP_KEY = "bootstrap"
+)
+
+// GenerateKey returns a new random key id and secret
+func GenerateKey() (keyID string, secret string, err error) {
+	if keyID, err = randomHex(KEY_ID_BYTES); err != nil {
+		return "", "", err
+	}
+	if secret, err = randomHex(SECRET_BYTES); err != nil {
+		return "", "", err
+	}
+	return keyID, secret, nil
+}
+
+// GenerateSecret returns a new secret for an existing key id, used when rotating
+func GenerateSecret() (string, error) {
+	return randomHex(SECRET_BYTES)
+}
+
+// FormatKey builds the key handed to the client
+func FormatKey(keyID, secret string) string {
+	return KEY_PREFIX + keyID + "_" + secret
+}
+
+// ParseKey splits a key into its id and secret
+func ParseKey(key string) (keyID string, secret string, ok bool) {
+	if !strings.HasPrefix(key, KEY_PREFIX) {
+		return "", "", false
+	}
+	keyID, secret, ok = strings.Cut(strings.TrimPrefix(key, KEY_PREFIX), "_")
+	if !ok || keyID == "" || secret == "" {
+		return "", "", false
+	}
+	return keyID, secret, true
+}
+
+// HashSecret hashes a secret for storage. The secrets are 256 random bits, so a
+// plain sha256 is enough and keeps the per request check cheap.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// secretMatches compares in constant time so the hash can't be guessed byte by byte
+func secretMatches(secret, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(hash)) == 1
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	JWKS_REFRESH = time.Hour
+	// unknown key ids trigger a refetch at most this often, so forged kids can't hammer the provider
+	JWKS_MIN_REFETCH  = time.Minute
+	JWKS_FETCH_LIMIT  = 1 << 20
+	JWKS_HTTP_TIMEOUT = 5 * time.Second
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource returns the public key a token's kid refers to
+type KeySource interface {
+	Key(kid string) (*JSONWebKey, error)
+}
+
+// JSONWebKey is a parsed verification key from a JWKS
+type JSONWebKey struct {
+	ID        string
+	Algorithm string
+	Key       crypto.PublicKey
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS reads the verification keys of a JWKS document, keys meant for
+// encryption or of unsupported types are skipped
+func ParseJWKS(document []byte) (map[string]*JSONWebKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(document, &set); err != nil {
+		return nil, fmt.Errorf("error reading JWKS: %v", err)
+	}
+	keys := map[string]*JSONWebKey{}
+	for _, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		key, err := raw.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("error reading JWK %q: %v", raw.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		keys[raw.Kid] = &JSONWebKey{ID: raw.Kid, Algorithm: raw.Alg, Key: key}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// StaticKeySet serves keys loaded once, e.g. from a file for offline testing
+type StaticKeySet map[string]*JSONWebKey
+
+func LoadJWKSFile(path string) (StaticKeySet, error) {
+	document, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ParseJWKS(document)
+	if err != nil {
+		return nil, err
+	}
+	return StaticKeySet(keys), nil
+}
+
+func (s StaticKeySet) Key(kid string) (*JSONWebKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// RemoteKeySet fetches the JWKS from the identity provider, refreshing it every
+// JWKS_REFRESH and early when a token names a kid it hasn't seen, which is how
+// providers roll their signing keys
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*JSONWebKey
+	fetchedAt time.Time
+	now       func() time.Time
+}
+
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: JWKS_HTTP_TIMEOUT}
+	}
+	return &RemoteKeySet{url: url, client: client, now: time.Now}
+}
+
+func (s *RemoteKeySet) Key(kid string) (*JSONWebKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	age := s.now().Sub(s.fetchedAt)
+	key, known := s.keys[kid]
+	if s.keys == nil || age > JWKS_REFRESH || (!known && age > JWKS_MIN_REFETCH) {
+		if err := s.fetch(); err != nil {
+			// keep serving the keys we have while the provider is unreachable
+			if s.keys == nil {
+				return nil, err
+			}
+		}
+		key, known = s.keys[kid]
+	}
+	if !known {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// fetch replaces the cached keys, the caller holds the lock
+func (s *RemoteKeySet) fetch() error {
+	s.fetchedAt = s.now()
+	response, err := s.client.Get(s.url)
+	if err != nil {
+		return fmt.Errorf("error fetching JWKS: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching JWKS: status %d", response.StatusCode)
+	}
+	document, err := io.ReadAll(io.LimitReader(response.Body, JWKS_FETCH_LIMIT))
+	if err != nil {
+		return fmt.Errorf("error fetching JWKS: %v", err)
+	}
+	keys, err := ParseJWKS(document)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"alibazlamit/feed-provider/models"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	JWT_LEEWAY      = time.Minute
+	JWT_ROLES_CLAIM = "roles"
+)
+
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+// JWTConfig describes which tokens of the identity provider are accepted and what their roles grant
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	JWKSFile string
+	JWKSURL  string
+	// RolesClaim is a dotted path into the claims, e.g. realm_access.roles
+	RolesClaim string
+	RoleScopes map[string][]models.Scope
+	Leeway     time.Duration
+}
+
+// Enabled reports whether a JWKS was configured at all
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSFile != "" || c.JWKSURL != ""
+}
+
+// JWTConfigFromEnv reads the JWT_* environment variables, JWT_ROLE_SCOPES looks
+// like "admin=admin:sync,admin:keys;operator=admin:sync"
+func JWTConfigFromEnv() (JWTConfig, error) {
+	config := JWTConfig{
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		JWKSFile:   os.Getenv("JWT_JWKS_FILE"),
+		JWKSURL:    os.Getenv("JWT_JWKS_URL"),
+		RolesClaim: JWT_ROLES_CLAIM,
+		RoleScopes: map[string][]models.Scope{},
+		Leeway:     JWT_LEEWAY,
+	}
+	if value := os.Getenv("JWT_ROLES_CLAIM"); value != "" {
+		config.RolesClaim = value
+	}
+	if raw := os.Getenv("JWT_LEEWAY"); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil || value < 0 {
+			return config, fmt.Errorf("invalid JWT_LEEWAY %q", raw)
+		}
+		config.Leeway = value
+	}
+	for _, entry := range strings.Split(os.Getenv("JWT_ROLE_SCOPES"), ";") {
+		role, scopes, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		for _, scope := range strings.Split(scopes, ",") {
+			config.RoleScopes[strings.TrimSpace(role)] = append(config.RoleScopes[strings.TrimSpace(role)], models.Scope(strings.TrimSpace(scope)))
+		}
+	}
+	if !config.Enabled() {
+		return config, nil
+	}
+	if config.JWKSFile != "" && config.JWKSURL != "" {
+		return config, fmt.Errorf("set only one of JWT_JWKS_FILE and JWT_JWKS_URL")
+	}
+	// a token from any issuer or for any audience must never pass
+	if config.Issuer == "" || config.Audience == "" {
+		return config, fmt.Errorf("JWT_ISSUER and JWT_AUDIENCE are required with a JWKS")
+	}
+	for role, scopes := range config.RoleScopes {
+		if !ValidScopes(scopes) {
+			return config, fmt.Errorf("role %q maps to unknown scopes %v", role, scopes)
+		}
+	}
+	return config, nil
+}
+
+// Claims are the validated claims of a token
+type Claims struct {
+	Subject string
+	Roles   []string
+	Scopes  []models.Scope
+	Raw     map[string]interface{}
+}
+
+// TokenValidator checks the signature, issuer, audience and validity period of bearer tokens
+type TokenValidator struct {
+	config JWTConfig
+	keys   KeySource
+	now    func() time.Time
+}
+
+func NewTokenValidator(config JWTConfig, keys KeySource) *TokenValidator {
+	return &TokenValidator{config: config, keys: keys, now: time.Now}
+}
+
+// NewTokenValidatorFromConfig loads the configured JWKS file or points at the JWKS URL
+func NewTokenValidatorFromConfig(config JWTConfig) (*TokenValidator, error) {
+	if config.JWKSFile != "" {
+		keys, err := LoadJWKSFile(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewTokenValidator(config, keys), nil
+	}
+	return NewTokenValidator(config, NewRemoteKeySet(config.JWKSURL, nil)), nil
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type registeredClaims struct {
+	Issuer    string       `json:"iss"`
+	Subject   string       `json:"sub"`
+	Audience  audience     `json:"aud"`
+	ExpiresAt *numericDate `json:"exp"`
+	NotBefore *numericDate `json:"nbf"`
+}
+
+// audience is a single string or a list of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+type numericDate struct{ time.Time }
+
+func (d *numericDate) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+	d.Time = time.Unix(0, int64(seconds*float64(time.Second)))
+	return nil
+}
+
+// Validate returns the claims of a token that passes every check
+func (v *TokenValidator) Validate(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	key, err := v.keys.Key(header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	// the key decides the algorithm, never the token alone, which rules out "none" and HS256 with a public key
+	if key.Algorithm != "" && key.Algorithm != header.Alg {
+		return nil, fmt.Errorf("%w: algorithm %s doesn't match the key", ErrInvalidToken, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature encoding", ErrInvalidToken)
+	}
+	if err := verifySignature(header.Alg, key.Key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var registered registeredClaims
+	if err := decodeSegment(parts[1], &registered); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	raw := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.checkRegistered(registered); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims := &Claims{Subject: registered.Subject, Roles: rolesAt(raw, v.config.RolesClaim), Raw: raw}
+	for _, role := range claims.Roles {
+		for _, scope := range v.config.RoleScopes[role] {
+			claims.Scopes = appendScope(claims.Scopes, scope)
+		}
+	}
+	return claims, nil
+}
+
+func (v *TokenValidator) checkRegistered(claims registeredClaims) error {
+	now := v.now()
+	if claims.Issuer != v.config.Issuer {
+		return fmt.Errorf("issuer %q is not accepted", claims.Issuer)
+	}
+	audienceOK := false
+	for _, aud := range claims.Audience {
+		audienceOK = audienceOK || aud == v.config.Audience
+	}
+	if !audienceOK {
+		return fmt.Errorf("audience %v is not accepted", []string(claims.Audience))
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(claims.ExpiresAt.Add(v.config.Leeway)) {
+		return fmt.Errorf("token expired at %s", claims.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	if claims.NotBefore != nil && now.Before(claims.NotBefore.Add(-v.config.Leeway)) {
+		return fmt.Errorf("token is not valid before %s", claims.NotBefore.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	if hash == 0 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	hasher := hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s needs an RSA key", alg)
+		}
+		if alg[:2] == "RS" {
+			return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+		}
+		return rsa.VerifyPSS(publicKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s needs an EC key", alg)
+		}
+		// JWS signatures are r || s, each padded to the curve size
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	return decoder.Decode(v)
+}
+
+// rolesAt follows a dotted path into the claims and returns the string or strings found there
+func rolesAt(claims map[string]interface{}, path string) []string {
+	var current interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[name]
+	}
+	switch value := current.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		roles := []string{}
+		for _, item := range value {
+			if role, ok := item.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+		return roles
+	default:
+		return nil
+	}
+}
+
+func appendScope(scopes []models.Scope, scope models.Scope) []models.Scope {
+	for _, existing := range scopes {
+		if existing == scope {
+			return scopes
+		}
+	}
+	return append(scopes, scope)
+}
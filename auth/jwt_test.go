@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/models"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+var testJWTConfig = JWTConfig{
+	Issuer:     "https://id.example.com/",
+	Audience:   "feed-provider",
+	RolesClaim: "realm_access.roles",
+	RoleScopes: map[string][]models.Scope{
+		"admin":    {models.ScopeAdminSync, models.ScopeAdminKeys},
+		"operator": {models.ScopeAdminSync},
+	},
+	Leeway: time.Minute,
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))}
+}
+
+func jwksDocument(keys ...map[string]string) []byte {
+	document, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return document
+}
+
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.Nil(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + b64(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":          testJWTConfig.Issuer,
+		"aud":          []string{"other", testJWTConfig.Audience},
+		"sub":          "ops@example.com",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"realm_access": map[string]interface{}{"roles": []string{"operator", "viewer"}},
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, jwksDocument(rsaJWK("rsa-1", rsaKey), ecJWK("ec-1", ecKey)), 0644))
+	keys, err := LoadJWKSFile(path)
+	assert.Nil(t, err)
+	validator := NewTokenValidator(testJWTConfig, keys)
+
+	claims, err := validator.Validate(sign(t, "RS256", "rsa-1", rsaKey, validClaims()))
+	assert.Nil(t, err)
+	assert.Equal(t, "ops@example.com", claims.Subject)
+	assert.Equal(t, []string{"operator", "viewer"}, claims.Roles)
+	assert.Equal(t, []models.Scope{models.ScopeAdminSync}, claims.Scopes)
+
+	_, err = validator.Validate(sign(t, "ES256", "ec-1", ecKey, validClaims()))
+	assert.Nil(t, err)
+
+	rejected := map[string]string{}
+	for name, change := range map[string]func(map[string]interface{}){
+		"issuer":   func(c map[string]interface{}) { c["iss"] = "https://evil.example.com/" },
+		"audience": func(c map[string]interface{}) { c["aud"] = "other" },
+		"expired":  func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() },
+		"no exp":   func(c map[string]interface{}) { delete(c, "exp") },
+		"nbf":      func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+	} {
+		claims := validClaims()
+		change(claims)
+		if _, err := validator.Validate(sign(t, "RS256", "rsa-1", rsaKey, claims)); err == nil {
+			rejected[name] = "accepted"
+		}
+	}
+	assert.Empty(t, rejected)
+
+	// within the leeway
+	claims2 := validClaims()
+	claims2["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	_, err = validator.Validate(sign(t, "RS256", "rsa-1", rsaKey, claims2))
+	assert.Nil(t, err)
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, err = validator.Validate(sign(t, "RS256", "rsa-1", otherKey, validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = validator.Validate(sign(t, "RS256", "rsa-2", rsaKey, validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	// the algorithm is pinned by the key
+	_, err = validator.Validate(sign(t, "PS256", "rsa-1", rsaKey, validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	payload := strings.Split(sign(t, "RS256", "rsa-1", rsaKey, validClaims()), ".")[1]
+	_, err = validator.Validate(b64([]byte(`{"alg":"none","kid":"ec-1"}`)) + "." + payload + ".")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRemoteKeySetPicksUpNewKeys(t *testing.T) {
+	first, _ := rsa.GenerateKey(rand.Reader, 2048)
+	second, _ := rsa.GenerateKey(rand.Reader, 2048)
+	document := jwksDocument(rsaJWK("k1", first))
+	fetches := 0
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(document)
+	}))
+	defer provider.Close()
+
+	keys := NewRemoteKeySet(provider.URL, provider.Client())
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+	validator := NewTokenValidator(testJWTConfig, keys)
+
+	_, err := validator.Validate(sign(t, "RS256", "k1", first, validClaims()))
+	assert.Nil(t, err)
+	_, err = validator.Validate(sign(t, "RS256", "k1", first, validClaims()))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, fetches)
+
+	// the provider rolls its key, an unknown kid refetches once the minimum interval passed
+	document = jwksDocument(rsaJWK("k1", first), rsaJWK("k2", second))
+	_, err = validator.Validate(sign(t, "RS256", "k2", second, validClaims()))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, fetches)
+	now = now.Add(JWKS_MIN_REFETCH + time.Second)
+	_, err = validator.Validate(sign(t, "RS256", "k2", second, validClaims()))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, fetches)
+}
+
+func TestMiddlewareAcceptsBearerTokensOnAdminRoutes(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys, err := ParseJWKS(jwksDocument(rsaJWK("rsa-1", rsaKey)))
+	assert.Nil(t, err)
+	authenticator := NewAuthenticator(database.NewMockAPIKeyRepository(), RouteScopes{
+		"articles": models.ScopeArticlesRead,
+		"crawl":    models.ScopeAdminSync,
+		"keys":     models.ScopeAdminKeys,
+	}, slog.New(slog.DiscardHandler))
+	authenticator.SetTokenValidator(NewTokenValidator(testJWTConfig, StaticKeySet(keys)))
+
+	router := mux.NewRouter()
+	router.Use(authenticator.Middleware)
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, KeyFromContext(r.Context()).KeyID)
+	}
+	router.HandleFunc("/articles", ok).Name("articles")
+	router.HandleFunc("/admin/crawl", ok).Name("crawl")
+	router.HandleFunc("/admin/keys", ok).Name("keys")
+
+	token := sign(t, "RS256", "rsa-1", rsaKey, validClaims())
+	call := func(path, authorization string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", authorization)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := call("/admin/crawl", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "jwt:ops@example.com", rr.Body.String())
+	// operator doesn't map to admin:keys
+	assert.Equal(t, http.StatusForbidden, call("/admin/keys", "Bearer "+token).Code)
+	assert.Equal(t, http.StatusUnauthorized, call("/articles", "Bearer "+token).Code)
+	rr = call("/admin/crawl", "Bearer "+token+"x")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "invalid_token")
+}
+
+func TestJWTConfigFromEnv(t *testing.T) {
+	config, err := JWTConfigFromEnv()
+	assert.Nil(t, err)
+	assert.False(t, config.Enabled())
+
+	t.Setenv("JWT_JWKS_FILE", "jwks.json")
+	_, err = JWTConfigFromEnv()
+	assert.NotNil(t, err)
+
+	t.Setenv("JWT_ISSUER", "https://id.example.com/")
+	t.Setenv("JWT_AUDIENCE", "feed-provider")
+	t.Setenv("JWT_ROLE_SCOPES", "admin=admin:sync,admin:keys;operator=admin:sync")
+	config, err = JWTConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Scope{models.ScopeAdminSync, models.ScopeAdminKeys}, config.RoleScopes["admin"])
+
+	t.Setenv("JWT_ROLE_SCOPES", "admin=root")
+	_, err = JWTConfigFromEnv()
+	assert.NotNil(t, err)
+
+	t.Setenv("JWT_ROLE_SCOPES", "")
+	t.Setenv("JWT_LEEWAY", "30")
+	_, err = JWTConfigFromEnv()
+	assert.EqualError(t, err, `invalid JWT_LEEWAY "30"`)
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/httpcache"
+	"alibazlamit/feed-provider/logging"
+	"alibazlamit/feed-provider/models"
+	"alibazlamit/feed-provider/requestlog"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	API_KEY_HEADER = "X-API-Key"
+	// feed readers and EventSource can't send headers, they pass the key in the query
+	API_KEY_PARAM = "api_key"
+	// scope of the routes open to everyone
+	PUBLIC models.Scope = ""
+	// bearer tokens are only accepted on routes under this path
+	BEARER_PATH_PREFIX = "/admin/"
+	BEARER_PREFIX      = "Bearer "
+)
+
+var (
+	ErrMissingKey        = errors.New("missing API key")
+	ErrInvalidKey        = errors.New("invalid API key")
+	ErrBearerNotAccepted = errors.New("bearer tokens are only accepted on " + BEARER_PATH_PREFIX + " routes")
+)
+
+// RouteScopes maps a route name to the scope it requires, a route missing from the
+// map is refused so a new route can't go public by accident
+type RouteScopes map[string]models.Scope
+
+type contextKey struct{}
+
+type Authenticator struct {
+	keys      database.APIKeyRepository
+	scopes    RouteScopes
+	logger    *slog.Logger
+	bootstrap string
+	tokens    *TokenValidator
+}
+
+func NewAuthenticator(keys database.APIKeyRepository, scopes RouteScopes, logger *slog.Logger) *Authenticator {
+	return &Authenticator{keys: keys, scopes: scopes, logger: logger}
+}
+
+// SetBootstrapKey accepts the key with every scope without storing it, so the first
+// keys can be created through the admin endpoints
+func (a *Authenticator) SetBootstrapKey(key string) {
+	if key == "" {
+		a.bootstrap = ""
+		return
+	}
+	a.bootstrap = HashSecret(key)
+}
+
+// SetTokenValidator accepts the identity provider's bearer tokens on the admin routes,
+// with the scopes their roles map to
+func (a *Authenticator) SetTokenValidator(tokens *TokenValidator) {
+	a.tokens = tokens
+}
+
+// Middleware authenticates the request and checks the scope of its named route
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, known, template := PUBLIC, false, ""
+		if route := mux.CurrentRoute(r); route != nil {
+			scope, known = a.scopes[route.GetName()]
+			template, _ = route.GetPathTemplate()
+		}
+		if !known {
+			writeError(w, http.StatusForbidden, "Route is not available")
+			return
+		}
+		if scope == PUBLIC {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// the answer depends on the credentials, a shared cache must not hand it to another client
+		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", API_KEY_HEADER)
+
+		var key *models.APIKey
+		var err error
+		if token, ok := bearerToken(r); ok {
+			key, err = a.authenticateBearer(r.Context(), token, strings.HasPrefix(template, BEARER_PATH_PREFIX))
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+		} else if key, err = a.Authenticate(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `APIKey header="`+API_KEY_HEADER+`"`)
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		requestlog.SetKeyID(r, key.KeyID)
+		if !key.HasScope(scope) {
+			writeError(w, http.StatusForbidden, "API key lacks the "+string(scope)+" scope")
+			return
+		}
+		// the key ID follows the request into the logs of the handlers and the repositories
+		ctx := logging.WithAttrs(context.WithValue(r.Context(), contextKey{}, key), "key_id", key.KeyID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Authenticate returns the active key presented with the request
+func (a *Authenticator) Authenticate(r *http.Request) (*models.APIKey, error) {
+	presented := r.Header.Get(API_KEY_HEADER)
+	if presented == "" {
+		presented = r.URL.Query().Get(API_KEY_PARAM)
+	}
+	return a.AuthenticateKey(r.Context(), presented)
+}
+
+// AuthenticateKey returns the active key for a presented fp_ key, for callers that
+// aren't HTTP requests such as the gRPC server
+func (a *Authenticator) AuthenticateKey(ctx context.Context, presented string) (*models.APIKey, error) {
+	if presented == "" {
+		return nil, ErrMissingKey
+	}
+	if a.bootstrap != "" && secretMatches(presented, a.bootstrap) {
+		return &models.APIKey{KeyID: BOOTSTRAP_KEY, Name: BOOTSTRAP_KEY, Scopes: models.Scopes}, nil
+	}
+
+	keyID, secret, ok := ParseKey(presented)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	key, err := a.keys.GetAPIKey(ctx, keyID)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "error retrieving API key", "key_id", keyID, "error", err)
+		return nil, ErrInvalidKey
+	}
+	if key == nil || key.Revoked() {
+		return nil, ErrInvalidKey
+	}
+	if secretMatches(secret, key.Hash) {
+		return key, nil
+	}
+	// the secret replaced by a rotation keeps working through its grace period
+	if key.PreviousExpiresAt != nil && time.Now().Before(*key.PreviousExpiresAt) && secretMatches(secret, key.PreviousHash) {
+		return key, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// authenticateBearer turns a valid token into a key carrying its subject and mapped scopes,
+// logged as jwt:<subject>
+func (a *Authenticator) authenticateBearer(ctx context.Context, token string, adminRoute bool) (*models.APIKey, error) {
+	if a.tokens == nil || !adminRoute {
+		return nil, ErrBearerNotAccepted
+	}
+	claims, err := a.tokens.Validate(token)
+	if err != nil {
+		a.logger.WarnContext(ctx, "rejected bearer token", "error", err)
+		return nil, ErrInvalidToken
+	}
+	return &models.APIKey{KeyID: "jwt:" + claims.Subject, Name: claims.Subject, Scopes: claims.Scopes}, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len(BEARER_PREFIX) || !strings.EqualFold(header[:len(BEARER_PREFIX)], BEARER_PREFIX) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(BEARER_PREFIX):]), true
+}
+
+// KeyFromContext returns the key that authenticated the request, nil on public routes,
+// for a bearer token it is built from the token's claims
+func KeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(contextKey{}).(*models.APIKey)
+	return key
+}
+
+// ValidScopes reports whether every scope is one a key can be given
+func ValidScopes(scopes []models.Scope) bool {
+	for _, scope := range scopes {
+		valid := false
+		for _, known := range models.Scopes {
+			valid = valid || scope == known
+		}
+		if !valid {
+			return false
+		}
+	}
+	return len(scopes) > 0
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", httpcache.NO_STORE)
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(models.NewsArticlesResponse{
+		Status: string(models.Failure),
+		Error:  strings.ToUpper(message[:1]) + message[1:],
+	})
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/models"
+	"alibazlamit/feed-provider/requestlog"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(keys database.APIKeyRepository, logs io.Writer) http.Handler {
+	authenticator := NewAuthenticator(keys, RouteScopes{
+		"ping":     PUBLIC,
+		"articles": models.ScopeArticlesRead,
+		"crawl":    models.ScopeAdminSync,
+	}, slog.New(slog.DiscardHandler))
+	authenticator.SetBootstrapKey("bootstrap-key-for-tests")
+
+	router := mux.NewRouter()
+	router.Use(authenticator.Middleware)
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if key := KeyFromContext(r.Context()); key != nil {
+			w.Write([]byte(key.KeyID))
+		}
+	}
+	router.HandleFunc("/ping", ok).Name("ping")
+	router.HandleFunc("/articles", ok).Name("articles")
+	router.HandleFunc("/admin/crawl", ok).Name("crawl")
+	router.HandleFunc("/unlisted", ok).Name("unlisted")
+	return requestlog.Middleware(slog.New(slog.NewJSONHandler(logs, nil)))(router)
+}
+
+func addKey(t *testing.T, keys *database.MockAPIKeyRepository, scopes ...models.Scope) (*models.APIKey, string) {
+	keyID, secret, err := GenerateKey()
+	assert.Nil(t, err)
+	key := &models.APIKey{KeyID: keyID, Name: "partner", Hash: HashSecret(secret), Scopes: scopes}
+	assert.Nil(t, keys.AddAPIKey(context.Background(), key))
+	return key, FormatKey(keyID, secret)
+}
+
+func call(router http.Handler, path, key string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	if key != "" {
+		req.Header.Set(API_KEY_HEADER, key)
+	}
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMiddlewareChecksScopes(t *testing.T) {
+	keys := database.NewMockAPIKeyRepository()
+	logs := &bytes.Buffer{}
+	router := newTestRouter(keys, logs)
+	key, plain := addKey(t, keys, models.ScopeArticlesRead)
+
+	assert.Equal(t, http.StatusOK, call(router, "/ping", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, call(router, "/articles", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, call(router, "/articles", plain+"0").Code)
+	assert.Equal(t, http.StatusUnauthorized, call(router, "/articles", "not-a-key").Code)
+	assert.Equal(t, http.StatusForbidden, call(router, "/unlisted", plain).Code)
+
+	rr := call(router, "/articles", plain)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, key.KeyID, rr.Body.String())
+	assert.Equal(t, []string{"Authorization", API_KEY_HEADER}, rr.Header().Values("Vary"))
+	assert.Empty(t, call(router, "/ping", "").Header().Values("Vary"))
+	assert.Equal(t, http.StatusForbidden, call(router, "/admin/crawl", plain).Code)
+
+	// feed readers pass the key in the query, which is kept out of the log
+	assert.Equal(t, http.StatusOK, call(router, "/articles?api_key="+plain, "").Code)
+	assert.Contains(t, logs.String(), `"path":"/articles","status":200`)
+	assert.Contains(t, logs.String(), `"key_id":"`+key.KeyID+`"`)
+	assert.NotContains(t, logs.String(), plain)
+
+	assert.Equal(t, http.StatusOK, call(router, "/admin/crawl", "bootstrap-key-for-tests").Code)
+}
+
+func TestMiddlewareRejectsRevokedAndRotatedKeys(t *testing.T) {
+	keys := database.NewMockAPIKeyRepository()
+	router := newTestRouter(keys, io.Discard)
+	key, plain := addKey(t, keys, models.ScopeArticlesRead)
+
+	// the old secret works through the grace period only
+	secret, _ := GenerateSecret()
+	expiresAt := time.Now().Add(time.Hour)
+	key.PreviousHash, key.PreviousExpiresAt, key.Hash = key.Hash, &expiresAt, HashSecret(secret)
+	keys.UpdateAPIKey(context.Background(), key)
+	assert.Equal(t, http.StatusOK, call(router, "/articles", plain).Code)
+	assert.Equal(t, http.StatusOK, call(router, "/articles", FormatKey(key.KeyID, secret)).Code)
+	expiresAt = time.Now().Add(-time.Second)
+	keys.UpdateAPIKey(context.Background(), key)
+	assert.Equal(t, http.StatusUnauthorized, call(router, "/articles", plain).Code)
+
+	revokedAt := time.Now()
+	key.RevokedAt = &revokedAt
+	keys.UpdateAPIKey(context.Background(), key)
+	assert.Equal(t, http.StatusUnauthorized, call(router, "/articles", FormatKey(key.KeyID, secret)).Code)
+}
+
+func TestParseKey(t *testing.T) {
+	keyID, secret, ok := ParseKey(FormatKey("abc123", "s3cret"))
+	assert.True(t, ok)
+	assert.Equal(t, "abc123", keyID)
+	assert.Equal(t, "s3cret", secret)
+
+	for _, key := range []string{"", "fp_", "fp_abc", "fp__secret", "xx_abc_secret"} {
+		_, _, ok = ParseKey(key)
+		assert.False(t, ok, key)
+	}
+}
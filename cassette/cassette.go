@@ -0,0 +1,145 @@
+// Package cassette records the reader's upstream traffic to disk and replays it,
+// so parsing can be tested against real payloads without reaching the upstream.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	REDACTED = "REDACTED"
+	// cassette layout version, bumped on incompatible changes
+	VERSION = 1
+)
+
+var ErrUnexpectedRequest = errors.New("request not found in cassette")
+
+// HTTPClient is the client the reader takes, both wrappers satisfy it
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Cassette struct {
+	Version      int           `json:"version"`
+	RecordedAt   time.Time     `json:"recordedAt"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Redaction lists what is replaced with REDACTED before an interaction is written
+type Redaction struct {
+	Headers     []string
+	QueryParams []string
+	// every match in a response body is replaced
+	Body []*regexp.Regexp
+}
+
+// DefaultRedaction covers the credentials an upstream usually sees
+func DefaultRedaction() Redaction {
+	return Redaction{
+		Headers:     []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+		QueryParams: []string{"api_key", "apikey", "token", "access_token"},
+	}
+}
+
+// URL redacts the query parameters, replaying redacts the incoming URL the same way before matching
+func (r Redaction) URL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := parsed.Query()
+	changed := false
+	for _, param := range r.QueryParams {
+		if _, ok := query[param]; ok {
+			query.Set(param, REDACTED)
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func (r Redaction) header(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	redacted := header.Clone()
+	for _, name := range r.Headers {
+		if _, ok := redacted[http.CanonicalHeaderKey(name)]; ok {
+			redacted.Set(name, REDACTED)
+		}
+	}
+	return redacted
+}
+
+func (r Redaction) body(body string) string {
+	for _, pattern := range r.Body {
+		body = pattern.ReplaceAllString(body, REDACTED)
+	}
+	return body
+}
+
+// Load reads a cassette written by a Recorder
+func Load(path string) (*Cassette, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(content, &cassette); err != nil {
+		return nil, fmt.Errorf("error reading cassette %s: %v", path, err)
+	}
+	if cassette.Version != VERSION {
+		return nil, fmt.Errorf("cassette %s has version %d, expected %d", path, cassette.Version, VERSION)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette through a temporary file so a crash never leaves half a cassette
+func (c *Cassette) Save(path string) error {
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func key(method, rawURL string) string {
+	return strings.ToUpper(method) + " " + rawURL
+}
@@ -0,0 +1,98 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type clientFunc func(req *http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upstream.json")
+	calls := 0
+	upstream := clientFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Set-Cookie": []string{"session=secret"}, "Etag": []string{`"v1"`}},
+			Body:       io.NopCloser(strings.NewReader("<Title>call " + string(rune('0'+calls)) + "</Title><Email>fan@example.com</Email>")),
+		}, nil
+	})
+	redaction := DefaultRedaction()
+	redaction.Body = []*regexp.Regexp{regexp.MustCompile(`[a-z]+@example\.com`)}
+	recorder := NewRecorder(path, upstream, redaction)
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/list?count=2&token=abc", nil)
+		req.Header.Set("Authorization", "Bearer abc")
+		response, err := recorder.Do(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(response.Body)
+		// the caller still sees the real response
+		assert.Contains(t, string(body), "fan@example.com")
+	}
+
+	cassette, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(cassette.Interactions))
+	recorded := cassette.Interactions[0]
+	assert.Equal(t, "https://example.com/list?count=2&token=REDACTED", recorded.Request.URL)
+	assert.Equal(t, REDACTED, recorded.Request.Headers.Get("Authorization"))
+	assert.Equal(t, REDACTED, recorded.Response.Headers.Get("Set-Cookie"))
+	assert.Equal(t, `"v1"`, recorded.Response.Headers.Get("ETag"))
+	assert.Equal(t, "<Title>call 1</Title><Email>REDACTED</Email>", recorded.Response.Body)
+
+	replayer, err := NewReplayer(path, redaction)
+	assert.Nil(t, err)
+	bodies := []string{}
+	for i := 0; i < 3; i++ {
+		// a different token still matches once redacted
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/list?count=2&token=xyz", nil)
+		response, err := replayer.Do(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(response.Body)
+		bodies = append(bodies, string(body))
+	}
+	assert.Equal(t, []string{
+		"<Title>call 1</Title><Email>REDACTED</Email>",
+		"<Title>call 2</Title><Email>REDACTED</Email>",
+		"<Title>call 2</Title><Email>REDACTED</Email>",
+	}, bodies)
+	assert.Equal(t, 2, calls)
+	assert.Empty(t, replayer.Unplayed())
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/list?count=3", nil)
+	_, err = replayer.Do(req)
+	assert.True(t, errors.Is(err, ErrUnexpectedRequest))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	config, err := ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, MODE_OFF, config.Mode)
+
+	t.Setenv("UPSTREAM_CASSETTE_MODE", "replay")
+	_, err = ConfigFromEnv()
+	assert.NotNil(t, err)
+
+	t.Setenv("UPSTREAM_CASSETTE", "upstream.json")
+	t.Setenv("CASSETTE_REDACT_QUERY", "club_key")
+	config, err = ConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Contains(t, config.Redaction.QueryParams, "club_key")
+
+	t.Setenv("UPSTREAM_CASSETTE_MODE", "rewind")
+	_, err = ConfigFromEnv()
+	assert.NotNil(t, err)
+}
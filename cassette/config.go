@@ -0,0 +1,65 @@
+package cassette
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	MODE_OFF    = ""
+	MODE_RECORD = "record"
+	MODE_REPLAY = "replay"
+)
+
+// Config decides whether the upstream traffic is recorded, replayed or left alone
+type Config struct {
+	Mode      string
+	Path      string
+	Redaction Redaction
+}
+
+// ConfigFromEnv reads UPSTREAM_CASSETTE_MODE and UPSTREAM_CASSETTE, the
+// CASSETTE_REDACT_* lists are added to the default redaction
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Mode:      strings.ToLower(os.Getenv("UPSTREAM_CASSETTE_MODE")),
+		Path:      os.Getenv("UPSTREAM_CASSETTE"),
+		Redaction: DefaultRedaction(),
+	}
+	config.Redaction.Headers = append(config.Redaction.Headers, splitList(os.Getenv("CASSETTE_REDACT_HEADERS"))...)
+	config.Redaction.QueryParams = append(config.Redaction.QueryParams, splitList(os.Getenv("CASSETTE_REDACT_QUERY"))...)
+
+	switch config.Mode {
+	case MODE_OFF:
+	case MODE_RECORD, MODE_REPLAY:
+		if config.Path == "" {
+			return config, fmt.Errorf("UPSTREAM_CASSETTE is required in %s mode", config.Mode)
+		}
+	default:
+		return config, fmt.Errorf("unknown cassette mode %q", config.Mode)
+	}
+	return config, nil
+}
+
+// Wrap returns the client the reader should use for the configured mode
+func Wrap(config Config, client HTTPClient) (HTTPClient, error) {
+	switch config.Mode {
+	case MODE_RECORD:
+		return NewRecorder(config.Path, client, config.Redaction), nil
+	case MODE_REPLAY:
+		return NewReplayer(config.Path, config.Redaction)
+	default:
+		return client, nil
+	}
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
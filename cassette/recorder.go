@@ -0,0 +1,65 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Recorder passes every request to the wrapped client and appends the redacted
+// exchange to the cassette file, which is rewritten after each interaction
+type Recorder struct {
+	client    HTTPClient
+	path      string
+	redaction Redaction
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder starts a new cassette at path, replacing any cassette already there
+func NewRecorder(path string, client HTTPClient, redaction Redaction) *Recorder {
+	return &Recorder{
+		client:    client,
+		path:      path,
+		redaction: redaction,
+		cassette:  &Cassette{Version: VERSION, RecordedAt: time.Now().UTC(), Interactions: []Interaction{}},
+	}
+}
+
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	response, err := r.client.Do(req)
+	if err != nil {
+		// nothing to replay for a network error
+		return nil, err
+	}
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     r.redaction.URL(req.URL.String()),
+			Headers: r.redaction.header(req.Header),
+		},
+		Response: Response{
+			StatusCode: response.StatusCode,
+			Headers:    r.redaction.header(response.Header),
+			Body:       r.redaction.body(string(body)),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.Save(r.path); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
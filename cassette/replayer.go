@@ -0,0 +1,88 @@
+package cassette
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Replayer answers requests from a cassette without touching the network. Requests
+// for the same method and URL get the recorded responses in order, the last one
+// repeating once they run out, and a request that was never recorded fails.
+type Replayer struct {
+	redaction Redaction
+
+	mu        sync.Mutex
+	responses map[string][]Response
+	played    map[string]int
+}
+
+// NewReplayer loads the cassette at path, redaction must match the one it was recorded with
+func NewReplayer(path string, redaction Redaction) (*Replayer, error) {
+	cassette, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{
+		redaction: redaction,
+		responses: map[string][]Response{},
+		played:    map[string]int{},
+	}
+	for _, interaction := range cassette.Interactions {
+		k := key(interaction.Request.Method, interaction.Request.URL)
+		r.responses[k] = append(r.responses[k], interaction.Response)
+	}
+	return r, nil
+}
+
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	k := key(req.Method, r.redaction.URL(req.URL.String()))
+
+	r.mu.Lock()
+	responses, ok := r.responses[k]
+	if !ok {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedRequest, k)
+	}
+	i := r.played[k]
+	if i >= len(responses) {
+		i = len(responses) - 1
+	}
+	r.played[k]++
+	r.mu.Unlock()
+
+	recorded := responses[i]
+	header := recorded.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unplayed lists the recorded requests nobody asked for, a sign the reader stopped
+// fetching something it used to
+func (r *Replayer) Unplayed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unplayed := []string{}
+	for k := range r.responses {
+		if r.played[k] == 0 {
+			unplayed = append(unplayed, k)
+		}
+	}
+	sort.Strings(unplayed)
+	return unplayed
+}
@@ -0,0 +1,97 @@
+// Command fake-upstream runs the fake InCrowd API on a fixed address so the
+// feed provider can be started against it with UPSTREAM_BASE_URL.
+package main
+
+import (
+	"alibazlamit/feed-provider/fakeupstream"
+	"alibazlamit/feed-provider/logging"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8081", "address to listen on")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	errorRate := flag.Float64("error-rate", 0, "share of requests, between 0 and 1, answered with 503")
+	malformed := flag.String("malformed", "", "comma separated article ids served as truncated XML")
+	editEvery := flag.Duration("edit-every", 0, "edit a random article at this interval, 0 never edits")
+	flag.Parse()
+
+	logConfig, err := logging.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring logging: %v\n", err)
+		os.Exit(1)
+	}
+	logger, _ := logging.New(os.Stdout, logConfig)
+	logger = logger.With("component", "fake-upstream")
+
+	server, err := fakeupstream.NewUnstartedServer()
+	if err != nil {
+		logger.Error("error loading fixtures", "error", err)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		logger.Error("error listening", "addr", *addr, "error", err)
+		os.Exit(1)
+	}
+	server.Listener.Close()
+	server.Listener = listener
+
+	server.SetLatency(*latency)
+	server.SetErrorRate(*errorRate, http.StatusServiceUnavailable)
+	for _, value := range strings.Split(*malformed, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			logger.Error("invalid article id", "value", value, "error", err)
+			os.Exit(1)
+		}
+		server.SetMalformed(id, true)
+	}
+
+	server.Start()
+	defer server.Close()
+	logger.Info("serving articles, run the feed provider with UPSTREAM_BASE_URL set to base_url",
+		"articles", len(server.Articles()),
+		"base_url", server.BaseURL(),
+	)
+
+	if *editEvery > 0 {
+		go editArticles(server, *editEvery, logger)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
+
+// editArticles appends a paragraph to a random article at every tick
+func editArticles(server *fakeupstream.Server, interval time.Duration, logger *slog.Logger) {
+	for range time.Tick(interval) {
+		articles := server.Articles()
+		if len(articles) == 0 {
+			continue
+		}
+		id := articles[rand.Intn(len(articles))].NewsArticleID
+		err := server.Edit(id, func(article *fakeupstream.Article) {
+			article.BodyText += fmt.Sprintf("<p>Updated at %s.</p>", time.Now().UTC().Format(time.RFC3339))
+		})
+		if err != nil {
+			logger.Error("error editing article", "article_id", id, "error", err)
+			continue
+		}
+		logger.Info("edited article", "article_id", id)
+	}
+}
@@ -0,0 +1,76 @@
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DEFAULT_MAX_AGE = 10 * time.Minute
+
+var (
+	DEFAULT_ALLOWED_METHODS = []string{"GET", "POST", "DELETE"}
+	// the API key, bearer tokens and the conditional request headers
+	DEFAULT_ALLOWED_HEADERS = []string{"Authorization", "Content-Type", "X-API-Key", "If-None-Match", "If-Modified-Since", "Last-Event-ID", "X-Request-ID"}
+	// headers a browser script can read besides the CORS-safelisted ones
+	DEFAULT_EXPOSED_HEADERS = []string{"ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "API-Version", "Deprecation", "Sunset", "Link", "X-Request-ID"}
+)
+
+// Config lists what browsers on other origins may do
+type Config struct {
+	// AllowedOrigins are exact origins or patterns with one "*", e.g. "https://*.htafc.com"
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer
+	MaxAge time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		AllowedMethods: DEFAULT_ALLOWED_METHODS,
+		AllowedHeaders: DEFAULT_ALLOWED_HEADERS,
+		ExposedHeaders: DEFAULT_EXPOSED_HEADERS,
+		MaxAge:         DEFAULT_MAX_AGE,
+	}
+}
+
+// ConfigFromEnv reads the CORS_* environment variables, CORS stays off until
+// CORS_ALLOWED_ORIGINS is set
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	config.AllowedOrigins = splitList(os.Getenv("CORS_ALLOWED_ORIGINS"))
+	if value, ok := os.LookupEnv("CORS_ALLOWED_METHODS"); ok {
+		config.AllowedMethods = splitList(strings.ToUpper(value))
+	}
+	if value, ok := os.LookupEnv("CORS_ALLOWED_HEADERS"); ok {
+		config.AllowedHeaders = splitList(value)
+	}
+	if value, ok := os.LookupEnv("CORS_EXPOSED_HEADERS"); ok {
+		config.ExposedHeaders = splitList(value)
+	}
+	if value := os.Getenv("CORS_ALLOW_CREDENTIALS"); value != "" {
+		credentials, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS %q", value)
+		}
+		config.AllowCredentials = credentials
+	}
+	if value := os.Getenv("CORS_MAX_AGE"); value != "" {
+		maxAge, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid CORS_MAX_AGE %q", value)
+		}
+		config.MaxAge = maxAge
+	}
+	if config.AllowCredentials && contains(config.AllowedOrigins, "*") {
+		// any site could then make calls carrying the visitor's credentials
+		return config, errors.New("CORS_ALLOW_CREDENTIALS can't be combined with the * origin")
+	}
+	return config, nil
+}
@@ -0,0 +1,115 @@
+// Package cors answers the browser's cross-origin checks. It wraps the whole router
+// rather than being a mux middleware, since preflight OPTIONS requests match no route.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Middleware adds the CORS headers for allowed origins and answers their preflight
+// requests with 204. Requests from other origins pass through without the headers,
+// so the browser refuses them. With no allowed origins it does nothing.
+func Middleware(config Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(config.AllowedOrigins) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+			if origin == "" || !config.originAllowed(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if config.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if len(config.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requested := splitList(r.Header.Get("Access-Control-Request-Headers"))
+			if !contains(config.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) || !config.headersAllowed(requested) {
+				w.Header().Del("Access-Control-Allow-Origin")
+				w.Header().Del("Access-Control-Allow-Credentials")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
+			if len(requested) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			if config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// originAllowed matches the origin against the patterns, "*" allows every origin and
+// "https://*.example.com" any subdomain of example.com
+func (c Config) originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range c.AllowedOrigins {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(pattern, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Config) headersAllowed(requested []string) bool {
+	if contains(c.AllowedHeaders, "*") {
+		return true
+	}
+	for _, header := range requested {
+		if !contains(c.AllowedHeaders, header) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,134 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(config Config) http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/articles", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+	return Middleware(config)(router)
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.AllowedOrigins = []string{"https://www.htafc.com", "https://*.htafc.com"}
+	return config
+}
+
+func TestOriginAllowed(t *testing.T) {
+	config := testConfig()
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"https://www.htafc.com", true},
+		{"https://WWW.htafc.com", true},
+		{"https://shop.htafc.com", true},
+		{"https://.htafc.com", false},
+		{"http://shop.htafc.com", false},
+		{"https://htafc.com.evil.com", false},
+		{"https://evilhtafc.com", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, config.originAllowed(test.origin), test.origin)
+	}
+	assert.True(t, Config{AllowedOrigins: []string{"*"}}.originAllowed("https://example.com"))
+}
+
+func TestSimpleRequest(t *testing.T) {
+	handler := newTestHandler(testConfig())
+
+	req := httptest.NewRequest("GET", "/articles", nil)
+	req.Header.Set("Origin", "https://shop.htafc.com")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "https://shop.htafc.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Expose-Headers"), "X-RateLimit-Remaining")
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", rr.Header().Get("Vary"))
+
+	req.Header.Set("Origin", "https://example.com")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestPreflight(t *testing.T) {
+	config := testConfig()
+	config.AllowCredentials = true
+	config.MaxAge = time.Hour
+	handler := newTestHandler(config)
+
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/articles", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", headers)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := preflight("https://www.htafc.com", "GET", "x-api-key, if-none-match")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "https://www.htafc.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST, DELETE", rr.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "x-api-key, if-none-match", rr.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "3600", rr.Header().Get("Access-Control-Max-Age"))
+
+	for _, rr := range []*httptest.ResponseRecorder{
+		preflight("https://example.com", "GET", ""),
+		preflight("https://www.htafc.com", "PUT", ""),
+		preflight("https://www.htafc.com", "GET", "X-Custom"),
+	} {
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Methods"))
+	}
+}
+
+func TestDisabledWithoutOrigins(t *testing.T) {
+	handler := newTestHandler(DefaultConfig())
+
+	req := httptest.NewRequest("OPTIONS", "/articles", nil)
+	req.Header.Set("Origin", "https://www.htafc.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://www.htafc.com, https://*.htafc.com")
+	t.Setenv("CORS_ALLOWED_METHODS", "get,post")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	config, err := ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://www.htafc.com", "https://*.htafc.com"}, config.AllowedOrigins)
+	assert.Equal(t, []string{"GET", "POST"}, config.AllowedMethods)
+	assert.Equal(t, DEFAULT_ALLOWED_HEADERS, config.AllowedHeaders)
+	assert.True(t, config.AllowCredentials)
+	assert.Equal(t, time.Hour, config.MaxAge)
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "maybe")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,13 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+)
+
+type APIKeyRepository interface {
+	AddAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	UpdateAPIKey(ctx context.Context, key *models.APIKey) (bool, error)
+}
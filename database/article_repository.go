@@ -2,12 +2,25 @@ package database
 
 import (
 	"alibazlamit/feed-provider/models"
+	"context"
+	"errors"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrCursorNotFound is returned by FindArticles when the article a page starts after doesn't exist
+var ErrCursorNotFound = errors.New("cursor article not found")
+
 type ArticleRepository interface {
-	GetArticleByID(id primitive.ObjectID) (*models.NewsArticleInformationMongoDB, error)
-	GetAllArticles() ([]models.NewsArticleInformationMongoDB, error)
-	AddOrUpdateArticle(articleID int, articleXml *models.NewsArticleInformationXML) error
+	GetArticleByID(ctx context.Context, id primitive.ObjectID) (*models.NewsArticleInformationMongoDB, error)
+	GetArticleByNewsArticleID(ctx context.Context, newsArticleID int) (*models.NewsArticleInformationMongoDB, error)
+	GetArticleBySlug(ctx context.Context, slug string) (*models.NewsArticleInformationMongoDB, error)
+	GetAllArticles(ctx context.Context) ([]models.NewsArticleInformationMongoDB, error)
+	// FindArticles returns one page of the articles matching the query, newest first
+	FindArticles(ctx context.Context, query models.ArticleQuery) (*models.ArticlePage, error)
+	AddOrUpdateArticle(ctx context.Context, articleID int, articleXml *models.NewsArticleInformationXML) (*models.NewsArticleInformationMongoDB, models.SyncOutcome, error)
+	GetArticleRevisions(ctx context.Context, id primitive.ObjectID) ([]models.NewsArticleRevision, error)
+	GetArticleRevision(ctx context.Context, id primitive.ObjectID, revision int) (*models.NewsArticleRevision, error)
+	// Ping checks the store can be reached, for the readiness check
+	Ping(ctx context.Context) error
 }
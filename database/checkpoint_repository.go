@@ -0,0 +1,11 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+)
+
+type CheckpointRepository interface {
+	GetCheckpoint(ctx context.Context, name string) (*models.CrawlCheckpoint, error)
+	SaveCheckpoint(ctx context.Context, checkpoint *models.CrawlCheckpoint) error
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockAPIKeyRepository struct {
+	Keys []models.APIKey
+	mu   sync.Mutex
+}
+
+func NewMockAPIKeyRepository() *MockAPIKeyRepository {
+	return &MockAPIKeyRepository{
+		Keys: []models.APIKey{},
+	}
+}
+
+func (r *MockAPIKeyRepository) AddAPIKey(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.ID = primitive.NewObjectID()
+	r.Keys = append(r.Keys, *key)
+	return nil
+}
+
+func (r *MockAPIKeyRepository) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.Keys {
+		if key.KeyID == keyID {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockAPIKeyRepository) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]models.APIKey, len(r.Keys))
+	copy(keys, r.Keys)
+	return keys, nil
+}
+
+func (r *MockAPIKeyRepository) UpdateAPIKey(ctx context.Context, key *models.APIKey) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.Keys {
+		if r.Keys[i].KeyID == key.KeyID {
+			r.Keys[i] = *key
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"sync"
+)
+
+type MockCheckpointRepository struct {
+	Checkpoints map[string]models.CrawlCheckpoint
+	mu          sync.Mutex
+}
+
+func NewMockCheckpointRepository() *MockCheckpointRepository {
+	return &MockCheckpointRepository{
+		Checkpoints: map[string]models.CrawlCheckpoint{},
+	}
+}
+
+func (r *MockCheckpointRepository) GetCheckpoint(ctx context.Context, name string) (*models.CrawlCheckpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	checkpoint, ok := r.Checkpoints[name]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (r *MockCheckpointRepository) SaveCheckpoint(ctx context.Context, checkpoint *models.CrawlCheckpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Checkpoints[checkpoint.Name] = *checkpoint
+	return nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockQualityRepository struct {
+	Violations  []models.ViolationRecord
+	Quarantined []models.QuarantinedArticle
+	mu          sync.Mutex
+}
+
+func NewMockQualityRepository() *MockQualityRepository {
+	return &MockQualityRepository{
+		Violations:  []models.ViolationRecord{},
+		Quarantined: []models.QuarantinedArticle{},
+	}
+}
+
+func (r *MockQualityRepository) AddViolations(ctx context.Context, records []models.ViolationRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, record := range records {
+		if !r.hasViolation(record) {
+			r.Violations = append(r.Violations, record)
+		}
+	}
+	return nil
+}
+
+// hasViolation expects mu to be held
+func (r *MockQualityRepository) hasViolation(record models.ViolationRecord) bool {
+	for _, existing := range r.Violations {
+		if existing.Feed == record.Feed && existing.NewsArticleID == record.NewsArticleID &&
+			existing.Field == record.Field && existing.Rule == record.Rule &&
+			existing.ContentHash == record.ContentHash {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MockQualityRepository) QuarantineArticle(ctx context.Context, article *models.QuarantinedArticle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.Quarantined {
+		if existing.Feed == article.Feed && existing.NewsArticleID == article.NewsArticleID {
+			r.Quarantined[i] = *article
+			return nil
+		}
+	}
+	r.Quarantined = append(r.Quarantined, *article)
+	return nil
+}
+
+func (r *MockQualityRepository) GetViolationBuckets(ctx context.Context, since time.Time) ([]models.ViolationBucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := map[models.ViolationBucket]int{}
+	for _, record := range r.Violations {
+		if record.OccurredAt.Before(since) {
+			continue
+		}
+		key := models.ViolationBucket{
+			Feed:   record.Feed,
+			Day:    record.OccurredAt.UTC().Format("2006-01-02"),
+			Rule:   record.Rule,
+			Action: record.Action,
+		}
+		counts[key]++
+	}
+
+	buckets := []models.ViolationBucket{}
+	for bucket, count := range counts {
+		bucket.Count = count
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Feed != buckets[j].Feed {
+			return buckets[i].Feed < buckets[j].Feed
+		}
+		if buckets[i].Day != buckets[j].Day {
+			return buckets[i].Day < buckets[j].Day
+		}
+		if buckets[i].Rule != buckets[j].Rule {
+			return buckets[i].Rule < buckets[j].Rule
+		}
+		return buckets[i].Action < buckets[j].Action
+	})
+	return buckets, nil
+}
@@ -2,45 +2,190 @@ package database
 
 import (
 	"alibazlamit/feed-provider/models"
+	"context"
+	"sort"
+	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MockArticleRepository struct {
-	Articles []models.NewsArticleInformationMongoDB
+	Articles  []models.NewsArticleInformationMongoDB
+	Revisions []models.NewsArticleRevision
+	PingErr   error
+	mu        sync.Mutex
 }
 
 func NewMockArticleRepository() *MockArticleRepository {
 	return &MockArticleRepository{
-		Articles: []models.NewsArticleInformationMongoDB{},
+		Articles:  []models.NewsArticleInformationMongoDB{},
+		Revisions: []models.NewsArticleRevision{},
 	}
 }
 
-func (r *MockArticleRepository) GetAllArticles() ([]models.NewsArticleInformationMongoDB, error) {
-	return r.Articles, nil
+func (r *MockArticleRepository) GetAllArticles(ctx context.Context) ([]models.NewsArticleInformationMongoDB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.NewsArticleInformationMongoDB{}, r.Articles...), nil
 }
 
-func (r *MockArticleRepository) GetArticleByID(id primitive.ObjectID) (*models.NewsArticleInformationMongoDB, error) {
+func (r *MockArticleRepository) FindArticles(ctx context.Context, query models.ArticleQuery) (*models.ArticlePage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	selected := []models.NewsArticleInformationMongoDB{}
+	for i := range r.Articles {
+		if query.Matches(&r.Articles[i]) {
+			selected = append(selected, r.Articles[i])
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return models.Newer(&selected[i], &selected[j])
+	})
+	page := &models.ArticlePage{TotalCount: len(selected)}
+
+	if !query.After.IsZero() {
+		after := r.findByID(query.After)
+		if after == nil {
+			return nil, ErrCursorNotFound
+		}
+		for len(selected) > 0 && !models.Newer(after, &selected[0]) {
+			selected = selected[1:]
+		}
+	}
+	if query.Limit >= 0 && len(selected) > query.Limit {
+		selected, page.HasMore = selected[:query.Limit], true
+	}
+	page.Articles = selected
+	return page, nil
+}
+
+func (r *MockArticleRepository) GetArticleByID(ctx context.Context, id primitive.ObjectID) (*models.NewsArticleInformationMongoDB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.findByID(id), nil
+}
+
+// findByID expects mu to be held
+func (r *MockArticleRepository) findByID(id primitive.ObjectID) *models.NewsArticleInformationMongoDB {
 	for _, article := range r.Articles {
 		if article.ID == id {
+			return &article
+		}
+	}
+	return nil // Return nil if article not found
+}
+
+func (r *MockArticleRepository) GetArticleByNewsArticleID(ctx context.Context, newsArticleID int) (*models.NewsArticleInformationMongoDB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, article := range r.Articles {
+		if article.NewsArticleID == newsArticleID {
 			return &article, nil
 		}
 	}
-	return nil, nil // Return nil if article not found
+	return nil, nil
 }
 
-func (r *MockArticleRepository) AddOrUpdateArticle(id int, article *models.NewsArticleInformationXML) error {
-	newsArticle := models.NewsArticleInformationMongoDB{
-		ID:             primitive.NewObjectID(),
-		Title:          article.NewsArticle.Title,
-		BodyText:       article.NewsArticle.BodyText,
-		ClubName:       article.ClubName,
-		ClubWebsiteURL: article.ClubWebsiteURL,
-		ArticleURL:     article.NewsArticle.ArticleURL,
-		NewsArticleID:  article.NewsArticle.NewsArticleID,
-		Taxonomies:     article.NewsArticle.Taxonomies,
+func (r *MockArticleRepository) GetArticleBySlug(ctx context.Context, slug string) (*models.NewsArticleInformationMongoDB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, article := range r.Articles {
+		if article.Slug == slug {
+			return &article, nil
+		}
 	}
+	return nil, nil
+}
+
+func (r *MockArticleRepository) AddOrUpdateArticle(ctx context.Context, id int, article *models.NewsArticleInformationXML) (*models.NewsArticleInformationMongoDB, models.SyncOutcome, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newsArticle := models.ConvertToMongoDB(article)
+	newsArticle.Slug = r.uniqueSlug(newsArticle)
+	for i, existing := range r.Articles {
+		if existing.NewsArticleID != newsArticle.NewsArticleID {
+			continue
+		}
+		if existing.ContentHash == newsArticle.ContentHash {
+			r.Articles[i].Slug = newsArticle.Slug
+			existing.Slug = newsArticle.Slug
+			return &existing, models.ArticleUnchanged, nil
+		}
+		newsArticle.ID = existing.ID
+		r.Articles[i] = *newsArticle
+		r.addRevision(ctx, newsArticle)
+		if existing.IsPublished && !newsArticle.IsPublished {
+			return newsArticle, models.ArticleUnpublished, nil
+		}
+		return newsArticle, models.ArticleUpdated, nil
+	}
+
+	newsArticle.ID = primitive.NewObjectID()
+	r.Articles = append(r.Articles, *newsArticle)
+	r.addRevision(ctx, newsArticle)
+	return newsArticle, models.ArticleInserted, nil
+}
+
+// uniqueSlug expects mu to be held
+func (r *MockArticleRepository) uniqueSlug(article *models.NewsArticleInformationMongoDB) string {
+	suffixed := models.SlugWithID(article.Slug, article.NewsArticleID)
+	for _, existing := range r.Articles {
+		if existing.NewsArticleID == article.NewsArticleID && existing.Slug == suffixed {
+			return suffixed
+		}
+	}
+	for _, existing := range r.Articles {
+		if article.Slug != "" && existing.NewsArticleID != article.NewsArticleID && existing.Slug == article.Slug {
+			return suffixed
+		}
+	}
+	return article.Slug
+}
+
+func (r *MockArticleRepository) addRevision(ctx context.Context, article *models.NewsArticleInformationMongoDB) {
+	revision := 1
+	for _, existing := range r.Revisions {
+		if existing.NewsArticleID == article.NewsArticleID {
+			revision++
+		}
+	}
+	r.Revisions = append(r.Revisions, models.NewsArticleRevision{
+		NewsArticleID: article.NewsArticleID,
+		Revision:      revision,
+		ContentHash:   article.ContentHash,
+		CapturedAt:    time.Now().UTC(),
+		Article:       *article,
+	})
+}
+
+func (r *MockArticleRepository) GetArticleRevisions(ctx context.Context, id primitive.ObjectID) ([]models.NewsArticleRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	article := r.findByID(id)
+	if article == nil {
+		return nil, nil
+	}
+	revisions := []models.NewsArticleRevision{}
+	for _, revision := range r.Revisions {
+		if revision.NewsArticleID == article.NewsArticleID {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+func (r *MockArticleRepository) GetArticleRevision(ctx context.Context, id primitive.ObjectID, revision int) (*models.NewsArticleRevision, error) {
+	revisions, _ := r.GetArticleRevisions(ctx, id)
+	for _, articleRevision := range revisions {
+		if articleRevision.Revision == revision {
+			return &articleRevision, nil
+		}
+	}
+	return nil, nil
+}
 
-	r.Articles = append(r.Articles, newsArticle)
-	return nil
+func (r *MockArticleRepository) Ping(ctx context.Context) error {
+	return r.PingErr
 }
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockWebhookRepository struct {
+	Subscriptions []models.WebhookSubscription
+	Deliveries    []models.WebhookDelivery
+	mu            sync.Mutex
+}
+
+func NewMockWebhookRepository() *MockWebhookRepository {
+	return &MockWebhookRepository{
+		Subscriptions: []models.WebhookSubscription{},
+		Deliveries:    []models.WebhookDelivery{},
+	}
+}
+
+func (r *MockWebhookRepository) AddSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription.ID = primitive.NewObjectID()
+	r.Subscriptions = append(r.Subscriptions, *subscription)
+	return nil
+}
+
+func (r *MockWebhookRepository) GetSubscription(ctx context.Context, id primitive.ObjectID) (*models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, subscription := range r.Subscriptions {
+		if subscription.ID == id {
+			return &subscription, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockWebhookRepository) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.WebhookSubscription{}, r.Subscriptions...), nil
+}
+
+func (r *MockWebhookRepository) DeleteSubscription(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, subscription := range r.Subscriptions {
+		if subscription.ID == id {
+			r.Subscriptions = append(r.Subscriptions[:i], r.Subscriptions[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockWebhookRepository) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.ID = primitive.NewObjectID()
+	r.Deliveries = append(r.Deliveries, *delivery)
+	return nil
+}
+
+func (r *MockWebhookRepository) GetDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deliveries := []models.WebhookDelivery{}
+	for i := len(r.Deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if r.Deliveries[i].SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, r.Deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
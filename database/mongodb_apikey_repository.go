@@ -0,0 +1,81 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const KEY_ID_KEY = "keyId"
+
+type MongoDBAPIKeyRepository struct {
+	Collection *mongo.Collection
+	Logger     *slog.Logger
+}
+
+// CreateIndexes sets up the unique index backing the key lookup of every request
+func (r *MongoDBAPIKeyRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: KEY_ID_KEY, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error creating API key indexes", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *MongoDBAPIKeyRepository) AddAPIKey(ctx context.Context, key *models.APIKey) error {
+	result, err := r.Collection.InsertOne(ctx, key)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error saving API key", "error", err)
+		return err
+	}
+	key.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoDBAPIKeyRepository) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.Collection.FindOne(ctx, bson.M{KEY_ID_KEY: keyID}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		r.Logger.ErrorContext(ctx, "error retrieving API key", "error", err)
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *MongoDBAPIKeyRepository) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	cursor, err := r.Collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error retrieving API keys", "error", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	err = cursor.All(ctx, &keys)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error decoding API keys", "error", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *MongoDBAPIKeyRepository) UpdateAPIKey(ctx context.Context, key *models.APIKey) (bool, error) {
+	result, err := r.Collection.ReplaceOne(ctx, bson.M{KEY_ID_KEY: key.KeyID}, key)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error updating API key", "error", err)
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
@@ -3,7 +3,10 @@ package database
 import (
 	"alibazlamit/feed-provider/models"
 	"context"
-	"log"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,34 +16,187 @@ import (
 
 const (
 	NEWS_ARTICLE_KEY = "NewsArticleID"
+	SLUG_KEY         = "slug"
+	REVISION_KEY     = "revision"
+	// attempts at numbering a revision when concurrent syncs of the article race for the same number
+	REVISION_ATTEMPTS = 5
+	// marks the one-off move of stored dates from the feed's wall clock to UTC as done
+	UTC_DATES_MIGRATION = "utc-dates"
+	// set on every article stored with UTC dates
+	DATES_UTC_KEY    = "datesUTC"
+	PUBLISH_DATE_KEY = "publishDate"
+	PUBLISHED_KEY    = "published"
 )
 
-var ctx = context.TODO()
-
 type MongoDBArticleRepository struct {
-	Collection *mongo.Collection
-	Logger     *log.Logger
+	Collection           *mongo.Collection
+	RevisionsCollection  *mongo.Collection
+	MigrationsCollection *mongo.Collection
+	Logger               *slog.Logger
 }
 
-func (r *MongoDBArticleRepository) GetArticleByID(id primitive.ObjectID) (*models.NewsArticleInformationMongoDB, error) {
+func (r *MongoDBArticleRepository) GetArticleByID(ctx context.Context, id primitive.ObjectID) (*models.NewsArticleInformationMongoDB, error) {
 	filter := bson.M{"_id": id}
 	var article models.NewsArticleInformationMongoDB
 	err := r.Collection.FindOne(ctx, filter).Decode(&article)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			r.Logger.Fatal("Article not found", err)
-			return nil, err
+			return nil, nil
+		}
+		r.Logger.ErrorContext(ctx, "error retrieving article", "id", id.Hex(), "error", err)
+		return nil, err
+	}
+	return &article, nil
+}
+
+func (r *MongoDBArticleRepository) GetArticleByNewsArticleID(ctx context.Context, newsArticleID int) (*models.NewsArticleInformationMongoDB, error) {
+	return r.findOneArticle(ctx, bson.M{NEWS_ARTICLE_KEY: newsArticleID})
+}
+
+func (r *MongoDBArticleRepository) GetArticleBySlug(ctx context.Context, slug string) (*models.NewsArticleInformationMongoDB, error) {
+	return r.findOneArticle(ctx, bson.M{SLUG_KEY: slug})
+}
+
+func (r *MongoDBArticleRepository) findOneArticle(ctx context.Context, filter bson.M) (*models.NewsArticleInformationMongoDB, error) {
+	var article models.NewsArticleInformationMongoDB
+	err := r.Collection.FindOne(ctx, filter).Decode(&article)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
 		}
-		r.Logger.Fatal("Error retrieving article", err)
+		r.Logger.ErrorContext(ctx, "error retrieving article", "filter", filter, "error", err)
 		return nil, err
 	}
 	return &article, nil
 }
 
-func (r *MongoDBArticleRepository) GetAllArticles() ([]models.NewsArticleInformationMongoDB, error) {
+// CreateIndexes sets up the unique indexes backing the upstream ID and slug lookups,
+// and the one keeping revision numbers unique per article
+func (r *MongoDBArticleRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: NEWS_ARTICLE_KEY, Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// articles synced before slugs existed have none, keep them out of the index
+			Keys: bson.D{{Key: SLUG_KEY, Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{SLUG_KEY: bson.M{"$gt": ""}}),
+		},
+		{
+			// FindArticles pages through the published articles newest first
+			Keys: bson.D{{Key: PUBLISHED_KEY, Value: 1}, {Key: PUBLISH_DATE_KEY, Value: -1}, {Key: "_id", Value: -1}},
+		},
+	}
+	_, err := r.Collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error creating indexes", "error", err)
+		return err
+	}
+	_, err = r.RevisionsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: NEWS_ARTICLE_KEY, Value: 1}, {Key: REVISION_KEY, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error creating revision indexes", "error", err)
+		return err
+	}
+	return nil
+}
+
+// MigrateDatesToUTC rewrites dates stored before they were normalised to UTC. Those kept
+// the feed's wall clock labelled as UTC, so every content hash would change on the next
+// sync and create a revision and an event per article. The dates and hashes of articles
+// and revisions are corrected in place instead. Every document is flagged datesUTC in the
+// same write that converts it, and only unflagged documents are written, so a migration
+// that is interrupted, retried or run by two replicas at once never converts one twice
+func (r *MongoDBArticleRepository) MigrateDatesToUTC(ctx context.Context, location *time.Location) (int, error) {
+	err := r.MigrationsCollection.FindOne(ctx, bson.M{"_id": UTC_DATES_MIGRATION}).Err()
+	if err == nil {
+		return 0, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		r.Logger.ErrorContext(ctx, "error retrieving migration", "migration", UTC_DATES_MIGRATION, "error", err)
+		return 0, err
+	}
+
+	migrated := 0
+	unflagged := bson.M{DATES_UTC_KEY: bson.M{"$exists": false}}
+	cursor, err := r.Collection.Find(ctx, unflagged)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error retrieving articles", "error", err)
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var article models.NewsArticleInformationMongoDB
+		if err = cursor.Decode(&article); err != nil {
+			r.Logger.ErrorContext(ctx, "error decoding articles", "error", err)
+			return migrated, err
+		}
+		if !article.NormaliseDates(location) {
+			continue
+		}
+		result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": article.ID, DATES_UTC_KEY: bson.M{"$exists": false}}, bson.M{"$set": bson.M{
+			"publishDate":    article.PublishDate,
+			"lastUpdateDate": article.LastUpdateDate,
+			"contentHash":    article.ContentHash,
+			DATES_UTC_KEY:    true,
+		}})
+		if err != nil {
+			r.Logger.ErrorContext(ctx, "error migrating article", "article_id", article.NewsArticleID, "error", err)
+			return migrated, err
+		}
+		migrated += int(result.ModifiedCount)
+	}
+	if err = cursor.Err(); err != nil {
+		return migrated, err
+	}
+
+	unflagged = bson.M{"article." + DATES_UTC_KEY: bson.M{"$exists": false}}
+	revisions, err := r.RevisionsCollection.Find(ctx, unflagged)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error retrieving revisions", "error", err)
+		return migrated, err
+	}
+	defer revisions.Close(ctx)
+	for revisions.Next(ctx) {
+		var revision models.NewsArticleRevision
+		if err = revisions.Decode(&revision); err != nil {
+			r.Logger.ErrorContext(ctx, "error decoding revisions", "error", err)
+			return migrated, err
+		}
+		if !revision.Article.NormaliseDates(location) {
+			continue
+		}
+		filter := bson.M{"_id": revision.ID, "article." + DATES_UTC_KEY: bson.M{"$exists": false}}
+		_, err = r.RevisionsCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+			"contentHash": revision.Article.ContentHash,
+			"article":     revision.Article,
+		}})
+		if err != nil {
+			r.Logger.ErrorContext(ctx, "error migrating revision", "article_id", revision.NewsArticleID, "revision", revision.Revision, "error", err)
+			return migrated, err
+		}
+	}
+	if err = revisions.Err(); err != nil {
+		return migrated, err
+	}
+
+	// another replica may have finished first
+	_, err = r.MigrationsCollection.InsertOne(ctx, bson.M{"_id": UTC_DATES_MIGRATION, "appliedAt": time.Now().UTC()})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		r.Logger.ErrorContext(ctx, "error recording migration", "migration", UTC_DATES_MIGRATION, "error", err)
+		return migrated, err
+	}
+	return migrated, nil
+}
+
+func (r *MongoDBArticleRepository) GetAllArticles(ctx context.Context) ([]models.NewsArticleInformationMongoDB, error) {
 	cursor, err := r.Collection.Find(ctx, bson.M{})
 	if err != nil {
-		r.Logger.Fatal("Error retrieving articles", err)
+		r.Logger.ErrorContext(ctx, "error retrieving articles", "error", err)
 		return nil, err
 	}
 	defer cursor.Close(ctx)
@@ -50,7 +206,7 @@ func (r *MongoDBArticleRepository) GetAllArticles() ([]models.NewsArticleInforma
 		var article models.NewsArticleInformationMongoDB
 		err := cursor.Decode(&article)
 		if err != nil {
-			r.Logger.Fatal("Error decoding articles", err)
+			r.Logger.ErrorContext(ctx, "error decoding articles", "error", err)
 			return nil, err
 		}
 		articles = append(articles, article)
@@ -59,13 +215,255 @@ func (r *MongoDBArticleRepository) GetAllArticles() ([]models.NewsArticleInforma
 	return articles, nil
 }
 
-func (r *MongoDBArticleRepository) AddOrUpdateArticle(articleID int, articleXml *models.NewsArticleInformationXML) error {
-	opts := options.Replace().SetUpsert(true)
+// FindArticles filters, counts and pages in Mongo. Pages continue from the publish date
+// and id of the article they start after, so inserts don't shift the following pages
+func (r *MongoDBArticleRepository) FindArticles(ctx context.Context, query models.ArticleQuery) (*models.ArticlePage, error) {
+	filter := articleQueryFilter(&query)
+	total, err := r.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error counting articles", "error", err)
+		return nil, err
+	}
+	page := &models.ArticlePage{Articles: []models.NewsArticleInformationMongoDB{}, TotalCount: int(total)}
+	if query.Limit == 0 {
+		return page, nil
+	}
+
+	if !query.After.IsZero() {
+		after, err := r.GetArticleByID(ctx, query.After)
+		if err != nil {
+			return nil, err
+		}
+		if after == nil {
+			return nil, ErrCursorNotFound
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{PUBLISH_DATE_KEY: bson.M{"$lt": after.PublishDate}},
+			bson.M{PUBLISH_DATE_KEY: after.PublishDate, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: PUBLISH_DATE_KEY, Value: -1}, {Key: "_id", Value: -1}})
+	if query.Limit > 0 {
+		// one more than the page tells whether there is a next one
+		opts.SetLimit(int64(query.Limit) + 1)
+	}
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error retrieving articles", "error", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &page.Articles)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error decoding articles", "error", err)
+		return nil, err
+	}
+	if query.Limit > 0 && len(page.Articles) > query.Limit {
+		page.Articles, page.HasMore = page.Articles[:query.Limit], true
+	}
+	return page, nil
+}
+
+// articleQueryFilter translates the query's filter, matching it the way ArticleQuery.Matches does
+func articleQueryFilter(query *models.ArticleQuery) bson.M {
+	conditions := bson.A{}
+	contains := func(value string) primitive.Regex {
+		return primitive.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"}
+	}
+	if !query.IncludeUnpublished {
+		conditions = append(conditions, bson.M{PUBLISHED_KEY: true})
+	}
+	if query.Club != "" {
+		conditions = append(conditions, bson.M{"clubName": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.Club) + "$", Options: "i"}})
+	}
+	if query.Taxonomy != "" {
+		// taxonomies are stored as one comma separated string
+		pattern := `(^|,)\s*` + regexp.QuoteMeta(strings.TrimSpace(query.Taxonomy)) + `\s*(,|$)`
+		conditions = append(conditions, bson.M{"taxonomies": primitive.Regex{Pattern: pattern, Options: "i"}})
+	}
+	if query.Search != "" {
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"title": contains(query.Search)},
+			bson.M{"teaser": contains(query.Search)},
+		}})
+	}
+	for _, term := range query.Terms {
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"title": contains(term)},
+			bson.M{"teaser": contains(term)},
+			bson.M{"content": contains(term)},
+		}})
+	}
+	if !query.PublishedAfter.IsZero() {
+		conditions = append(conditions, bson.M{PUBLISH_DATE_KEY: bson.M{"$gt": query.PublishedAfter}})
+	}
+	if !query.PublishedBefore.IsZero() {
+		conditions = append(conditions, bson.M{PUBLISH_DATE_KEY: bson.M{"$lt": query.PublishedBefore}})
+	}
+	if len(conditions) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": conditions}
+}
+
+func (r *MongoDBArticleRepository) AddOrUpdateArticle(ctx context.Context, articleID int, articleXml *models.NewsArticleInformationXML) (*models.NewsArticleInformationMongoDB, models.SyncOutcome, error) {
+	article := models.ConvertToMongoDB(articleXml)
 	filter := bson.D{{Key: NEWS_ARTICLE_KEY, Value: articleID}}
-	_, err := r.Collection.ReplaceOne(context.TODO(), filter, models.ConvertToMongoDB(articleXml), opts)
+
+	// skip the write entirely when the content hasn't changed since the last sync
+	var existing models.NewsArticleInformationMongoDB
+	err := r.Collection.FindOne(ctx, filter).Decode(&existing)
+	if err != nil && err != mongo.ErrNoDocuments {
+		r.Logger.ErrorContext(ctx, "error retrieving article", "article_id", articleID, "error", err)
+		return nil, "", err
+	}
+	found := err == nil
+	if article.Slug, err = r.uniqueSlug(ctx, articleID, article.Slug, existing.Slug); err != nil {
+		return nil, "", err
+	}
+	if found && existing.ContentHash == article.ContentHash {
+		// the slug isn't content, articles stored under an older slug scheme get the new one
+		if existing.Slug != article.Slug {
+			_, err = r.Collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{SLUG_KEY: article.Slug}})
+			if err != nil {
+				r.Logger.ErrorContext(ctx, "error updating slug", "article_id", articleID, "error", err)
+				return nil, "", err
+			}
+			existing.Slug = article.Slug
+		}
+		return &existing, models.ArticleUnchanged, nil
+	}
+
+	// the revision is written first: if it fails the stored hash still differs and the next
+	// poll tries again, if the article write fails the next poll finds the revision in place
+	if err = r.addRevision(ctx, articleID, article); err != nil {
+		return nil, "", err
+	}
+	opts := options.Replace().SetUpsert(true)
+	result, err := r.Collection.ReplaceOne(ctx, filter, article, opts)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error saving article", "article_id", articleID, "error", err)
+		return nil, "", err
+	}
+
+	if !found {
+		if upsertedID, ok := result.UpsertedID.(primitive.ObjectID); ok {
+			article.ID = upsertedID
+		}
+		return article, models.ArticleInserted, nil
+	}
+	article.ID = existing.ID
+	if existing.IsPublished && !article.IsPublished {
+		return article, models.ArticleUnpublished, nil
+	}
+	return article, models.ArticleUpdated, nil
+}
+
+// uniqueSlug suffixes the NewsArticleID when another article already has the slug, an article
+// stored with the suffix keeps it. Two new articles racing for one slug fail on the unique
+// index, the one that lost finds the slug taken on the next poll
+func (r *MongoDBArticleRepository) uniqueSlug(ctx context.Context, articleID int, slug, stored string) (string, error) {
+	suffixed := models.SlugWithID(slug, articleID)
+	if slug == "" {
+		return slug, nil
+	}
+	if stored == suffixed {
+		return suffixed, nil
+	}
+	taken, err := r.Collection.CountDocuments(ctx, bson.M{SLUG_KEY: slug, NEWS_ARTICLE_KEY: bson.M{"$ne": articleID}})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error checking slug", "article_id", articleID, "error", err)
+		return "", err
+	}
+	if taken > 0 {
+		return suffixed, nil
+	}
+	return slug, nil
+}
+
+// addRevision stores the article as its next revision unless the latest revision already
+// has its content, the unique index makes a sync racing for the same number fail so it can count again
+func (r *MongoDBArticleRepository) addRevision(ctx context.Context, articleID int, article *models.NewsArticleInformationMongoDB) error {
+	var latest models.NewsArticleRevision
+	opts := options.FindOne().SetSort(bson.D{{Key: REVISION_KEY, Value: -1}})
+	err := r.RevisionsCollection.FindOne(ctx, bson.M{NEWS_ARTICLE_KEY: articleID}, opts).Decode(&latest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		r.Logger.ErrorContext(ctx, "error retrieving revision", "article_id", articleID, "error", err)
+		return err
+	}
+	if err == nil && latest.ContentHash == article.ContentHash {
+		return nil
+	}
+
+	for attempt := 0; attempt < REVISION_ATTEMPTS; attempt++ {
+		var count int64
+		count, err = r.RevisionsCollection.CountDocuments(ctx, bson.M{NEWS_ARTICLE_KEY: articleID})
+		if err != nil {
+			r.Logger.ErrorContext(ctx, "error counting revisions", "article_id", articleID, "error", err)
+			return err
+		}
+		revision := models.NewsArticleRevision{
+			NewsArticleID: articleID,
+			Revision:      int(count) + 1,
+			ContentHash:   article.ContentHash,
+			CapturedAt:    time.Now().UTC(),
+			Article:       *article,
+		}
+		_, err = r.RevisionsCollection.InsertOne(ctx, revision)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
 	if err != nil {
-		r.Logger.Printf("Error saving article with id:%d and error: %v\n", articleID, err)
+		r.Logger.ErrorContext(ctx, "error saving revision", "article_id", articleID, "error", err)
 		return err
 	}
 	return nil
 }
+
+func (r *MongoDBArticleRepository) GetArticleRevisions(ctx context.Context, id primitive.ObjectID) ([]models.NewsArticleRevision, error) {
+	article, err := r.GetArticleByID(ctx, id)
+	if err != nil || article == nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: REVISION_KEY, Value: 1}})
+	cursor, err := r.RevisionsCollection.Find(ctx, bson.M{NEWS_ARTICLE_KEY: article.NewsArticleID}, opts)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error retrieving revisions", "error", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.NewsArticleRevision{}
+	err = cursor.All(ctx, &revisions)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error decoding revisions", "error", err)
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *MongoDBArticleRepository) GetArticleRevision(ctx context.Context, id primitive.ObjectID, revision int) (*models.NewsArticleRevision, error) {
+	article, err := r.GetArticleByID(ctx, id)
+	if err != nil || article == nil {
+		return nil, err
+	}
+
+	filter := bson.M{NEWS_ARTICLE_KEY: article.NewsArticleID, REVISION_KEY: revision}
+	var articleRevision models.NewsArticleRevision
+	err = r.RevisionsCollection.FindOne(ctx, filter).Decode(&articleRevision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		r.Logger.ErrorContext(ctx, "error retrieving revision", "error", err)
+		return nil, err
+	}
+	return &articleRevision, nil
+}
+
+func (r *MongoDBArticleRepository) Ping(ctx context.Context) error {
+	return r.Collection.Database().Client().Ping(ctx, nil)
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoDBCheckpointRepository struct {
+	Collection *mongo.Collection
+	Logger     *slog.Logger
+}
+
+func (r *MongoDBCheckpointRepository) GetCheckpoint(ctx context.Context, name string) (*models.CrawlCheckpoint, error) {
+	var checkpoint models.CrawlCheckpoint
+	err := r.Collection.FindOne(ctx, bson.M{"_id": name}).Decode(&checkpoint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		r.Logger.ErrorContext(ctx, "error retrieving checkpoint", "checkpoint", name, "error", err)
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (r *MongoDBCheckpointRepository) SaveCheckpoint(ctx context.Context, checkpoint *models.CrawlCheckpoint) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.Collection.ReplaceOne(ctx, bson.M{"_id": checkpoint.Name}, checkpoint, opts)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error saving checkpoint", "checkpoint", checkpoint.Name, "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoDBQualityRepository struct {
+	ViolationsCollection *mongo.Collection
+	QuarantineCollection *mongo.Collection
+	Logger               *slog.Logger
+}
+
+// CreateIndexes sets up the unique index that keeps a violation of unchanged content from being recorded twice
+func (r *MongoDBQualityRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.ViolationsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "feed", Value: 1},
+			{Key: NEWS_ARTICLE_KEY, Value: 1},
+			{Key: "field", Value: 1},
+			{Key: "rule", Value: 1},
+			{Key: "contentHash", Value: 1},
+		},
+		// violations recorded before the hash was kept have none, and may repeat
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"contentHash": bson.M{"$gt": ""}}),
+	})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error creating violation indexes", "error", err)
+		return err
+	}
+	return nil
+}
+
+// AddViolations upserts on the feed, article, field, rule and content hash, so polling
+// an article that hasn't changed doesn't count its violations again
+func (r *MongoDBQualityRepository) AddViolations(ctx context.Context, records []models.ViolationRecord) error {
+	writes := make([]mongo.WriteModel, len(records))
+	for i, record := range records {
+		filter := bson.M{
+			"feed":           record.Feed,
+			NEWS_ARTICLE_KEY: record.NewsArticleID,
+			"field":          record.Field,
+			"rule":           record.Rule,
+			"contentHash":    record.ContentHash,
+		}
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$setOnInsert": record}).
+			SetUpsert(true)
+	}
+	_, err := r.ViolationsCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error saving violations", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *MongoDBQualityRepository) QuarantineArticle(ctx context.Context, article *models.QuarantinedArticle) error {
+	filter := bson.M{"feed": article.Feed, NEWS_ARTICLE_KEY: article.NewsArticleID}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.QuarantineCollection.ReplaceOne(ctx, filter, article, opts)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error quarantining article", "article_id", article.NewsArticleID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *MongoDBQualityRepository) GetViolationBuckets(ctx context.Context, since time.Time) ([]models.ViolationBucket, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"occurredAt": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"feed":   "$feed",
+				"day":    bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$occurredAt"}},
+				"rule":   "$rule",
+				"action": "$action",
+			},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":    0,
+			"feed":   "$_id.feed",
+			"day":    "$_id.day",
+			"rule":   "$_id.rule",
+			"action": "$_id.action",
+			"count":  1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "feed", Value: 1}, {Key: "day", Value: 1}, {Key: "rule", Value: 1}}}},
+	}
+	cursor, err := r.ViolationsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error aggregating violations", "error", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	buckets := []models.ViolationBucket{}
+	err = cursor.All(ctx, &buckets)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error decoding violations", "error", err)
+		return nil, err
+	}
+	return buckets, nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	SUBSCRIPTION_KEY = "subscriptionId"
+	DELIVERED_AT_KEY = "deliveredAt"
+	// delivery attempts are kept for troubleshooting, not as an audit trail
+	DELIVERY_RETENTION = 30 * 24 * time.Hour
+)
+
+type MongoDBWebhookRepository struct {
+	SubscriptionsCollection *mongo.Collection
+	DeliveriesCollection    *mongo.Collection
+	Logger                  *slog.Logger
+}
+
+// CreateIndexes sets up the index listing a subscription's deliveries newest first,
+// and the TTL index that drops deliveries older than DELIVERY_RETENTION
+func (r *MongoDBWebhookRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.DeliveriesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: SUBSCRIPTION_KEY, Value: 1}, {Key: DELIVERED_AT_KEY, Value: -1}}},
+		{
+			Keys:    bson.D{{Key: DELIVERED_AT_KEY, Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(DELIVERY_RETENTION.Seconds())),
+		},
+	})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error creating webhook delivery indexes", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *MongoDBWebhookRepository) AddSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	result, err := r.SubscriptionsCollection.InsertOne(ctx, subscription)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error saving webhook subscription", "error", err)
+		return err
+	}
+	subscription.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoDBWebhookRepository) GetSubscription(ctx context.Context, id primitive.ObjectID) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.SubscriptionsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&subscription)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		r.Logger.ErrorContext(ctx, "error retrieving webhook subscription", "error", err)
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *MongoDBWebhookRepository) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	cursor, err := r.SubscriptionsCollection.Find(ctx, bson.M{})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error retrieving webhook subscriptions", "error", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	subscriptions := []models.WebhookSubscription{}
+	err = cursor.All(ctx, &subscriptions)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error decoding webhook subscriptions", "error", err)
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *MongoDBWebhookRepository) DeleteSubscription(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.SubscriptionsCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error deleting webhook subscription", "error", err)
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *MongoDBWebhookRepository) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := r.DeliveriesCollection.InsertOne(ctx, delivery)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error saving webhook delivery", "error", err)
+		return err
+	}
+	return nil
+}
+
+// GetDeliveries returns the most recent delivery attempts for a subscription, newest first
+func (r *MongoDBWebhookRepository) GetDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, limit int) ([]models.WebhookDelivery, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: DELIVERED_AT_KEY, Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.DeliveriesCollection.Find(ctx, bson.M{SUBSCRIPTION_KEY: subscriptionID}, opts)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error retrieving webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	err = cursor.All(ctx, &deliveries)
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "error decoding webhook deliveries", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,14 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"time"
+)
+
+type QualityRepository interface {
+	AddViolations(ctx context.Context, records []models.ViolationRecord) error
+	QuarantineArticle(ctx context.Context, article *models.QuarantinedArticle) error
+	// GetViolationBuckets counts the violations since the given time per feed, day and rule
+	GetViolationBuckets(ctx context.Context, since time.Time) ([]models.ViolationBucket, error)
+}
@@ -0,0 +1,17 @@
+package database
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookRepository interface {
+	AddSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id primitive.ObjectID) (*models.WebhookSubscription, error)
+	GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id primitive.ObjectID) (bool, error)
+	AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, limit int) ([]models.WebhookDelivery, error)
+}
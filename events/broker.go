@@ -0,0 +1,109 @@
+package events
+
+import (
+	"alibazlamit/feed-provider/logging"
+	"alibazlamit/feed-provider/models"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_LOG_SIZE       = 1000
+	SUBSCRIBER_BUFFER_SIZE = 64
+)
+
+// Publisher is what the feed reader needs to announce article changes
+type Publisher interface {
+	Publish(eventType models.SyncOutcome, article *models.NewsArticleInformationMongoDB) models.ArticleEvent
+}
+
+// Broker fans article events out to subscribers and keeps a bounded log of
+// the most recent ones so clients can resume after a disconnect
+type Broker struct {
+	// event IDs count from 1 in every process, the epoch tells one process's IDs from another's
+	epoch       string
+	mu          sync.Mutex
+	lastID      uint64
+	log         []models.ArticleEvent
+	logSize     int
+	subscribers map[chan models.ArticleEvent]struct{}
+}
+
+func NewBroker(logSize int) *Broker {
+	if logSize <= 0 {
+		logSize = DEFAULT_LOG_SIZE
+	}
+	return &Broker{
+		epoch:       logging.NewID(),
+		log:         []models.ArticleEvent{},
+		logSize:     logSize,
+		subscribers: map[chan models.ArticleEvent]struct{}{},
+	}
+}
+
+// Epoch identifies this broker's event IDs, clients resuming with an ID from
+// another epoch have to resync as its events are not in the log
+func (b *Broker) Epoch() string {
+	return b.epoch
+}
+
+// LastID returns the ID of the newest event, 0 before the first one
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+func (b *Broker) Publish(eventType models.SyncOutcome, article *models.NewsArticleInformationMongoDB) models.ArticleEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := models.ArticleEvent{
+		ID:         b.lastID,
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Article:    *article,
+	}
+	b.log = append(b.log, event)
+	if len(b.log) > b.logSize {
+		b.log = b.log[len(b.log)-b.logSize:]
+	}
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// the subscriber can't keep up, drop it so it reconnects and resumes from the log
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return event
+}
+
+// Subscribe returns the logged events after lastEventID and a channel receiving every
+// event published from now on, the channel is closed by cancel or when the subscriber falls behind
+func (b *Broker) Subscribe(lastEventID uint64) ([]models.ArticleEvent, <-chan models.ArticleEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	replay := []models.ArticleEvent{}
+	for _, event := range b.log {
+		if event.ID > lastEventID {
+			replay = append(replay, event)
+		}
+	}
+
+	subscriber := make(chan models.ArticleEvent, SUBSCRIBER_BUFFER_SIZE)
+	b.subscribers[subscriber] = struct{}{}
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[subscriber]; ok {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return replay, subscriber, cancel
+}
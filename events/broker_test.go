@@ -0,0 +1,51 @@
+package events
+
+import (
+	"alibazlamit/feed-provider/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeReplaysAfterLastEventID(t *testing.T) {
+	broker := NewBroker(2)
+	article := &models.NewsArticleInformationMongoDB{Title: "TEST"}
+	broker.Publish(models.ArticleInserted, article)
+	broker.Publish(models.ArticleUpdated, article)
+	broker.Publish(models.ArticleUnpublished, article)
+
+	// the log only keeps the last two events
+	replay, _, cancel := broker.Subscribe(0)
+	cancel()
+	assert.Equal(t, 2, len(replay))
+	assert.Equal(t, uint64(2), replay[0].ID)
+
+	replay, subscription, cancel := broker.Subscribe(2)
+	defer cancel()
+	assert.Equal(t, 1, len(replay))
+	assert.Equal(t, models.ArticleUnpublished, replay[0].Type)
+
+	broker.Publish(models.ArticleUpdated, article)
+	event := <-subscription
+	assert.Equal(t, uint64(4), event.ID)
+	assert.Equal(t, uint64(4), broker.LastID())
+	// the IDs of another broker, another replica or run, restart at 1
+	assert.NotEqual(t, broker.Epoch(), NewBroker(2).Epoch())
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	broker := NewBroker(0)
+	_, subscription, cancel := broker.Subscribe(0)
+	defer cancel()
+
+	article := &models.NewsArticleInformationMongoDB{Title: "TEST"}
+	for i := 0; i <= SUBSCRIBER_BUFFER_SIZE; i++ {
+		broker.Publish(models.ArticleUpdated, article)
+	}
+
+	received := 0
+	for range subscription {
+		received++
+	}
+	assert.Equal(t, SUBSCRIBER_BUFFER_SIZE, received)
+}
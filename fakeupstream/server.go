@@ -0,0 +1,361 @@
+// Package fakeupstream serves an in-memory copy of the InCrowd news API so the
+// reader can be exercised end to end without reaching www.htafc.com. The articles
+// start from the XML fixtures and can be edited, deleted, slowed down or broken
+// while the server runs.
+package fakeupstream
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	NEWS_LIST_PATH   = "/api/incrowd/getnewlistinformation"
+	ARTICLE_PATH     = "/api/incrowd/getnewsarticleinformation"
+	OFFSET_PARAM     = "skip"
+	DEFAULT_COUNT    = 10
+	TIME_LAYOUT      = "2006-01-02 15:04:05"
+	CLUB_NAME        = "Huddersfield Town"
+	CLUB_WEBSITE_URL = "https://www.htafc.com"
+)
+
+//go:embed fixtures/*.xml
+var fixtures embed.FS
+
+// Article is the NewsArticle element of the upstream, dates and flags are kept as
+// the upstream writes them
+type Article struct {
+	ArticleURL        string `xml:"ArticleURL"`
+	NewsArticleID     int    `xml:"NewsArticleID"`
+	PublishDate       string `xml:"PublishDate"`
+	Taxonomies        string `xml:"Taxonomies"`
+	TeaserText        string `xml:"TeaserText"`
+	Subtitle          string `xml:"Subtitle"`
+	ThumbnailImageURL string `xml:"ThumbnailImageURL"`
+	Title             string `xml:"Title"`
+	BodyText          string `xml:"BodyText"`
+	GalleryImageURLs  string `xml:"GalleryImageURLs"`
+	VideoURL          string `xml:"VideoURL"`
+	OptaMatchID       string `xml:"OptaMatchId"`
+	LastUpdateDate    string `xml:"LastUpdateDate"`
+	IsPublished       string `xml:"IsPublished"`
+}
+
+type articleInformation struct {
+	XMLName        xml.Name `xml:"NewsArticleInformation"`
+	ClubName       string   `xml:"ClubName"`
+	ClubWebsiteURL string   `xml:"ClubWebsiteURL"`
+	NewsArticle    Article  `xml:"NewsArticle"`
+}
+
+type newListInformation struct {
+	XMLName        xml.Name   `xml:"NewListInformation"`
+	ClubName       string     `xml:"ClubName"`
+	ClubWebsiteURL string     `xml:"ClubWebsiteURL"`
+	Items          []newsItem `xml:"NewsletterNewsItems>NewsletterNewsItem"`
+}
+
+type newsItem struct {
+	NewsArticleID  int    `xml:"NewsArticleID"`
+	ArticleURL     string `xml:"ArticleURL"`
+	Title          string `xml:"Title"`
+	PublishDate    string `xml:"PublishDate"`
+	LastUpdateDate string `xml:"LastUpdateDate"`
+	IsPublished    string `xml:"IsPublished"`
+}
+
+// Server is an httptest server answering the list and article endpoints
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	articles      map[int]Article
+	latency       time.Duration
+	failNext      int
+	failStatus    int
+	errorRate     float64
+	errorStatus   int
+	malformedList bool
+	malformed     map[int]bool
+	requests      map[string]int
+	random        *rand.Rand
+}
+
+// NewServer starts a fake upstream on a local port, close it when done
+func NewServer() (*Server, error) {
+	s, err := NewUnstartedServer()
+	if err != nil {
+		return nil, err
+	}
+	s.Start()
+	return s, nil
+}
+
+// NewUnstartedServer loads the fixtures without starting the server, so its
+// Listener can be swapped, e.g. for a fixed address
+func NewUnstartedServer() (*Server, error) {
+	s := &Server{
+		articles:  map[int]Article{},
+		malformed: map[int]bool{},
+		requests:  map[string]int{},
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	names, err := fixtures.ReadDir("fixtures")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		content, err := fixtures.ReadFile(path.Join("fixtures", name.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var information articleInformation
+		if err := xml.Unmarshal(content, &information); err != nil {
+			return nil, fmt.Errorf("error reading fixture %s: %v", name.Name(), err)
+		}
+		s.articles[information.NewsArticle.NewsArticleID] = information.NewsArticle
+	}
+	s.Server = httptest.NewUnstartedServer(s)
+	return s, nil
+}
+
+// BaseURL is the value to hand the reader in place of https://www.htafc.com
+func (s *Server) BaseURL() string {
+	return s.URL
+}
+
+// Articles returns the current articles, newest first as the list serves them
+func (s *Server) Articles() []Article {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedArticles()
+}
+
+// Article returns one article and whether it exists
+func (s *Server) Article(id int) (Article, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	article, ok := s.articles[id]
+	return article, ok
+}
+
+// Add publishes a new article, or replaces the article with the same id
+func (s *Server) Add(article Article) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.articles[article.NewsArticleID] = article
+}
+
+// Edit changes an article in place and bumps its LastUpdateDate like an editor saving it would
+func (s *Server) Edit(id int, edit func(*Article)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	article, ok := s.articles[id]
+	if !ok {
+		return fmt.Errorf("article %d not found", id)
+	}
+	edit(&article)
+	article.LastUpdateDate = time.Now().UTC().Format(TIME_LAYOUT)
+	s.articles[id] = article
+	return nil
+}
+
+// Delete removes an article from the list and makes its detail endpoint answer 404
+func (s *Server) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.articles[id]; !ok {
+		return fmt.Errorf("article %d not found", id)
+	}
+	delete(s.articles, id)
+	return nil
+}
+
+// SetLatency delays every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// FailNext answers the next n requests with the given status
+func (s *Server) FailNext(n int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+	s.failStatus = status
+}
+
+// SetErrorRate answers a random share of the requests, between 0 and 1, with the given status
+func (s *Server) SetErrorRate(rate float64, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorRate = rate
+	s.errorStatus = status
+}
+
+// SetMalformed makes the article's detail endpoint return truncated XML
+func (s *Server) SetMalformed(id int, malformed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.malformed[id] = malformed
+}
+
+// SetMalformedList makes the list endpoint return truncated XML
+func (s *Server) SetMalformedList(malformed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.malformedList = malformed
+}
+
+// Requests returns how many requests reached the path, NEWS_LIST_PATH or ARTICLE_PATH
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	latency := s.latency
+	status := 0
+	if s.failNext > 0 {
+		s.failNext--
+		status = s.failStatus
+	} else if s.errorRate > 0 && s.random.Float64() < s.errorRate {
+		status = s.errorStatus
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	switch r.URL.Path {
+	case NEWS_LIST_PATH:
+		s.serveNewsList(w, r)
+	case ARTICLE_PATH:
+		s.serveArticle(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveNewsList(w http.ResponseWriter, r *http.Request) {
+	count, err := queryInt(r, "count", DEFAULT_COUNT)
+	if err != nil || count < 1 {
+		http.Error(w, "invalid count", http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, OFFSET_PARAM, 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid "+OFFSET_PARAM, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	articles := s.sortedArticles()
+	malformed := s.malformedList
+	s.mu.Unlock()
+
+	list := newListInformation{ClubName: CLUB_NAME, ClubWebsiteURL: CLUB_WEBSITE_URL, Items: []newsItem{}}
+	for i := offset; i < len(articles) && i < offset+count; i++ {
+		article := articles[i]
+		list.Items = append(list.Items, newsItem{
+			NewsArticleID:  article.NewsArticleID,
+			ArticleURL:     article.ArticleURL,
+			Title:          article.Title,
+			PublishDate:    article.PublishDate,
+			LastUpdateDate: article.LastUpdateDate,
+			IsPublished:    article.IsPublished,
+		})
+	}
+	writeXML(w, r, list, malformed)
+}
+
+func (s *Server) serveArticle(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	article, ok := s.articles[id]
+	malformed := s.malformed[id]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	information := articleInformation{ClubName: CLUB_NAME, ClubWebsiteURL: CLUB_WEBSITE_URL, NewsArticle: article}
+	writeXML(w, r, information, malformed)
+}
+
+// sortedArticles orders by publish date, newest first, the caller holds the lock
+func (s *Server) sortedArticles() []Article {
+	articles := make([]Article, 0, len(s.articles))
+	for _, article := range s.articles {
+		articles = append(articles, article)
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		if articles[i].PublishDate != articles[j].PublishDate {
+			return articles[i].PublishDate > articles[j].PublishDate
+		}
+		return articles[i].NewsArticleID > articles[j].NewsArticleID
+	})
+	return articles
+}
+
+// writeXML sends the document with an ETag and answers a matching If-None-Match with 304,
+// a malformed document is cut in half
+func writeXML(w http.ResponseWriter, r *http.Request, document interface{}, malformed bool) {
+	body, err := xml.Marshal(document)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+	if malformed {
+		body = body[:len(body)/2]
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(body)
+}
+
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
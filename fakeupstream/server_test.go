@@ -0,0 +1,98 @@
+package fakeupstream
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, s *Server, url string, etag string) (*http.Response, []byte) {
+	req, _ := http.NewRequest(http.MethodGet, s.URL+url, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	response, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	return response, body
+}
+
+func TestNewsListPages(t *testing.T) {
+	s, err := NewServer()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	response, body := get(t, s, NEWS_LIST_PATH+"?count=2&skip=1", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var list newListInformation
+	assert.Nil(t, xml.Unmarshal(body, &list))
+	assert.Equal(t, 2, len(list.Items))
+	articles := s.Articles()
+	assert.Equal(t, articles[1].NewsArticleID, list.Items[0].NewsArticleID)
+	assert.Equal(t, articles[2].NewsArticleID, list.Items[1].NewsArticleID)
+
+	_, body = get(t, s, NEWS_LIST_PATH+"?count=2&skip=100", "")
+	var last newListInformation
+	assert.Nil(t, xml.Unmarshal(body, &last))
+	assert.Empty(t, last.Items)
+	assert.Equal(t, 2, s.Requests(NEWS_LIST_PATH))
+}
+
+func TestArticleEditsAndDeletes(t *testing.T) {
+	s, err := NewServer()
+	assert.Nil(t, err)
+	defer s.Close()
+	id := s.Articles()[0].NewsArticleID
+	url := ARTICLE_PATH + "?id=" + strconv.Itoa(id)
+
+	response, _ := get(t, s, url, "")
+	etag := response.Header.Get("ETag")
+	response, _ = get(t, s, url, etag)
+	assert.Equal(t, http.StatusNotModified, response.StatusCode)
+
+	assert.Nil(t, s.Edit(id, func(article *Article) { article.Title = "Edited" }))
+	response, body := get(t, s, url, etag)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var information articleInformation
+	assert.Nil(t, xml.Unmarshal(body, &information))
+	assert.Equal(t, "Edited", information.NewsArticle.Title)
+	assert.Equal(t, time.Now().UTC().Format("2006-01-02"), information.NewsArticle.LastUpdateDate[:10])
+
+	assert.Nil(t, s.Delete(id))
+	response, _ = get(t, s, url, "")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.NotNil(t, s.Delete(id))
+}
+
+func TestSimulatedFailures(t *testing.T) {
+	s, err := NewServer()
+	assert.Nil(t, err)
+	defer s.Close()
+	id := s.Articles()[0].NewsArticleID
+
+	s.FailNext(2, http.StatusBadGateway)
+	response, _ := get(t, s, NEWS_LIST_PATH, "")
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	response, _ = get(t, s, NEWS_LIST_PATH, "")
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	response, _ = get(t, s, NEWS_LIST_PATH, "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	s.SetMalformed(id, true)
+	_, body := get(t, s, ARTICLE_PATH+"?id="+strconv.Itoa(id), "")
+	var information articleInformation
+	assert.NotNil(t, xml.Unmarshal(body, &information))
+
+	s.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	get(t, s, NEWS_LIST_PATH, "")
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
@@ -0,0 +1,239 @@
+package reader
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ARCHIVE_CHECKPOINT   = "archive"
+	ARCHIVE_PAGE_SIZE    = 50
+	ARCHIVE_OFFSET_PARAM = "skip"
+	ARCHIVE_MAX_PAGES    = 1000
+)
+
+var ErrCrawlInProgress = errors.New("archive crawl already in progress")
+var ErrArchiveNotConfigured = errors.New("archive crawl is not configured")
+
+// ArchiveConfig describes how to page through the upstream list beyond the newest items
+type ArchiveConfig struct {
+	ListURL     string
+	PageSize    int
+	OffsetParam string
+	// the crawl stops after the first page reaching articles published before the cutoff,
+	// a zero cutoff crawls to the end of the archive
+	Cutoff time.Time
+	// safety net against an upstream that never runs out of pages
+	MaxPages int
+}
+
+func DefaultArchiveConfig() ArchiveConfig {
+	return ArchiveConfig{
+		ListURL:     NEWS_LIST_FEED,
+		PageSize:    ARCHIVE_PAGE_SIZE,
+		OffsetParam: ARCHIVE_OFFSET_PARAM,
+		MaxPages:    ARCHIVE_MAX_PAGES,
+	}
+}
+
+// ArchiveConfigFromEnv overrides the defaults with the ARCHIVE_* environment variables
+func ArchiveConfigFromEnv() (ArchiveConfig, error) {
+	config := DefaultArchiveConfig()
+	if err := intFromEnv("ARCHIVE_PAGE_SIZE", &config.PageSize, positive[int]); err != nil {
+		return ArchiveConfig{}, err
+	}
+	if value := os.Getenv("ARCHIVE_OFFSET_PARAM"); value != "" {
+		config.OffsetParam = value
+	}
+	if value := os.Getenv("ARCHIVE_CUTOFF"); value != "" {
+		cutoff, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return ArchiveConfig{}, fmt.Errorf("invalid ARCHIVE_CUTOFF %q", value)
+		}
+		config.Cutoff = cutoff
+	}
+	if err := intFromEnv("ARCHIVE_MAX_PAGES", &config.MaxPages, positive[int]); err != nil {
+		return ArchiveConfig{}, err
+	}
+	return config, nil
+}
+
+type archiveCrawler struct {
+	config      ArchiveConfig
+	checkpoints database.CheckpointRepository
+	running     int32
+}
+
+// WithArchive enables CrawlArchive, progress is checkpointed after every page
+func WithArchive(config ArchiveConfig, checkpoints database.CheckpointRepository) Option {
+	return func(r *Reader) {
+		r.archive = &archiveCrawler{config: config, checkpoints: checkpoints}
+	}
+}
+
+// ArchiveStatus returns the current checkpoint of the archive crawl
+func (r *Reader) ArchiveStatus(ctx context.Context) (*models.CrawlCheckpoint, error) {
+	if r.archive == nil {
+		return nil, ErrArchiveNotConfigured
+	}
+	checkpoint, err := r.archive.checkpoints.GetCheckpoint(ctx, ARCHIVE_CHECKPOINT)
+	if err != nil {
+		return nil, err
+	}
+	// a checkpoint for another cutoff is discarded by the next crawl, so it isn't reported either
+	if checkpoint == nil || !checkpoint.Cutoff.Equal(r.archive.config.Cutoff) {
+		checkpoint = &models.CrawlCheckpoint{Name: ARCHIVE_CHECKPOINT, Cutoff: r.archive.config.Cutoff}
+	}
+	checkpoint.Running = atomic.LoadInt32(&r.archive.running) == 1
+	return checkpoint, nil
+}
+
+// CrawlArchive pages through the upstream list from the last checkpoint until the
+// cutoff date or the end of the archive, restart begins again from the newest page.
+// Articles that failed to sync are kept in the checkpoint and retried first on the next run
+func (r *Reader) CrawlArchive(ctx context.Context, restart bool) error {
+	if r.archive == nil {
+		return ErrArchiveNotConfigured
+	}
+	if !atomic.CompareAndSwapInt32(&r.archive.running, 0, 1) {
+		return ErrCrawlInProgress
+	}
+	defer atomic.StoreInt32(&r.archive.running, 0)
+
+	config := r.archive.config
+	checkpoint, err := r.archive.checkpoints.GetCheckpoint(ctx, ARCHIVE_CHECKPOINT)
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil || restart || !checkpoint.Cutoff.Equal(config.Cutoff) {
+		checkpoint = &models.CrawlCheckpoint{Name: ARCHIVE_CHECKPOINT, Cutoff: config.Cutoff}
+	}
+	checkpoint.LastError = ""
+	if len(checkpoint.FailedArticleIDs) > 0 {
+		retried := len(checkpoint.FailedArticleIDs)
+		_, checkpoint.FailedArticleIDs = r.syncPage(ctx, checkpoint.FailedArticleIDs)
+		checkpoint.ArticlesSynced += retried - len(checkpoint.FailedArticleIDs)
+		if err = r.saveCheckpoint(ctx, checkpoint); err != nil {
+			return err
+		}
+	}
+	if checkpoint.Completed {
+		return nil
+	}
+
+	for page := 0; page < config.MaxPages; page++ {
+		items, err := r.getNewsListPage(ctx, config.pageURL(checkpoint.Offset))
+		if err != nil {
+			checkpoint.LastError = err.Error()
+			r.saveCheckpoint(ctx, checkpoint)
+			return err
+		}
+		if len(items) == 0 {
+			checkpoint.Completed = true
+			checkpoint.StopReason = "end of archive"
+			return r.saveCheckpoint(ctx, checkpoint)
+		}
+
+		articleIDs := make([]int, len(items))
+		for i, item := range items {
+			articleIDs[i] = item.NewsArticleID
+		}
+		oldest, failed := r.syncPage(ctx, articleIDs)
+		checkpoint.Offset += len(items)
+		checkpoint.PagesCrawled++
+		checkpoint.ArticlesSynced += len(items) - len(failed)
+		checkpoint.FailedArticleIDs = append(checkpoint.FailedArticleIDs, failed...)
+		if !oldest.IsZero() && (checkpoint.OldestPublishDate.IsZero() || oldest.Before(checkpoint.OldestPublishDate)) {
+			checkpoint.OldestPublishDate = oldest
+		}
+
+		switch {
+		case !config.Cutoff.IsZero() && !oldest.IsZero() && oldest.Before(config.Cutoff):
+			checkpoint.Completed = true
+			checkpoint.StopReason = "cutoff reached"
+		case len(items) < config.PageSize:
+			checkpoint.Completed = true
+			checkpoint.StopReason = "end of archive"
+		}
+		if err = r.saveCheckpoint(ctx, checkpoint); err != nil || checkpoint.Completed {
+			return err
+		}
+	}
+	r.logger.InfoContext(ctx, "archive crawl paused", "pages", config.MaxPages, "offset", checkpoint.Offset)
+	return nil
+}
+
+func (r *Reader) saveCheckpoint(ctx context.Context, checkpoint *models.CrawlCheckpoint) error {
+	checkpoint.UpdatedAt = time.Now().UTC()
+	return r.archive.checkpoints.SaveCheckpoint(ctx, checkpoint)
+}
+
+// syncPage stores the articles through the worker pool and returns the oldest
+// publish date and the IDs that failed to sync
+func (r *Reader) syncPage(ctx context.Context, articleIDs []int) (time.Time, []int) {
+	var mu sync.Mutex
+	var oldest time.Time
+	var failed []int
+
+	articleIDChan := make(chan int)
+	go func() {
+		for _, articleID := range articleIDs {
+			articleIDChan <- articleID
+		}
+		close(articleIDChan)
+	}()
+	newWorkerPool(r.poolConfig).run(articleIDChan, func(articleID int) error {
+		published, err := r.fetchAndStore(ctx, articleID)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			failed = append(failed, articleID)
+		case !published.IsZero() && (oldest.IsZero() || published.Before(oldest)):
+			oldest = published
+		}
+		return err
+	})
+	sort.Ints(failed)
+	return oldest, failed
+}
+
+// getNewsListPage fetches one archive page, unlike the newest list it is never sent conditionally
+func (r *Reader) getNewsListPage(ctx context.Context, pageURL string) ([]models.NewsletterNewsItem, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.httpClient.Do(req)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "error fetching archive page", "url", pageURL, "error", err)
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, &StatusError{
+			URL:        pageURL,
+			StatusCode: response.StatusCode,
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+		}
+	}
+	return r.readNewsList(ctx, response.Body)
+}
+
+func (c ArchiveConfig) pageURL(offset int) string {
+	query := url.Values{}
+	query.Set("count", strconv.Itoa(c.PageSize))
+	query.Set(c.OffsetParam, strconv.Itoa(offset))
+	return fmt.Sprintf("%s?%s", c.ListURL, query.Encode())
+}
@@ -0,0 +1,137 @@
+package reader
+
+import (
+	"alibazlamit/feed-provider/database"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// archiveTransport serves a five article archive in pages of two, newest first
+type archiveTransport struct {
+	mu           sync.Mutex
+	pages        []string
+	failPages    map[string]bool
+	failArticles map[int]bool
+}
+
+func (a *archiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if strings.HasPrefix(req.URL.String(), ONE_ARTICLE_FEED) {
+		var id int
+		fmt.Sscanf(req.URL.Query().Get("id"), "%d", &id)
+		a.mu.Lock()
+		fail := a.failArticles[id]
+		delete(a.failArticles, id)
+		a.mu.Unlock()
+		if fail {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+		body = fmt.Sprintf(`<NewsArticleInformation><NewsArticle>
+			<NewsArticleID>%d</NewsArticleID>
+			<PublishDate>2023-07-%02d 09:00:00</PublishDate>
+			<LastUpdateDate>2023-07-%02d 09:00:00</LastUpdateDate>
+			</NewsArticle></NewsArticleInformation>`, id, 6-id, 6-id)
+	} else {
+		a.mu.Lock()
+		a.pages = append(a.pages, req.URL.RawQuery)
+		fail := a.failPages[req.URL.RawQuery]
+		delete(a.failPages, req.URL.RawQuery)
+		a.mu.Unlock()
+		if fail {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+
+		var skip int
+		fmt.Sscanf(req.URL.Query().Get("skip"), "%d", &skip)
+		items := ""
+		for id := skip + 1; id <= skip+2 && id <= 5; id++ {
+			items += fmt.Sprintf("<NewsletterNewsItem><NewsArticleID>%d</NewsArticleID></NewsletterNewsItem>", id)
+		}
+		body = "<NewListInformation><NewsletterNewsItems>" + items + "</NewsletterNewsItems></NewListInformation>"
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func newArchiveReader(transport *archiveTransport, config ArchiveConfig) (*Reader, *database.MockArticleRepository, *database.MockCheckpointRepository) {
+	mockRepo := database.NewMockArticleRepository()
+	checkpoints := database.NewMockCheckpointRepository()
+	config.PageSize = 2
+	reader := NewReader(mockRepo, slog.New(slog.DiscardHandler), &http.Client{Transport: transport},
+		WithArchive(config, checkpoints))
+	return reader, mockRepo, checkpoints
+}
+
+func TestCrawlArchiveToTheEnd(t *testing.T) {
+	transport := &archiveTransport{}
+	reader, mockRepo, checkpoints := newArchiveReader(transport, DefaultArchiveConfig())
+
+	err := reader.CrawlArchive(context.Background(), false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"count=2&skip=0", "count=2&skip=2", "count=2&skip=4"}, transport.pages)
+	assert.Equal(t, 5, len(mockRepo.Articles))
+	checkpoint := checkpoints.Checkpoints[ARCHIVE_CHECKPOINT]
+	assert.True(t, checkpoint.Completed)
+	assert.Equal(t, "end of archive", checkpoint.StopReason)
+	// 09:00 in London during BST
+	assert.Equal(t, time.Date(2023, 7, 1, 8, 0, 0, 0, time.UTC), checkpoint.OldestPublishDate)
+}
+
+func TestCrawlArchiveStopsAtCutoffAndResumes(t *testing.T) {
+	transport := &archiveTransport{failPages: map[string]bool{"count=2&skip=2": true}}
+	config := DefaultArchiveConfig()
+	config.Cutoff = time.Date(2023, 7, 3, 12, 0, 0, 0, time.UTC)
+	reader, mockRepo, checkpoints := newArchiveReader(transport, config)
+
+	// the second page fails, the checkpoint keeps the first one
+	err := reader.CrawlArchive(context.Background(), false)
+	assert.Error(t, err)
+	checkpoint := checkpoints.Checkpoints[ARCHIVE_CHECKPOINT]
+	assert.Equal(t, 2, checkpoint.Offset)
+	assert.False(t, checkpoint.Completed)
+	assert.NotEmpty(t, checkpoint.LastError)
+
+	err = reader.CrawlArchive(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"count=2&skip=0", "count=2&skip=2", "count=2&skip=2"}, transport.pages)
+	assert.Equal(t, 4, len(mockRepo.Articles))
+	checkpoint = checkpoints.Checkpoints[ARCHIVE_CHECKPOINT]
+	assert.True(t, checkpoint.Completed)
+	assert.Equal(t, "cutoff reached", checkpoint.StopReason)
+	assert.Equal(t, 4, checkpoint.Offset)
+
+	// a completed crawl only runs again when restarted
+	assert.NoError(t, reader.CrawlArchive(context.Background(), false))
+	assert.Equal(t, 3, len(transport.pages))
+	assert.NoError(t, reader.CrawlArchive(context.Background(), true))
+	assert.Equal(t, 5, len(transport.pages))
+}
+
+func TestCrawlArchiveKeepsFailedArticles(t *testing.T) {
+	transport := &archiveTransport{failArticles: map[int]bool{2: true}}
+	reader, mockRepo, checkpoints := newArchiveReader(transport, DefaultArchiveConfig())
+
+	assert.NoError(t, reader.CrawlArchive(context.Background(), false))
+	checkpoint := checkpoints.Checkpoints[ARCHIVE_CHECKPOINT]
+	assert.True(t, checkpoint.Completed)
+	assert.Equal(t, []int{2}, checkpoint.FailedArticleIDs)
+	assert.Equal(t, 4, checkpoint.ArticlesSynced)
+	assert.Equal(t, 4, len(mockRepo.Articles))
+
+	// the next run only retries the failed article
+	assert.NoError(t, reader.CrawlArchive(context.Background(), false))
+	checkpoint = checkpoints.Checkpoints[ARCHIVE_CHECKPOINT]
+	assert.Empty(t, checkpoint.FailedArticleIDs)
+	assert.Equal(t, 5, checkpoint.ArticlesSynced)
+	assert.Equal(t, 5, len(mockRepo.Articles))
+	assert.Equal(t, 3, len(transport.pages))
+}
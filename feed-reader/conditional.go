@@ -0,0 +1,89 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrNotModified is returned when the upstream answers a conditional request with 304
+var ErrNotModified = errors.New("not modified")
+
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// validatorCache remembers the ETag and Last-Modified the upstream sent for each URL
+type validatorCache struct {
+	mu      sync.Mutex
+	entries map[string]validators
+}
+
+func newValidatorCache() *validatorCache {
+	return &validatorCache{entries: map[string]validators{}}
+}
+
+func (c *validatorCache) get(url string) (validators, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.entries[url]
+	return v, ok
+}
+
+func (c *validatorCache) set(url string, v validators) {
+	if v.etag == "" && v.lastModified == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = v
+}
+
+// forget drops the validators of a URL so the next request fetches it in full
+func (c *validatorCache) forget(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, url)
+}
+
+// conditionalGet sends a GET carrying the validators stored for the url, the
+// validators of a 200 response are stored for the next run
+func (r *Reader) conditionalGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := r.validators.get(url); ok {
+		if v.etag != "" {
+			req.Header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			req.Header.Set("If-Modified-Since", v.lastModified)
+		}
+	}
+
+	response, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotModified {
+		response.Body.Close()
+		return nil, ErrNotModified
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, &StatusError{
+			URL:        url,
+			StatusCode: response.StatusCode,
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+		}
+	}
+
+	r.validators.set(url, validators{
+		etag:         response.Header.Get("ETag"),
+		lastModified: response.Header.Get("Last-Modified"),
+	})
+	return response, nil
+}
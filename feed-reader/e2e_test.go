@@ -0,0 +1,102 @@
+package reader
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/fakeupstream"
+	"alibazlamit/feed-provider/upstream"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestFromFakeUpstream(t *testing.T) {
+	upstream, err := fakeupstream.NewServer()
+	assert.Nil(t, err)
+	defer upstream.Close()
+
+	mockRepo := database.NewMockArticleRepository()
+	reader := NewReader(mockRepo, slog.New(slog.DiscardHandler), upstream.Client(), WithBaseURL(upstream.BaseURL()))
+
+	reader.feedNewsIntoDb()
+	articles := upstream.Articles()
+	assert.Equal(t, len(articles), len(mockRepo.Articles))
+	assert.Equal(t, len(articles), len(mockRepo.Revisions))
+	synced := reader.SyncStatus()
+	assert.False(t, synced.LastSuccess.IsZero())
+	assert.Empty(t, synced.LastError)
+
+	// an unchanged upstream answers 304 and records nothing new
+	reader.feedNewsIntoDb()
+	assert.Equal(t, len(articles), len(mockRepo.Revisions))
+
+	edited := articles[0].NewsArticleID
+	assert.Nil(t, upstream.Edit(edited, func(article *fakeupstream.Article) {
+		article.Title = "Edited title"
+	}))
+	reader.feedNewsIntoDb()
+	stored, _ := mockRepo.GetArticleByNewsArticleID(context.Background(), edited)
+	assert.Equal(t, "Edited title", stored.Title)
+	assert.Equal(t, len(articles)+1, len(mockRepo.Revisions))
+
+	// a broken article doesn't stop the others from syncing
+	broken := articles[1].NewsArticleID
+	upstream.SetMalformed(broken, true)
+	assert.Nil(t, upstream.Edit(broken, func(article *fakeupstream.Article) { article.Title = "Never stored" }))
+	assert.Nil(t, upstream.Edit(articles[2].NewsArticleID, func(article *fakeupstream.Article) { article.Title = "Stored" }))
+	reader.feedNewsIntoDb()
+	stored, _ = mockRepo.GetArticleByNewsArticleID(context.Background(), broken)
+	assert.NotEqual(t, "Never stored", stored.Title)
+	stored, _ = mockRepo.GetArticleByNewsArticleID(context.Background(), articles[2].NewsArticleID)
+	assert.Equal(t, "Stored", stored.Title)
+
+	// a failing list leaves the store as it was
+	upstream.FailNext(1, http.StatusInternalServerError)
+	assert.Nil(t, upstream.Delete(articles[3].NewsArticleID))
+	reader.feedNewsIntoDb()
+	assert.Equal(t, 5, upstream.Requests(fakeupstream.NEWS_LIST_PATH))
+	assert.Equal(t, len(articles), len(mockRepo.Articles))
+	failed := reader.SyncStatus()
+	assert.NotEmpty(t, failed.LastError)
+	assert.True(t, failed.LastRun.After(failed.LastSuccess))
+}
+
+func TestIngestWithOneRequestPerHost(t *testing.T) {
+	fake, err := fakeupstream.NewServer()
+	assert.Nil(t, err)
+	defer fake.Close()
+	template := fake.Articles()[0]
+	for id := 1; id <= 60; id++ {
+		article := template
+		article.NewsArticleID = 900000 + id
+		article.ArticleURL = fmt.Sprintf("%s/news/e2e-%d", fakeupstream.CLUB_WEBSITE_URL, id)
+		fake.Add(article)
+	}
+
+	// the list body holds the only slot until it is closed, the articles must wait for it
+	config := upstream.DefaultConfig()
+	config.MaxConcurrentPerHost = 1
+	config.RequestsPerSecond = 0
+	client, err := upstream.NewClient(config)
+	assert.Nil(t, err)
+	mockRepo := database.NewMockArticleRepository()
+	reader := NewReader(mockRepo, slog.New(slog.DiscardHandler), client, WithBaseURL(fake.BaseURL()))
+
+	done := make(chan struct{})
+	go func() {
+		reader.feedNewsIntoDb()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("sync still running after 30s")
+	}
+	articles, _ := mockRepo.GetAllArticles(context.Background())
+	assert.Equal(t, 50, len(articles))
+	assert.Empty(t, reader.SyncStatus().LastError)
+}
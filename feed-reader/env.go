@@ -0,0 +1,58 @@
+package reader
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// the lookups below leave target alone when the variable is unset and fail on values
+// that don't parse or are out of range, instead of quietly keeping the default
+
+func intFromEnv(name string, target *int, valid func(int) bool) error {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || !valid(value) {
+		return fmt.Errorf("invalid %s %q", name, raw)
+	}
+	*target = value
+	return nil
+}
+
+func floatFromEnv(name string, target *float64, valid func(float64) bool) error {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !valid(value) {
+		return fmt.Errorf("invalid %s %q", name, raw)
+	}
+	*target = value
+	return nil
+}
+
+func durationFromEnv(name string, target *time.Duration, valid func(time.Duration) bool) error {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || !valid(value) {
+		return fmt.Errorf("invalid %s %q", name, raw)
+	}
+	*target = value
+	return nil
+}
+
+func positive[T int | int64 | float64 | time.Duration](value T) bool {
+	return value > 0
+}
+
+func nonNegative[T int | int64 | float64 | time.Duration](value T) bool {
+	return value >= 0
+}
@@ -0,0 +1,271 @@
+package reader
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	MIN_WORKERS      = 2
+	MAX_WORKERS      = 20
+	TARGET_LATENCY   = 1 * time.Second
+	MAX_ERROR_RATE   = 0.2
+	THROTTLE_BACKOFF = 30 * time.Second
+	// the pool re-evaluates its size after this many finished articles
+	ADJUST_EVERY = 5
+)
+
+// pool decisions are published on /debug/vars under reader_pool
+var poolMetrics = expvar.NewMap("reader_pool")
+
+// PoolConfig bounds the adaptive worker pool used to fetch articles
+type PoolConfig struct {
+	MinWorkers int
+	MaxWorkers int
+	// above this average latency per article the pool shrinks, below it grows
+	TargetLatency time.Duration
+	// above this share of failed articles the pool shrinks
+	MaxErrorRate float64
+	// pause after a 429 or 503 that didn't come with a Retry-After
+	ThrottleBackoff time.Duration
+}
+
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MinWorkers:      MIN_WORKERS,
+		MaxWorkers:      MAX_WORKERS,
+		TargetLatency:   TARGET_LATENCY,
+		MaxErrorRate:    MAX_ERROR_RATE,
+		ThrottleBackoff: THROTTLE_BACKOFF,
+	}
+}
+
+// PoolConfigFromEnv overrides the defaults with the READER_* environment variables
+func PoolConfigFromEnv() (PoolConfig, error) {
+	config := DefaultPoolConfig()
+	if err := intFromEnv("READER_MIN_WORKERS", &config.MinWorkers, positive[int]); err != nil {
+		return PoolConfig{}, err
+	}
+	if config.MaxWorkers < config.MinWorkers {
+		config.MaxWorkers = config.MinWorkers
+	}
+	atLeastMin := func(value int) bool { return value >= config.MinWorkers }
+	if err := intFromEnv("READER_MAX_WORKERS", &config.MaxWorkers, atLeastMin); err != nil {
+		return PoolConfig{}, err
+	}
+	if err := durationFromEnv("READER_TARGET_LATENCY", &config.TargetLatency, positive[time.Duration]); err != nil {
+		return PoolConfig{}, err
+	}
+	rate := func(value float64) bool { return value >= 0 && value <= 1 }
+	if err := floatFromEnv("READER_MAX_ERROR_RATE", &config.MaxErrorRate, rate); err != nil {
+		return PoolConfig{}, err
+	}
+	if err := durationFromEnv("READER_THROTTLE_BACKOFF", &config.ThrottleBackoff, nonNegative[time.Duration]); err != nil {
+		return PoolConfig{}, err
+	}
+	return config, nil
+}
+
+type observation struct {
+	latency time.Duration
+	err     error
+}
+
+// workerPool processes article IDs with between MinWorkers and MaxWorkers workers,
+// growing while the upstream is fast and healthy and shrinking when it slows down,
+// fails or throttles us
+type workerPool struct {
+	config      PoolConfig
+	mu          sync.Mutex
+	desired     int
+	active      int
+	pausedUntil time.Time
+	window      []observation
+	wg          sync.WaitGroup
+}
+
+func newWorkerPool(config PoolConfig) *workerPool {
+	if config.MinWorkers < 1 {
+		config.MinWorkers = 1
+	}
+	if config.MaxWorkers < config.MinWorkers {
+		config.MaxWorkers = config.MinWorkers
+	}
+	return &workerPool{
+		config:  config,
+		desired: clamp(WORKERS, config.MinWorkers, config.MaxWorkers),
+	}
+}
+
+// run blocks until every ID from the channel has been processed
+func (p *workerPool) run(ids <-chan int, work func(int) error) {
+	p.mu.Lock()
+	p.spawn(p.desired, ids, work)
+	p.mu.Unlock()
+	p.wg.Wait()
+	poolMetrics.Set("workers", intVar(0))
+}
+
+// spawn must be called with mu held
+func (p *workerPool) spawn(count int, ids <-chan int, work func(int) error) {
+	for i := 0; i < count; i++ {
+		p.active++
+		p.wg.Add(1)
+		go p.worker(ids, work)
+	}
+	poolMetrics.Set("workers", intVar(int64(p.active)))
+}
+
+func (p *workerPool) worker(ids <-chan int, work func(int) error) {
+	defer p.wg.Done()
+	for {
+		pause, exit := p.next()
+		if exit {
+			return
+		}
+		if pause > 0 {
+			time.Sleep(pause)
+			continue
+		}
+
+		id, ok := <-ids
+		if !ok {
+			p.mu.Lock()
+			p.active--
+			p.mu.Unlock()
+			return
+		}
+		start := time.Now()
+		err := work(id)
+		p.observe(observation{latency: time.Since(start), err: err}, ids, work)
+	}
+}
+
+// next tells a worker to exit when the pool has shrunk or how long to pause while throttled
+func (p *workerPool) next() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active > p.desired {
+		p.active--
+		poolMetrics.Set("workers", intVar(int64(p.active)))
+		return 0, true
+	}
+	return time.Until(p.pausedUntil), false
+}
+
+func (p *workerPool) observe(o observation, ids <-chan int, work func(int) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	poolMetrics.Add("articles", 1)
+	if o.err != nil {
+		poolMetrics.Add("errors", 1)
+	}
+
+	var statusErr *StatusError
+	if errors.As(o.err, &statusErr) && statusErr.Throttled() {
+		p.throttle(statusErr.RetryAfter)
+		return
+	}
+
+	p.window = append(p.window, o)
+	if len(p.window) < ADJUST_EVERY {
+		return
+	}
+
+	var total time.Duration
+	failures := 0
+	for _, observed := range p.window {
+		total += observed.latency
+		if observed.err != nil {
+			failures++
+		}
+	}
+	averageLatency := total / time.Duration(len(p.window))
+	errorRate := float64(failures) / float64(len(p.window))
+	p.window = nil
+	poolMetrics.Set("latency_ms", intVar(averageLatency.Milliseconds()))
+	poolMetrics.Set("error_rate", floatVar(errorRate))
+
+	switch {
+	case errorRate > p.config.MaxErrorRate || averageLatency > p.config.TargetLatency:
+		if p.desired > p.config.MinWorkers {
+			p.desired--
+			poolMetrics.Add("scale_downs", 1)
+		}
+	case p.desired < p.config.MaxWorkers:
+		p.desired++
+		poolMetrics.Add("scale_ups", 1)
+		if p.active < p.desired {
+			p.spawn(p.desired-p.active, ids, work)
+		}
+	}
+	poolMetrics.Set("desired_workers", intVar(int64(p.desired)))
+}
+
+// throttle halves the pool and pauses every worker, must be called with mu held
+func (p *workerPool) throttle(retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = p.config.ThrottleBackoff
+	}
+	if until := time.Now().Add(retryAfter); until.After(p.pausedUntil) {
+		p.pausedUntil = until
+	}
+	p.desired = clamp(p.desired/2, p.config.MinWorkers, p.config.MaxWorkers)
+	p.window = nil
+	poolMetrics.Add("throttle_backoffs", 1)
+	poolMetrics.Set("desired_workers", intVar(int64(p.desired)))
+}
+
+// StatusError is returned for upstream responses other than 200 and 304
+type StatusError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status " + strconv.Itoa(e.StatusCode) + " from " + e.URL
+}
+
+// Throttled reports whether the upstream asked us to slow down
+func (e *StatusError) Throttled() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter accepts both the delay-seconds and the HTTP-date form
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+func clamp(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func intVar(value int64) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(value)
+	return v
+}
+
+func floatVar(value float64) *expvar.Float {
+	v := new(expvar.Float)
+	v.Set(value)
+	return v
+}
@@ -0,0 +1,114 @@
+package reader
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func feedIDs(count int) <-chan int {
+	ids := make(chan int)
+	go func() {
+		for i := 0; i < count; i++ {
+			ids <- i
+		}
+		close(ids)
+	}()
+	return ids
+}
+
+func TestWorkerPoolScalesUpWhenHealthy(t *testing.T) {
+	pool := newWorkerPool(PoolConfig{MinWorkers: 1, MaxWorkers: 8, TargetLatency: time.Second, MaxErrorRate: 0.5})
+
+	var processed int32
+	pool.run(feedIDs(100), func(id int) error {
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+
+	assert.Equal(t, int32(100), processed)
+	assert.Equal(t, 8, pool.desired)
+	assert.Equal(t, 0, pool.active)
+}
+
+func TestWorkerPoolScalesDownWhenSlow(t *testing.T) {
+	pool := newWorkerPool(PoolConfig{MinWorkers: 2, MaxWorkers: 8, TargetLatency: time.Millisecond, MaxErrorRate: 0.5})
+
+	pool.run(feedIDs(40), func(id int) error {
+		time.Sleep(3 * time.Millisecond)
+		return nil
+	})
+
+	assert.Equal(t, 2, pool.desired)
+}
+
+func TestWorkerPoolBacksOffWhenThrottled(t *testing.T) {
+	pool := newWorkerPool(PoolConfig{MinWorkers: 1, MaxWorkers: 8, TargetLatency: time.Second, MaxErrorRate: 0.5})
+
+	var mu sync.Mutex
+	var throttledAt time.Time
+	starts := []time.Time{}
+	desiredAfterPause := 0
+	pool.run(feedIDs(20), func(id int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if throttledAt.IsZero() {
+			throttledAt = now
+			return &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 100 * time.Millisecond}
+		}
+		starts = append(starts, now)
+		if desiredAfterPause == 0 && now.Sub(throttledAt) >= 100*time.Millisecond {
+			pool.mu.Lock()
+			desiredAfterPause = pool.desired
+			pool.mu.Unlock()
+		}
+		return nil
+	})
+
+	// only workers already waiting on the channel can slip in during the pause
+	duringPause := 0
+	for _, start := range starts {
+		if start.Sub(throttledAt) < 90*time.Millisecond {
+			duringPause++
+		}
+	}
+	assert.Equal(t, 19, len(starts))
+	assert.LessOrEqual(t, duringPause, WORKERS)
+	// halved, give or take one scale up from the calls that slipped in
+	assert.LessOrEqual(t, desiredAfterPause, WORKERS/2+1)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5"))
+	assert.InDelta(t, float64(time.Minute), float64(parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))), float64(2*time.Second))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+}
+
+func TestPoolConfigFromEnv(t *testing.T) {
+	t.Setenv("READER_MIN_WORKERS", "4")
+	t.Setenv("READER_MAX_ERROR_RATE", "0.5")
+	config, err := PoolConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, config.MinWorkers)
+	assert.GreaterOrEqual(t, config.MaxWorkers, 4)
+	assert.Equal(t, 0.5, config.MaxErrorRate)
+
+	t.Setenv("READER_MAX_WORKERS", "2")
+	_, err = PoolConfigFromEnv()
+	assert.EqualError(t, err, `invalid READER_MAX_WORKERS "2"`)
+
+	t.Setenv("READER_MAX_WORKERS", "")
+	t.Setenv("READER_TARGET_LATENCY", "fast")
+	_, err = PoolConfigFromEnv()
+	assert.EqualError(t, err, `invalid READER_TARGET_LATENCY "fast"`)
+
+	t.Setenv("READER_TARGET_LATENCY", "")
+	t.Setenv("ARCHIVE_CUTOFF", "last year")
+	_, err = ArchiveConfigFromEnv()
+	assert.EqualError(t, err, `invalid ARCHIVE_CUTOFF "last year"`)
+}
@@ -2,12 +2,16 @@ package reader
 
 import (
 	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
+	"alibazlamit/feed-provider/logging"
 	"alibazlamit/feed-provider/models"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,34 +20,106 @@ import (
 
 const (
 	NEWS_ARTICLE_KEY     = "NewsArticleID"
-	ALL_ARTICLES_FEED    = "https://www.htafc.com/api/incrowd/getnewlistinformation?count=50"
-	ONE_ARTICLE_FEED     = "https://www.htafc.com/api/incrowd/getnewsarticleinformation?id="
+	DEFAULT_BASE_URL     = "https://www.htafc.com"
+	NEWS_LIST_PATH       = "/api/incrowd/getnewlistinformation"
+	ONE_ARTICLE_PATH     = "/api/incrowd/getnewsarticleinformation?id="
+	NEWS_LIST_FEED       = DEFAULT_BASE_URL + NEWS_LIST_PATH
+	ALL_ARTICLES_FEED    = NEWS_LIST_FEED + "?count=50"
+	ONE_ARTICLE_FEED     = DEFAULT_BASE_URL + ONE_ARTICLE_PATH
 	WORKERS              = 5
 	CRON_JOB_INTERVAL_MS = 300000
 )
 
 type HTTPClient interface {
-	Get(url string) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
 }
 
 type Reader struct {
 	db         database.ArticleRepository
-	logger     *log.Logger
+	logger     *slog.Logger
 	httpClient HTTPClient
+	events     events.Publisher
+	validators *validatorCache
+	poolConfig PoolConfig
+	archive    *archiveCrawler
+	listLimits ListLimits
+	timeFormat models.TimeFormat
+	validator  *validator
+	// upstream endpoints, the htafc.com ones unless WithBaseURL points elsewhere
+	baseURL     string
+	listFeed    string
+	articleFeed string
+	// last list parsed, reused when the upstream answers 304
+	newsListMu sync.Mutex
+	newsList   []models.NewsletterNewsItem
+	// outcome of the polls, for the readiness check
+	syncMu     sync.Mutex
+	syncStatus models.SyncStatus
 }
 
-func NewReader(db database.ArticleRepository, logger *log.Logger, httpClient HTTPClient) *Reader {
+// Option configures optional Reader behaviour
+type Option func(*Reader)
 
-	return &Reader{
-		db:         db,
-		logger:     logger,
-		httpClient: httpClient,
+// WithEventPublisher makes the reader announce every inserted, updated or unpublished article
+func WithEventPublisher(publisher events.Publisher) Option {
+	return func(r *Reader) {
+		r.events = publisher
 	}
 }
 
+// WithPoolConfig sets the bounds of the adaptive pool fetching the articles
+func WithPoolConfig(config PoolConfig) Option {
+	return func(r *Reader) {
+		r.poolConfig = config
+	}
+}
+
+// WithBaseURL points the reader at another InCrowd compatible upstream, such as the fake one,
+// an archive configured with the default list URL follows it
+func WithBaseURL(baseURL string) Option {
+	return func(r *Reader) {
+		r.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTimeFormat sets the date layouts and timezone of the feed
+func WithTimeFormat(format models.TimeFormat) Option {
+	return func(r *Reader) {
+		r.timeFormat = format
+	}
+}
+
+func NewReader(db database.ArticleRepository, logger *slog.Logger, httpClient HTTPClient, opts ...Option) *Reader {
+
+	r := &Reader{
+		db:          db,
+		logger:      logger,
+		httpClient:  httpClient,
+		validators:  newValidatorCache(),
+		poolConfig:  DefaultPoolConfig(),
+		listLimits:  DefaultListLimits(),
+		timeFormat:  models.DefaultTimeFormat,
+		listFeed:    ALL_ARTICLES_FEED,
+		articleFeed: ONE_ARTICLE_FEED,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.baseURL != "" {
+		r.listFeed = r.baseURL + NEWS_LIST_PATH + "?count=50"
+		r.articleFeed = r.baseURL + ONE_ARTICLE_PATH
+		if r.archive != nil && r.archive.config.ListURL == NEWS_LIST_FEED {
+			r.archive.config.ListURL = r.baseURL + NEWS_LIST_PATH
+		}
+	}
+	return r
+}
+
 func (r *Reader) RunCronFeedReader() error {
 	// run a cron every interval in milliseconds
 	s := gocron.NewScheduler(time.UTC)
+	// a tick doesn't start another sync while the previous one is still running
+	s.SingletonModeAll()
 	_, err := s.Every(CRON_JOB_INTERVAL_MS).Milliseconds().Do(r.feedNewsIntoDb)
 	if err != nil {
 		return err
@@ -53,93 +129,198 @@ func (r *Reader) RunCronFeedReader() error {
 }
 
 func (r *Reader) feedNewsIntoDb() {
-	var wg sync.WaitGroup
-	//read news feed
-	newsListIds, err := r.getNewsList()
-	if err != nil {
-		r.logger.Printf("Error getting news list: %v", err)
-		return
-	}
+	started := time.Now()
+	// every log line of the run carries the same sync id
+	ctx := logging.WithAttrs(context.Background(), "sync_id", logging.NewID())
+	// articles are queued while the list is still being decoded. The queue never blocks the
+	// decoder, so the list body is closed and frees its slot of the upstream's per host
+	// concurrency for the article requests even while every worker is waiting on one
+	queue := newIDQueue()
+	articleIDChan := make(chan int)
+	go queue.drain(articleIDChan)
+	var listErr error
+	go func() {
+		defer queue.close()
+		listErr = r.streamNewsList(ctx, func(item models.NewsletterNewsItem) {
+			queue.push(item.NewsArticleID)
+		})
+		if listErr != nil {
+			r.logger.ErrorContext(ctx, "error getting news list", "error", listErr)
+		}
+	}()
 
-	//create a buffered channel of the number of workers set
-	articleIDChan := make(chan int, WORKERS)
+	// the pool sizes itself to the upstream's latency and error rate
+	newWorkerPool(r.poolConfig).run(articleIDChan, func(articleID int) error {
+		return r.syncArticle(ctx, articleID)
+	})
+	r.recordSync(started, listErr)
+	r.logger.InfoContext(ctx, "sync finished", "duration_ms", time.Since(started).Milliseconds())
+}
 
-	// sync process all articles from feed at the same time
-	for i := 0; i < WORKERS; i++ {
-		go r.processArticles(articleIDChan, r.db, &wg)
-	}
+// SyncStatus returns when the news list was last polled and last polled successfully
+func (r *Reader) SyncStatus() models.SyncStatus {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+	return r.syncStatus
+}
 
-	for _, articleId := range newsListIds {
-		wg.Add(1)
-		articleIDChan <- articleId.NewsArticleID
+func (r *Reader) recordSync(started time.Time, err error) {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+	r.syncStatus.LastRun = started
+	if err != nil {
+		r.syncStatus.LastError = err.Error()
+		return
 	}
+	r.syncStatus.LastSuccess = started
+	r.syncStatus.LastError = ""
+}
 
-	close(articleIDChan)
-	wg.Wait()
+// syncArticle fetches one article and stores it, errors are logged and returned for the pool's statistics
+func (r *Reader) syncArticle(ctx context.Context, articleID int) error {
+	_, err := r.fetchAndStore(ctx, articleID)
+	return err
 }
 
-func (r *Reader) processArticles(articleIDChan <-chan int, db database.ArticleRepository, wg *sync.WaitGroup) {
-	for articleID := range articleIDChan {
-		article, err := r.getFullArticle(articleID)
-		if err != nil {
-			r.logger.Printf("Error getting article with id:%d and error: %v\n", articleID, err)
-			wg.Done()
-			continue
+// fetchAndStore syncs one article and returns its publish date, which comes
+// from the stored copy when the upstream answers 304
+func (r *Reader) fetchAndStore(ctx context.Context, articleID int) (time.Time, error) {
+	started := time.Now()
+	ctx = logging.WithAttrs(ctx, "article_id", articleID)
+	done := func(level slog.Level, outcome string) {
+		r.logger.Log(ctx, level, "article synced", "outcome", outcome, "duration_ms", time.Since(started).Milliseconds())
+	}
+
+	article, err := r.getFullArticle(ctx, articleID)
+	if err == ErrNotModified {
+		done(slog.LevelDebug, "not_modified")
+		stored, err := r.db.GetArticleByNewsArticleID(ctx, articleID)
+		if err != nil || stored == nil {
+			return time.Time{}, err
 		}
-		err = r.db.AddOrUpdateArticle(articleID, article)
-		if err != nil {
-			r.logger.Printf("Error saving article with id:%d and error: %v\n", articleID, err)
+		return stored.PublishDate, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "error getting article", "error", err)
+		return time.Time{}, err
+	}
+	if r.validator != nil && !r.validate(ctx, articleID, article) {
+		done(slog.LevelInfo, "rejected")
+		return article.NewsArticle.PublishDate.Time, nil
+	}
+	saved, outcome, err := r.db.AddOrUpdateArticle(ctx, articleID, article)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "error saving article", "error", err)
+		// make sure the next run doesn't get a 304 for an article we never stored
+		r.validators.forget(r.articleURL(articleID))
+		return time.Time{}, err
+	}
+	if outcome == models.ArticleUnchanged {
+		done(slog.LevelDebug, string(outcome))
+	} else {
+		done(slog.LevelInfo, string(outcome))
+		if r.events != nil {
+			r.events.Publish(outcome, saved)
 		}
-		wg.Done()
 	}
+	return article.NewsArticle.PublishDate.Time, nil
 }
 
 // reading from feed and transforming xml into structs
-func (r *Reader) getNewsList() ([]models.NewsletterNewsItem, error) {
-	response, err := r.httpClient.Get(ALL_ARTICLES_FEED)
+func (r *Reader) getNewsList(ctx context.Context) ([]models.NewsletterNewsItem, error) {
+	newsList := []models.NewsletterNewsItem{}
+	err := r.streamNewsList(ctx, func(item models.NewsletterNewsItem) {
+		newsList = append(newsList, item)
+	})
 	if err != nil {
-		r.logger.Printf("Error fetching the URL: %v", err)
 		return nil, err
 	}
+	return newsList, nil
+}
+
+// streamNewsList hands every item of the newest list to emit as soon as it is decoded,
+// on a 304 the items of the last complete list are replayed instead
+func (r *Reader) streamNewsList(ctx context.Context, emit func(models.NewsletterNewsItem)) error {
+	response, err := r.conditionalGet(ctx, r.listFeed)
+	if err == ErrNotModified {
+		r.newsListMu.Lock()
+		newsList := r.newsList
+		r.newsListMu.Unlock()
+		for _, item := range newsList {
+			emit(item)
+		}
+		return nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "error fetching news list", "url", r.listFeed, "error", err)
+		return err
+	}
 	defer response.Body.Close()
 
-	body, err := io.ReadAll(response.Body)
+	newsList := []models.NewsletterNewsItem{}
+	err = decodeNewsList(response.Body, r.listLimits, func(item models.NewsletterNewsItem) {
+		newsList = append(newsList, item)
+		emit(item)
+	})
 	if err != nil {
-		r.logger.Printf("Error reading response: %v", err)
-		return nil, err
+		r.logger.ErrorContext(ctx, "error decoding news list", "error", err)
+		r.validators.forget(r.listFeed)
+		return err
 	}
 
-	var newsList models.NewListInformation
-	err = xml.Unmarshal(body, &newsList)
+	r.newsListMu.Lock()
+	r.newsList = newsList
+	r.newsListMu.Unlock()
+	return nil
+}
+
+// readNewsList transforms a list response into its items
+func (r *Reader) readNewsList(ctx context.Context, body io.Reader) ([]models.NewsletterNewsItem, error) {
+	newsList := []models.NewsletterNewsItem{}
+	err := decodeNewsList(body, r.listLimits, func(item models.NewsletterNewsItem) {
+		newsList = append(newsList, item)
+	})
 	if err != nil {
-		r.logger.Printf("Error unmarshaling XML: %v", err)
+		r.logger.ErrorContext(ctx, "error decoding news list", "error", err)
 		return nil, err
 	}
-	return newsList.NewsletterNewsItems, nil
+	return newsList, nil
 }
 
 // reading from feed and transforming xml into structs
-func (r *Reader) getFullArticle(articleID int) (*models.NewsArticleInformationXML, error) {
-	url := fmt.Sprintf("%s%d", ONE_ARTICLE_FEED, articleID)
+func (r *Reader) getFullArticle(ctx context.Context, articleID int) (*models.NewsArticleInformationXML, error) {
+	url := r.articleURL(articleID)
 
-	response, err := r.httpClient.Get(url)
+	response, err := r.conditionalGet(ctx, url)
+	if err == ErrNotModified {
+		return nil, err
+	}
 	if err != nil {
-		r.logger.Printf("Error fetching full article with id:%d and error: %v", articleID, err)
+		r.logger.ErrorContext(ctx, "error fetching article", "url", url, "error", err)
 		return nil, err
 	}
 	defer response.Body.Close()
 
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		r.logger.Printf("Error reading response: %v", err)
+		r.logger.ErrorContext(ctx, "error reading article", "url", url, "error", err)
+		r.validators.forget(url)
 		return nil, err
 	}
 
 	var article models.NewsArticleInformationXML
 	err = xml.Unmarshal(body, &article)
+	if err == nil {
+		err = article.NewsArticle.ApplyTimeFormat(r.timeFormat)
+	}
 	if err != nil {
-		r.logger.Printf("Error unmarshaling XML: %v", err)
+		r.logger.ErrorContext(ctx, "error unmarshaling article", "url", url, "error", err)
+		r.validators.forget(url)
 		return nil, err
 	}
 	return &article, nil
 }
+
+func (r *Reader) articleURL(articleID int) string {
+	return fmt.Sprintf("%s%d", r.articleFeed, articleID)
+}
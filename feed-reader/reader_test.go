@@ -2,12 +2,14 @@ package reader
 
 import (
 	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/logging"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
-	"sync"
 	"testing"
 	"time"
 
@@ -19,17 +21,13 @@ type MockHTTPClient struct {
 	err      error
 }
 
-func (c *MockHTTPClient) Get(url string) (*http.Response, error) {
+func (c *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	return c.response, c.err
 }
 
-func TestProcessArticles(t *testing.T) {
-	articleIDChan := make(chan int)
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-
+func TestSyncArticle(t *testing.T) {
 	mockRepo := database.NewMockArticleRepository()
-	mockLogger := log.New(nil, "", 0)
+	mockLogger := slog.New(slog.DiscardHandler)
 	mockHTTPClient := &MockHTTPClient{
 		response: &http.Response{
 			StatusCode: http.StatusOK,
@@ -58,21 +56,17 @@ func TestProcessArticles(t *testing.T) {
 	}
 
 	reader := NewReader(mockRepo, mockLogger, mockHTTPClient)
-	go reader.processArticles(articleIDChan, mockRepo, &wg)
-
-	articleID := 123
-	articleIDChan <- articleID
-	close(articleIDChan)
-	wg.Wait()
-
-	assert.Equal(t, "TEST CITY", mockRepo.Articles[0].ClubName)
-	assert.Equal(t, 1, mockRepo.Articles[0].NewsArticleID)
+	assert.NoError(t, reader.syncArticle(context.Background(), 123))
 
+	articles, err := mockRepo.GetAllArticles(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "TEST CITY", articles[0].ClubName)
+	assert.Equal(t, 1, articles[0].NewsArticleID)
 }
 
 func TestGetFullArticle(t *testing.T) {
 	mockRepo := database.NewMockArticleRepository()
-	mockLogger := log.New(nil, "", 0)
+	mockLogger := slog.New(slog.DiscardHandler)
 	mockHTTPClient := &MockHTTPClient{
 		response: &http.Response{
 			StatusCode: http.StatusOK,
@@ -105,7 +99,7 @@ func TestGetFullArticle(t *testing.T) {
 	reader := NewReader(mockRepo, mockLogger, mockHTTPClient)
 
 	articleID := 123
-	artcl, err := reader.getFullArticle(articleID)
+	artcl, err := reader.getFullArticle(context.Background(), articleID)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -116,7 +110,7 @@ func TestGetFullArticle(t *testing.T) {
 
 func TestGetNewsList(t *testing.T) {
 	mockRepo := database.NewMockArticleRepository()
-	mockLogger := log.New(nil, "", 0)
+	mockLogger := slog.New(slog.DiscardHandler)
 	mockHTTPClient := &MockHTTPClient{
 		response: &http.Response{
 			StatusCode: http.StatusOK,
@@ -148,7 +142,7 @@ func TestGetNewsList(t *testing.T) {
 
 	reader := NewReader(mockRepo, mockLogger, mockHTTPClient)
 
-	newsList, err := reader.getNewsList()
+	newsList, err := reader.getNewsList(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -160,7 +154,7 @@ func TestGetNewsList(t *testing.T) {
 
 func TestRunCronFeedReader(t *testing.T) {
 	mockRepo := database.NewMockArticleRepository()
-	mockLogger := log.New(nil, "", 0)
+	mockLogger := slog.New(slog.DiscardHandler)
 
 	roundTripper := &customRoundTripper{
 		responses: map[string]*http.Response{
@@ -232,7 +226,8 @@ func TestRunCronFeedReader(t *testing.T) {
 	// Wait for the cron jobs to run
 	time.Sleep(2 * time.Second)
 
-	assert.Equal(t, 1, len(mockRepo.Articles))
+	articles, _ := mockRepo.GetAllArticles(context.Background())
+	assert.Equal(t, 1, len(articles))
 }
 
 type customRoundTripper struct {
@@ -248,3 +243,104 @@ func (c *customRoundTripper) RoundTrip(req *http.Request) (*http.Response, error
 
 	return response, nil
 }
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestConditionalRequests(t *testing.T) {
+	mockRepo := database.NewMockArticleRepository()
+	mockLogger := slog.New(slog.DiscardHandler)
+
+	bodies := map[string]string{
+		ALL_ARTICLES_FEED: `<NewListInformation><NewsletterNewsItems>
+			<NewsletterNewsItem><NewsArticleID>3</NewsArticleID></NewsletterNewsItem>
+			</NewsletterNewsItems></NewListInformation>`,
+		fmt.Sprintf("%s%d", ONE_ARTICLE_FEED, 3): `<NewsArticleInformation><NewsArticle>
+			<NewsArticleID>3</NewsArticleID>
+			<PublishDate>2023-07-26 09:45:00</PublishDate>
+			<LastUpdateDate>2023-07-27 02:00:28</LastUpdateDate>
+			</NewsArticle></NewsArticleInformation>`,
+	}
+	notModified := 0
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		etag := fmt.Sprintf(`"%d"`, len(bodies[req.URL.String()]))
+		if req.Header.Get("If-None-Match") == etag {
+			notModified++
+			return &http.Response{StatusCode: http.StatusNotModified, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{etag}},
+			Body:       io.NopCloser(strings.NewReader(bodies[req.URL.String()])),
+		}, nil
+	})}
+
+	reader := NewReader(mockRepo, mockLogger, client)
+	reader.feedNewsIntoDb()
+	assert.Equal(t, 0, notModified)
+	assert.Equal(t, 1, len(mockRepo.Revisions))
+
+	// the list is served from the last parse and the unchanged article is never re-parsed
+	reader.feedNewsIntoDb()
+	assert.Equal(t, 2, notModified)
+	assert.Equal(t, 1, len(mockRepo.Articles))
+	assert.Equal(t, 1, len(mockRepo.Revisions))
+}
+
+func TestSyncArticleLogFields(t *testing.T) {
+	logs := &strings.Builder{}
+	logger, level := logging.New(logs, logging.Config{Level: slog.LevelInfo, Format: logging.FORMAT_TEXT})
+	mockRepo := database.NewMockArticleRepository()
+	article := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(strings.NewReader(`<NewsArticleInformation><ClubName>TEST CITY</ClubName><NewsArticle>
+			<NewsArticleID>7</NewsArticleID><PublishDate>2023-07-26 09:45:00</PublishDate><Title>TEST</Title>
+			<LastUpdateDate>2023-07-27 02:00:28</LastUpdateDate><IsPublished>True</IsPublished>
+			</NewsArticle></NewsArticleInformation>`)),
+		}
+	}
+	mockHTTPClient := &MockHTTPClient{response: article()}
+	reader := NewReader(mockRepo, logger, mockHTTPClient)
+	ctx := logging.WithAttrs(context.Background(), "sync_id", "sync-1")
+
+	assert.NoError(t, reader.syncArticle(ctx, 7))
+	assert.Contains(t, logs.String(), `msg="article synced" outcome=inserted`)
+	assert.Contains(t, logs.String(), "sync_id=sync-1 article_id=7")
+
+	// unchanged articles only show up at debug level
+	logs.Reset()
+	mockHTTPClient.response = article()
+	assert.NoError(t, reader.syncArticle(ctx, 7))
+	assert.Empty(t, logs.String())
+
+	level.Set(slog.LevelDebug)
+	mockHTTPClient.response = article()
+	assert.NoError(t, reader.syncArticle(ctx, 7))
+	assert.Contains(t, logs.String(), "outcome=unchanged")
+}
+
+func TestSyncArticleSuffixesCollidingSlugs(t *testing.T) {
+	urls := map[string]string{"1": "https://www.htafc.com/news/a-b", "2": "https://www.htafc.com/news-a/b"}
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		id := req.URL.Query().Get("id")
+		body := fmt.Sprintf(`<NewsArticleInformation><NewsArticle>
+			<ArticleURL>%s</ArticleURL><NewsArticleID>%s</NewsArticleID><Title>TEST</Title>
+			</NewsArticle></NewsArticleInformation>`, urls[id], id)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	mockRepo := database.NewMockArticleRepository()
+	reader := NewReader(mockRepo, slog.New(slog.DiscardHandler), client)
+
+	// both paths join to news-a-b, the second article is stored under its ID
+	assert.NoError(t, reader.syncArticle(context.Background(), 1))
+	assert.NoError(t, reader.syncArticle(context.Background(), 2))
+	assert.NoError(t, reader.syncArticle(context.Background(), 2))
+	first, _ := mockRepo.GetArticleByNewsArticleID(context.Background(), 1)
+	second, _ := mockRepo.GetArticleByNewsArticleID(context.Background(), 2)
+	assert.Equal(t, "news-a-b", first.Slug)
+	assert.Equal(t, "news-a-b-2", second.Slug)
+}
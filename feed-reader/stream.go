@@ -0,0 +1,166 @@
+package reader
+
+import (
+	"alibazlamit/feed-provider/models"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	MAX_LIST_BYTES = 32 << 20
+	MAX_LIST_ITEMS = 10000
+	NEWS_ITEM_TAG  = "NewsletterNewsItem"
+)
+
+var ErrListTooLarge = errors.New("news list exceeds the maximum body size")
+var ErrTooManyItems = errors.New("news list exceeds the maximum number of items")
+
+// ListLimits bound what the reader accepts from a single list response
+type ListLimits struct {
+	MaxBytes int64
+	MaxItems int
+}
+
+func DefaultListLimits() ListLimits {
+	return ListLimits{
+		MaxBytes: MAX_LIST_BYTES,
+		MaxItems: MAX_LIST_ITEMS,
+	}
+}
+
+// ListLimitsFromEnv overrides the defaults with READER_MAX_LIST_BYTES and READER_MAX_LIST_ITEMS
+func ListLimitsFromEnv() (ListLimits, error) {
+	limits := DefaultListLimits()
+	maxBytes := int(limits.MaxBytes)
+	if err := intFromEnv("READER_MAX_LIST_BYTES", &maxBytes, positive[int]); err != nil {
+		return ListLimits{}, err
+	}
+	limits.MaxBytes = int64(maxBytes)
+	if err := intFromEnv("READER_MAX_LIST_ITEMS", &limits.MaxItems, positive[int]); err != nil {
+		return ListLimits{}, err
+	}
+	return limits, nil
+}
+
+// WithListLimits sets the maximum body size and item count of list responses
+func WithListLimits(limits ListLimits) Option {
+	return func(r *Reader) {
+		r.listLimits = limits
+	}
+}
+
+// limitedReader fails instead of silently truncating once more than max bytes were read,
+// it reads one byte past the limit so a body of exactly max bytes still reaches EOF
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrListTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.reader.Read(p)
+	if int64(n) > l.remaining {
+		n, l.remaining = int(l.remaining), -1
+		return n, ErrListTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// decodeNewsList walks the list token by token and hands every NewsletterNewsItem
+// to emit as soon as it is decoded, without holding the document in memory
+func decodeNewsList(body io.Reader, limits ListLimits, emit func(models.NewsletterNewsItem)) error {
+	if limits.MaxBytes > 0 {
+		body = &limitedReader{reader: body, remaining: limits.MaxBytes}
+	}
+	decoder := xml.NewDecoder(body)
+
+	items := 0
+	sawRoot := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if !sawRoot {
+				return fmt.Errorf("empty news list response")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		sawRoot = true
+		if start.Name.Local != NEWS_ITEM_TAG {
+			continue
+		}
+		if limits.MaxItems > 0 && items >= limits.MaxItems {
+			return ErrTooManyItems
+		}
+
+		var item models.NewsletterNewsItem
+		if err = decoder.DecodeElement(&item, &start); err != nil {
+			return err
+		}
+		items++
+		emit(item)
+	}
+}
+
+// idQueue is an unbounded FIFO of article IDs between the list decoder and the pool
+type idQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	ids    []int
+	closed bool
+}
+
+func newIDQueue() *idQueue {
+	q := &idQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *idQueue) push(id int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ids = append(q.ids, id)
+	q.cond.Signal()
+}
+
+func (q *idQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Signal()
+}
+
+// drain sends the IDs to out in order and closes out once the queue is closed and empty
+func (q *idQueue) drain(out chan<- int) {
+	defer close(out)
+	for {
+		q.mu.Lock()
+		for len(q.ids) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.ids) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		id := q.ids[0]
+		q.ids = q.ids[1:]
+		q.mu.Unlock()
+		out <- id
+	}
+}
@@ -0,0 +1,65 @@
+package reader
+
+import (
+	"alibazlamit/feed-provider/models"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeNewsListEmitsBeforeTheDocumentEnds(t *testing.T) {
+	body, writer := io.Pipe()
+	emitted := make(chan int, 2)
+	done := make(chan error)
+	go func() {
+		done <- decodeNewsList(body, DefaultListLimits(), func(item models.NewsletterNewsItem) {
+			emitted <- item.NewsArticleID
+		})
+	}()
+
+	writer.Write([]byte(`<NewListInformation><NewsletterNewsItems>
+		<NewsletterNewsItem><NewsArticleID>1</NewsArticleID><IsPublished>true</IsPublished></NewsletterNewsItem>`))
+	select {
+	case id := <-emitted:
+		assert.Equal(t, 1, id)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the first item before the rest of the list was sent")
+	}
+
+	writer.Write([]byte(`<NewsletterNewsItem><NewsArticleID>2</NewsArticleID></NewsletterNewsItem>
+		</NewsletterNewsItems></NewListInformation>`))
+	writer.Close()
+	assert.NoError(t, <-done)
+	assert.Equal(t, 2, <-emitted)
+}
+
+func TestDecodeNewsListLimits(t *testing.T) {
+	list := `<NewListInformation><NewsletterNewsItems>
+		<NewsletterNewsItem><NewsArticleID>1</NewsArticleID></NewsletterNewsItem>
+		<NewsletterNewsItem><NewsArticleID>2</NewsArticleID></NewsletterNewsItem>
+		<NewsletterNewsItem><NewsArticleID>3</NewsArticleID></NewsletterNewsItem>
+		</NewsletterNewsItems></NewListInformation>`
+
+	ids := []int{}
+	err := decodeNewsList(strings.NewReader(list), ListLimits{MaxItems: 2}, func(item models.NewsletterNewsItem) {
+		ids = append(ids, item.NewsArticleID)
+	})
+	assert.Equal(t, ErrTooManyItems, err)
+	assert.Equal(t, []int{1, 2}, ids)
+
+	err = decodeNewsList(strings.NewReader(list), ListLimits{MaxBytes: 100}, func(item models.NewsletterNewsItem) {})
+	assert.ErrorIs(t, err, ErrListTooLarge)
+
+	// a list of exactly MaxBytes still fits, one more byte doesn't
+	size := int64(len(list))
+	err = decodeNewsList(strings.NewReader(list), ListLimits{MaxBytes: size}, func(item models.NewsletterNewsItem) {})
+	assert.NoError(t, err)
+	err = decodeNewsList(strings.NewReader(list), ListLimits{MaxBytes: size - 1}, func(item models.NewsletterNewsItem) {})
+	assert.ErrorIs(t, err, ErrListTooLarge)
+
+	err = decodeNewsList(strings.NewReader(""), DefaultListLimits(), func(item models.NewsletterNewsItem) {})
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package reader
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/models"
+	"alibazlamit/feed-provider/validation"
+	"context"
+	"time"
+)
+
+const DEFAULT_FEED_NAME = "htafc"
+
+type validator struct {
+	feed    string
+	rules   validation.Rules
+	quality database.QualityRepository
+}
+
+// WithValidation checks every fetched article against the rules, records the
+// violations for the data-quality report and applies the rules' policy
+func WithValidation(feed string, rules validation.Rules, quality database.QualityRepository) Option {
+	return func(r *Reader) {
+		if feed == "" {
+			feed = DEFAULT_FEED_NAME
+		}
+		r.validator = &validator{feed: feed, rules: rules, quality: quality}
+	}
+}
+
+// validate reports whether the article should be stored, attaching the violations
+// as warnings when the policy allows storing it anyway
+func (r *Reader) validate(ctx context.Context, articleID int, article *models.NewsArticleInformationXML) bool {
+	now := time.Now().UTC()
+	violations := r.validator.rules.Validate(article, now)
+	if len(violations) == 0 {
+		return true
+	}
+
+	policy := r.validator.rules.Policy
+	contentHash := models.ConvertToMongoDB(article).ContentHash
+	records := make([]models.ViolationRecord, len(violations))
+	for i, violation := range violations {
+		records[i] = models.ViolationRecord{
+			Feed:          r.validator.feed,
+			NewsArticleID: articleID,
+			Field:         violation.Field,
+			Rule:          violation.Rule,
+			Message:       violation.Message,
+			Action:        policy,
+			ContentHash:   contentHash,
+			OccurredAt:    now,
+		}
+	}
+	if err := r.validator.quality.AddViolations(ctx, records); err != nil {
+		r.logger.ErrorContext(ctx, "error recording violations", "error", err)
+	}
+
+	switch policy {
+	case models.PolicyReject:
+		r.logger.InfoContext(ctx, "rejected article", "violations", len(violations))
+		return false
+	case models.PolicyQuarantine:
+		quarantined := &models.QuarantinedArticle{
+			Feed:          r.validator.feed,
+			NewsArticleID: articleID,
+			Article:       *models.ConvertToMongoDB(article),
+			Violations:    violations,
+			QuarantinedAt: now,
+		}
+		if err := r.validator.quality.QuarantineArticle(ctx, quarantined); err != nil {
+			// let the next run try again
+			r.validators.forget(r.articleURL(articleID))
+		}
+		return false
+	default:
+		article.Warnings = []string{}
+		for _, violation := range violations {
+			article.Warnings = append(article.Warnings, violation.Field+": "+violation.Message)
+		}
+		return true
+	}
+}
@@ -0,0 +1,68 @@
+package reader
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/models"
+	"alibazlamit/feed-provider/validation"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// an article without a title or an absolute URL
+const invalidArticle = `<NewsArticleInformation><NewsArticle>
+	<ArticleURL>/news/7</ArticleURL>
+	<NewsArticleID>7</NewsArticleID>
+	<PublishDate>2023-07-26 09:45:00</PublishDate>
+	<BodyText>test</BodyText>
+	<Title></Title>
+	</NewsArticle></NewsArticleInformation>`
+
+func validatingReader(policy models.ValidationPolicy) (*Reader, *database.MockArticleRepository, *database.MockQualityRepository) {
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(invalidArticle))}, nil
+	})}
+	rules := validation.DefaultRules()
+	rules.Policy = policy
+	articles := database.NewMockArticleRepository()
+	quality := database.NewMockQualityRepository()
+	reader := NewReader(articles, slog.New(slog.DiscardHandler), client, WithValidation("", rules, quality))
+	return reader, articles, quality
+}
+
+func TestValidationPolicies(t *testing.T) {
+	reader, articles, quality := validatingReader(models.PolicyReject)
+	assert.Nil(t, reader.syncArticle(context.Background(), 7))
+	assert.Empty(t, articles.Articles)
+	assert.Empty(t, quality.Quarantined)
+	assert.Equal(t, 2, len(quality.Violations))
+	assert.Equal(t, DEFAULT_FEED_NAME, quality.Violations[0].Feed)
+	assert.Equal(t, models.PolicyReject, quality.Violations[0].Action)
+
+	reader, articles, quality = validatingReader(models.PolicyQuarantine)
+	assert.Nil(t, reader.syncArticle(context.Background(), 7))
+	assert.Empty(t, articles.Articles)
+	assert.Equal(t, 1, len(quality.Quarantined))
+	assert.Equal(t, 7, quality.Quarantined[0].NewsArticleID)
+	assert.Equal(t, 2, len(quality.Quarantined[0].Violations))
+
+	reader, articles, quality = validatingReader(models.PolicyStoreWithWarnings)
+	assert.Nil(t, reader.syncArticle(context.Background(), 7))
+	assert.Equal(t, 1, len(articles.Articles))
+	assert.Equal(t, []string{"Title: title is empty", `ArticleURL: "/news/7" is not an absolute http(s) URL`}, articles.Articles[0].Warnings)
+	assert.Equal(t, 2, len(quality.Violations))
+}
+
+func TestValidationRecordsUnchangedContentOnce(t *testing.T) {
+	// the transport sends no ETag, so every sync fetches and validates the article again
+	reader, _, quality := validatingReader(models.PolicyReject)
+	assert.Nil(t, reader.syncArticle(context.Background(), 7))
+	assert.Nil(t, reader.syncArticle(context.Background(), 7))
+	assert.Equal(t, 2, len(quality.Violations))
+	assert.NotEmpty(t, quality.Violations[0].ContentHash)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"alibazlamit/feed-provider/feeds"
+	"alibazlamit/feed-provider/httpcache"
+	"alibazlamit/feed-provider/models"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// getFeed renders the stored articles as RSS 2.0 or Atom,
+// optionally narrowed down to a club or a taxonomy by the route
+func getFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	format := vars["format"]
+	filter := models.ArticleFilter{
+		Club:     vars["club"],
+		Taxonomy: vars["taxonomy"],
+	}
+
+	articles, err := articleRepository.GetAllArticles(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Error retrieving articles", err)
+		return
+	}
+	articles = feeds.Select(articles, filter)
+
+	// the body embeds links built from the request's host, so the tag depends on them too
+	channel := feedChannel(r, filter)
+	parts := []string{format, channel.SelfLink}
+	for _, article := range articles {
+		parts = append(parts, article.ID.Hex(), contentHash(&article))
+	}
+	validators := httpcache.Validators{
+		ETag:         httpcache.StrongETag(parts...),
+		LastModified: feeds.LastModified(articles),
+	}
+	if httpcache.NotModified(w, r, validators) {
+		return
+	}
+
+	var body []byte
+	contentType := "application/rss+xml; charset=utf-8"
+	if format == "atom" {
+		contentType = "application/atom+xml; charset=utf-8"
+		body, err = feeds.BuildAtom(channel, articles)
+	} else {
+		body, err = feeds.BuildRSS(channel, articles)
+	}
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error encoding feed", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func feedChannel(r *http.Request, filter models.ArticleFilter) feeds.Channel {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	base := fmt.Sprintf("%s://%s", scheme, r.Host)
+
+	title := "News"
+	switch {
+	case filter.Club != "":
+		title = fmt.Sprintf("%s news", filter.Club)
+	case filter.Taxonomy != "":
+		title = fmt.Sprintf("%s news", filter.Taxonomy)
+	}
+	return feeds.Channel{
+		Title:       title,
+		Link:        base + "/articles",
+		SelfLink:    base + r.URL.RequestURI(),
+		Description: fmt.Sprintf("Latest %d published articles", feeds.MAX_ITEMS),
+	}
+}
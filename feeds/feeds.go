@@ -0,0 +1,238 @@
+package feeds
+
+import (
+	"alibazlamit/feed-provider/models"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MAX_ITEMS          = 50
+	DEFAULT_IMAGE_TYPE = "image/jpeg"
+)
+
+// Channel describes the feed being rendered
+type Channel struct {
+	Title       string
+	Link        string
+	SelfLink    string
+	Description string
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	SelfLink      atomLink  `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description"`
+	Categories  []string      `xml:"category"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Author     atomAuthor     `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Select returns the published articles matching the filter, newest first
+func Select(articles []models.NewsArticleInformationMongoDB, filter models.ArticleFilter) []models.NewsArticleInformationMongoDB {
+	selected := []models.NewsArticleInformationMongoDB{}
+	for _, article := range articles {
+		if article.IsPublished && filter.Matches(&article) {
+			selected = append(selected, article)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].PublishDate.After(selected[j].PublishDate)
+	})
+	if len(selected) > MAX_ITEMS {
+		selected = selected[:MAX_ITEMS]
+	}
+	return selected
+}
+
+// LastModified returns the newest update time among the articles
+func LastModified(articles []models.NewsArticleInformationMongoDB) time.Time {
+	var lastModified time.Time
+	for _, article := range articles {
+		updated := updatedAt(&article)
+		if updated.After(lastModified) {
+			lastModified = updated
+		}
+	}
+	return lastModified
+}
+
+func BuildRSS(channel Channel, articles []models.NewsArticleInformationMongoDB) ([]byte, error) {
+	feed := rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       channel.Title,
+			Link:        channel.Link,
+			SelfLink:    atomLink{Href: channel.SelfLink, Rel: "self", Type: "application/rss+xml"},
+			Description: channel.Description,
+			Items:       []rssItem{},
+		},
+	}
+	if lastModified := LastModified(articles); !lastModified.IsZero() {
+		feed.Channel.LastBuildDate = lastModified.Format(time.RFC1123Z)
+	}
+
+	for _, article := range articles {
+		item := rssItem{
+			Title:       article.Title,
+			Link:        article.ArticleURL,
+			GUID:        rssGUID{Value: guid(&article)},
+			Description: summary(&article),
+			Categories:  article.TaxonomyList(),
+			PubDate:     article.PublishDate.Format(time.RFC1123Z),
+		}
+		if article.ThumbnailImageURL != "" {
+			// the upstream doesn't tell us the image size, 0 is the accepted placeholder
+			item.Enclosure = &rssEnclosure{URL: article.ThumbnailImageURL, Type: imageType(article.ThumbnailImageURL)}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return marshal(feed)
+}
+
+func BuildAtom(channel Channel, articles []models.NewsArticleInformationMongoDB) ([]byte, error) {
+	feed := atomFeed{
+		ID:    channel.SelfLink,
+		Title: channel.Title,
+		Links: []atomLink{
+			{Href: channel.SelfLink, Rel: "self", Type: "application/atom+xml"},
+			{Href: channel.Link, Rel: "alternate"},
+		},
+		Updated: LastModified(articles).Format(time.RFC3339),
+		Entries: []atomEntry{},
+	}
+
+	for _, article := range articles {
+		entry := atomEntry{
+			ID:        guid(&article),
+			Title:     article.Title,
+			Updated:   updatedAt(&article).Format(time.RFC3339),
+			Published: article.PublishDate.Format(time.RFC3339),
+			Author:    atomAuthor{Name: article.ClubName, URI: article.ClubWebsiteURL},
+			Links:     []atomLink{{Href: article.ArticleURL, Rel: "alternate", Type: "text/html"}},
+			Summary:   article.TeaserText,
+			Content:   atomContent{Type: "html", Value: article.BodyText},
+		}
+		if article.ThumbnailImageURL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: article.ThumbnailImageURL, Rel: "enclosure", Type: imageType(article.ThumbnailImageURL)})
+		}
+		for _, taxonomy := range article.TaxonomyList() {
+			entry.Categories = append(entry.Categories, atomCategory{Term: taxonomy})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshal(feed)
+}
+
+func marshal(feed interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// guid prefers the article URL and falls back to a URN of the upstream ID
+func guid(article *models.NewsArticleInformationMongoDB) string {
+	if article.ArticleURL != "" {
+		return article.ArticleURL
+	}
+	return fmt.Sprintf("urn:feed-provider:article:%d", article.NewsArticleID)
+}
+
+func summary(article *models.NewsArticleInformationMongoDB) string {
+	if article.TeaserText != "" {
+		return article.TeaserText
+	}
+	return article.BodyText
+}
+
+func updatedAt(article *models.NewsArticleInformationMongoDB) time.Time {
+	if article.LastUpdateDate.After(article.PublishDate) {
+		return article.LastUpdateDate
+	}
+	return article.PublishDate
+}
+
+func imageType(imageURL string) string {
+	extension := strings.ToLower(path.Ext(strings.SplitN(imageURL, "?", 2)[0]))
+	if mimeType := mime.TypeByExtension(extension); strings.HasPrefix(mimeType, "image/") {
+		return mimeType
+	}
+	return DEFAULT_IMAGE_TYPE
+}
@@ -0,0 +1,67 @@
+package feeds
+
+import (
+	"alibazlamit/feed-provider/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testArticles = []models.NewsArticleInformationMongoDB{
+	{
+		ClubName:          "TEST CITY",
+		ArticleURL:        "https://test.com/news/old",
+		Title:             "Old",
+		Taxonomies:        "First Team, Academy",
+		ThumbnailImageURL: "https://test.com/old.png",
+		PublishDate:       time.Date(2023, 7, 25, 9, 0, 0, 0, time.UTC),
+		IsPublished:       true,
+	},
+	{
+		ClubName:    "TEST CITY",
+		ArticleURL:  "https://test.com/news/new",
+		Title:       "New",
+		Taxonomies:  "First Team",
+		PublishDate: time.Date(2023, 7, 26, 9, 0, 0, 0, time.UTC),
+		IsPublished: true,
+	},
+	{
+		ClubName:    "TEST CITY",
+		Title:       "Draft",
+		PublishDate: time.Date(2023, 7, 27, 9, 0, 0, 0, time.UTC),
+	},
+}
+
+func TestSelect(t *testing.T) {
+	articles := Select(testArticles, models.ArticleFilter{})
+	assert.Equal(t, 2, len(articles))
+	assert.Equal(t, "New", articles[0].Title)
+
+	articles = Select(testArticles, models.ArticleFilter{Taxonomy: "academy"})
+	assert.Equal(t, 1, len(articles))
+	assert.Equal(t, "Old", articles[0].Title)
+}
+
+func TestBuildRSS(t *testing.T) {
+	body, err := BuildRSS(Channel{Title: "News", Link: "http://localhost/articles"}, Select(testArticles, models.ArticleFilter{}))
+	assert.NoError(t, err)
+
+	rss := string(body)
+	assert.True(t, strings.HasPrefix(rss, "<?xml"))
+	assert.Contains(t, rss, `<enclosure url="https://test.com/old.png" type="image/png" length="0"></enclosure>`)
+	assert.Contains(t, rss, "<category>Academy</category>")
+	assert.Contains(t, rss, "<pubDate>Wed, 26 Jul 2023 09:00:00 +0000</pubDate>")
+	assert.NotContains(t, rss, "Draft")
+}
+
+func TestBuildAtom(t *testing.T) {
+	body, err := BuildAtom(Channel{Title: "News", SelfLink: "http://localhost/feeds/atom.xml"}, Select(testArticles, models.ArticleFilter{}))
+	assert.NoError(t, err)
+
+	atom := string(body)
+	assert.Contains(t, atom, `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, atom, `<link href="https://test.com/old.png" rel="enclosure" type="image/png"></link>`)
+	assert.Contains(t, atom, "<updated>2023-07-26T09:00:00Z</updated>")
+}
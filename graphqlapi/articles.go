@@ -0,0 +1,205 @@
+package graphqlapi
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DEFAULT_PAGE_SIZE    = 20
+	MAX_PAGE_SIZE        = 100
+	DEFAULT_RELATED_SIZE = 5
+	CURSOR_PREFIX        = "article:"
+	SNAPSHOT_TTL         = 30 * time.Second
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// snapshot caches the published articles for the fields that need every one of them, the
+// clubs, the taxonomies and the related articles. It is shared by every request and reloaded
+// at most every SNAPSHOT_TTL, article lists are filtered and paged by the repository instead
+type snapshot struct {
+	repository database.ArticleRepository
+	ttl        time.Duration
+	mu         sync.Mutex
+	loadedAt   time.Time
+	articles   []models.NewsArticleInformationMongoDB
+}
+
+func newSnapshot(repository database.ArticleRepository) *snapshot {
+	return &snapshot{repository: repository, ttl: SNAPSHOT_TTL}
+}
+
+// load returns the published articles, newest first
+func (s *snapshot) load(ctx context.Context) ([]models.NewsArticleInformationMongoDB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loadedAt.IsZero() && time.Since(s.loadedAt) < s.ttl {
+		return s.articles, nil
+	}
+	page, err := s.repository.FindArticles(ctx, models.ArticleQuery{Limit: -1})
+	if err != nil {
+		return nil, err
+	}
+	s.articles, s.loadedAt = page.Articles, time.Now()
+	return s.articles, nil
+}
+
+func selectArticles(articles []models.NewsArticleInformationMongoDB, query models.ArticleQuery) []models.NewsArticleInformationMongoDB {
+	selected := []models.NewsArticleInformationMongoDB{}
+	for i := range articles {
+		if query.Matches(&articles[i]) {
+			selected = append(selected, articles[i])
+		}
+	}
+	return selected
+}
+
+type edge struct {
+	Cursor string
+	Node   models.NewsArticleInformationMongoDB
+}
+
+type pageInfo struct {
+	HasNextPage bool
+	EndCursor   *string
+}
+
+type connection struct {
+	Edges      []edge
+	PageInfo   pageInfo
+	TotalCount int
+}
+
+func encodeCursor(article *models.NewsArticleInformationMongoDB) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(CURSOR_PREFIX + article.ID.Hex()))
+}
+
+func decodeCursor(cursor string) (primitive.ObjectID, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), CURSOR_PREFIX) {
+		return primitive.NilObjectID, ErrInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(strings.TrimPrefix(string(decoded), CURSOR_PREFIX))
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// paginate asks the repository for first articles matching the query after the one the cursor points at
+func paginate(ctx context.Context, repository database.ArticleRepository, query models.ArticleQuery, first int, after string) (connection, error) {
+	if after != "" {
+		id, err := decodeCursor(after)
+		if err != nil {
+			return connection{}, err
+		}
+		query.After = id
+	}
+	query.Limit = first
+	result, err := repository.FindArticles(ctx, query)
+	if err == database.ErrCursorNotFound {
+		return connection{}, ErrInvalidCursor
+	}
+	if err != nil {
+		return connection{}, err
+	}
+
+	page := connection{Edges: []edge{}, TotalCount: result.TotalCount}
+	for i := range result.Articles {
+		page.Edges = append(page.Edges, edge{Cursor: encodeCursor(&result.Articles[i]), Node: result.Articles[i]})
+	}
+	page.PageInfo.HasNextPage = result.HasMore
+	if len(page.Edges) > 0 {
+		page.PageInfo.EndCursor = &page.Edges[len(page.Edges)-1].Cursor
+	}
+	return page, nil
+}
+
+// related ranks the other articles by the taxonomies they share with article, then by
+// whether they are about the same club, the newest first among equals
+func related(articles []models.NewsArticleInformationMongoDB, article *models.NewsArticleInformationMongoDB, first int) []models.NewsArticleInformationMongoDB {
+	type scored struct {
+		article models.NewsArticleInformationMongoDB
+		score   int
+	}
+	candidates := []scored{}
+	for _, other := range articles {
+		if other.ID == article.ID {
+			continue
+		}
+		score := 0
+		for _, taxonomy := range article.TaxonomyList() {
+			if other.HasTaxonomy(taxonomy) {
+				score += 2
+			}
+		}
+		if strings.EqualFold(other.ClubName, article.ClubName) {
+			score++
+		}
+		if score > 0 {
+			candidates = append(candidates, scored{other, score})
+		}
+	}
+	// articles are sorted newest first already, a stable sort keeps that among equal scores
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	selected := []models.NewsArticleInformationMongoDB{}
+	for i := 0; i < len(candidates) && i < first; i++ {
+		selected = append(selected, candidates[i].article)
+	}
+	return selected
+}
+
+type club struct {
+	Name    string
+	Website string
+}
+
+type taxonomy struct {
+	Name string
+}
+
+// clubs lists every club with a published article, sorted by name
+func clubs(articles []models.NewsArticleInformationMongoDB) []club {
+	seen := map[string]bool{}
+	list := []club{}
+	for _, article := range articles {
+		key := strings.ToLower(article.ClubName)
+		if article.ClubName == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		list = append(list, club{Name: article.ClubName, Website: article.ClubWebsiteURL})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// taxonomies lists every taxonomy of the articles, sorted by name
+func taxonomies(articles []models.NewsArticleInformationMongoDB) []taxonomy {
+	seen := map[string]bool{}
+	list := []taxonomy{}
+	for _, article := range articles {
+		for _, name := range article.TaxonomyList() {
+			key := strings.ToLower(name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			list = append(list, taxonomy{Name: name})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,230 @@
+package graphqlapi
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
+	"alibazlamit/feed-provider/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testRepository() *database.MockArticleRepository {
+	repo := database.NewMockArticleRepository()
+	add := func(title, club, taxonomies string, day int, published bool) {
+		repo.Articles = append(repo.Articles, models.NewsArticleInformationMongoDB{
+			ID:          primitive.NewObjectID(),
+			Title:       title,
+			Slug:        strings.ToLower(title),
+			ClubName:    club,
+			Taxonomies:  taxonomies,
+			PublishDate: time.Date(2023, 7, day, 9, 0, 0, 0, time.UTC),
+			IsPublished: published,
+		})
+	}
+	add("Signing", "TEST CITY", "First Team, Transfers", 20, true)
+	add("Preview", "TEST CITY", "First Team", 21, true)
+	add("Academy", "TEST CITY", "Academy", 22, true)
+	add("Loan", "OTHER TOWN", "Transfers", 23, true)
+	add("Draft", "TEST CITY", "First Team", 24, false)
+	return repo
+}
+
+func testHandler(t *testing.T, repo database.ArticleRepository, broker *events.Broker, limits Limits) *Handler {
+	schema, err := NewSchema(repo, broker)
+	assert.NoError(t, err)
+	return NewHandler(schema, limits, slog.New(slog.DiscardHandler))
+}
+
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func post(t *testing.T, handler http.Handler, query string, variables map[string]interface{}) (int, response) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body))))
+	var result response
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	return rr.Code, result
+}
+
+func titles(t *testing.T, value interface{}) []string {
+	list := []string{}
+	for _, node := range value.([]interface{}) {
+		list = append(list, node.(map[string]interface{})["title"].(string))
+	}
+	return list
+}
+
+func TestPaginate(t *testing.T) {
+	repo := testRepository()
+	page, err := paginate(context.Background(), repo, models.ArticleQuery{}, 3, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(page.Edges))
+	assert.Equal(t, "Loan", page.Edges[0].Node.Title)
+	assert.True(t, page.PageInfo.HasNextPage)
+
+	page, err = paginate(context.Background(), repo, models.ArticleQuery{}, 3, *page.PageInfo.EndCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(page.Edges))
+	assert.Equal(t, "Signing", page.Edges[0].Node.Title)
+	assert.False(t, page.PageInfo.HasNextPage)
+	assert.Equal(t, 4, page.TotalCount)
+
+	_, err = paginate(context.Background(), repo, models.ArticleQuery{}, 3, "bm90LWEtY3Vyc29y")
+	assert.Equal(t, ErrInvalidCursor, err)
+	missing := models.NewsArticleInformationMongoDB{ID: primitive.NewObjectID()}
+	_, err = paginate(context.Background(), repo, models.ArticleQuery{}, 3, encodeCursor(&missing))
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestSnapshotIsSharedUntilItExpires(t *testing.T) {
+	repo := testRepository()
+	s := newSnapshot(repo)
+	articles, err := s.load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(articles))
+
+	repo.Articles[4].IsPublished = true
+	articles, _ = s.load(context.Background())
+	assert.Equal(t, 4, len(articles))
+
+	s.loadedAt = time.Now().Add(-SNAPSHOT_TTL)
+	articles, _ = s.load(context.Background())
+	assert.Equal(t, 5, len(articles))
+	assert.Equal(t, "Draft", articles[0].Title)
+}
+
+func TestRelated(t *testing.T) {
+	repo := testRepository()
+	articles, _ := newSnapshot(repo).load(context.Background())
+
+	// Signing shares First Team with Preview and Transfers with Loan, Preview is also the same club
+	selected := related(articles, &repo.Articles[0], 5)
+	assert.Equal(t, []string{"Preview", "Loan", "Academy"}, []string{selected[0].Title, selected[1].Title, selected[2].Title})
+}
+
+func TestQueryArticles(t *testing.T) {
+	handler := testHandler(t, testRepository(), nil, Limits{MaxDepth: DEFAULT_MAX_DEPTH, MaxComplexity: DEFAULT_MAX_COMPLEXITY})
+
+	query := `query($after: String) {
+		articles(first: 2, after: $after, filter: {club: "test city"}) {
+			nodes { title club { name } taxonomies { name } }
+			pageInfo { hasNextPage endCursor }
+			totalCount
+		}
+	}`
+	status, result := post(t, handler, query, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, result.Errors)
+	connection := result.Data["articles"].(map[string]interface{})
+	assert.Equal(t, []string{"Academy", "Preview"}, titles(t, connection["nodes"]))
+	assert.Equal(t, float64(3), connection["totalCount"])
+
+	pageInfo := connection["pageInfo"].(map[string]interface{})
+	_, result = post(t, handler, query, map[string]interface{}{"after": pageInfo["endCursor"]})
+	connection = result.Data["articles"].(map[string]interface{})
+	assert.Equal(t, []string{"Signing"}, titles(t, connection["nodes"]))
+
+	_, result = post(t, handler, `{ articles(filter: {search: "LOAN"}) { nodes { title } } }`, nil)
+	assert.Equal(t, []string{"Loan"}, titles(t, result.Data["articles"].(map[string]interface{})["nodes"]))
+}
+
+func TestQueryClubsAndTaxonomies(t *testing.T) {
+	handler := testHandler(t, testRepository(), nil, Limits{MaxDepth: DEFAULT_MAX_DEPTH, MaxComplexity: DEFAULT_MAX_COMPLEXITY})
+
+	_, result := post(t, handler, `{
+		clubs { name }
+		club(name: "other town") { taxonomies { name } articles { totalCount } }
+		taxonomy(name: "transfers") { articles(first: 1) { nodes { title } } }
+		article(slug: "signing") { title related(first: 1) { title } }
+	}`, nil)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "OTHER TOWN"}, map[string]interface{}{"name": "TEST CITY"}}, result.Data["clubs"])
+	club := result.Data["club"].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "Transfers"}}, club["taxonomies"])
+	assert.Equal(t, float64(1), club["articles"].(map[string]interface{})["totalCount"])
+	taxonomy := result.Data["taxonomy"].(map[string]interface{})
+	assert.Equal(t, []string{"Loan"}, titles(t, taxonomy["articles"].(map[string]interface{})["nodes"]))
+	article := result.Data["article"].(map[string]interface{})
+	assert.Equal(t, []string{"Preview"}, titles(t, article["related"]))
+
+	// drafts stay hidden
+	_, result = post(t, handler, `{ article(slug: "draft") { title } }`, nil)
+	assert.Nil(t, result.Data["article"])
+}
+
+func TestLimits(t *testing.T) {
+	handler := testHandler(t, testRepository(), nil, Limits{MaxDepth: 4, MaxComplexity: 100})
+
+	status, result := post(t, handler, `{ articles { nodes { related { related { title } } } } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "query depth 5 exceeds the maximum of 4", result.Errors[0].Message)
+
+	// 1 + 50 * (1 + 1), fragments count as if they were inlined
+	status, result = post(t, handler, `query($n: Int) { articles(first: $n) { ...titles } } fragment titles on ArticleConnection { nodes { title } }`, map[string]interface{}{"n": 50})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "query complexity 101 exceeds the maximum of 100", result.Errors[0].Message)
+
+	status, _ = post(t, handler, `{ articles(first: 10) { nodes { title } } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestLimitsFromEnv(t *testing.T) {
+	t.Setenv("GRAPHQL_MAX_DEPTH", "5")
+	limits, err := LimitsFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{MaxDepth: 5, MaxComplexity: DEFAULT_MAX_COMPLEXITY}, limits)
+
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "lots")
+	_, err = LimitsFromEnv()
+	assert.Error(t, err)
+}
+
+func TestSubscription(t *testing.T) {
+	repo := testRepository()
+	broker := events.NewBroker(10)
+	server := httptest.NewServer(testHandler(t, repo, broker, Limits{MaxDepth: DEFAULT_MAX_DEPTH, MaxComplexity: DEFAULT_MAX_COMPLEXITY}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	query := url.Values{"query": {`subscription { articleAdded(club: "OTHER TOWN") { title club { name } } }`}}
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"?"+query.Encode(), nil)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	// the subscription is set up in the background, keep publishing until it sees one
+	go func() {
+		other := models.NewsArticleInformationMongoDB{Title: "Ignored", ClubName: "TEST CITY", IsPublished: true}
+		added := models.NewsArticleInformationMongoDB{Title: "Added", ClubName: "OTHER TOWN", IsPublished: true}
+		for ctx.Err() == nil {
+			broker.Publish(models.ArticleInserted, &other)
+			broker.Publish(models.ArticleUpdated, &added)
+			broker.Publish(models.ArticleInserted, &added)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	scanner := bufio.NewScanner(res.Body)
+	assert.True(t, scanner.Scan())
+	assert.Equal(t, "event: next", scanner.Text())
+	assert.True(t, scanner.Scan())
+	assert.Equal(t, `data: {"data":{"articleAdded":{"club":{"name":"OTHER TOWN"},"title":"Added"}}}`, scanner.Text())
+}
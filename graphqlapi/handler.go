@@ -0,0 +1,148 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	MAX_REQUEST_BYTES = 1 << 20
+	HEARTBEAT_PERIOD  = 15 * time.Second
+)
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler serves queries as JSON and subscriptions as Server-Sent Events, one
+// "next" event per result and a "complete" event when the subscription ends
+type Handler struct {
+	schema graphql.Schema
+	limits Limits
+	logger *slog.Logger
+}
+
+func NewHandler(schema graphql.Schema, limits Limits, logger *slog.Logger) *Handler {
+	return &Handler{schema: schema, limits: limits, logger: logger}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(r)
+	if err != nil {
+		writeResult(w, http.StatusBadRequest, errorResult(err))
+		return
+	}
+	if req.Query == "" {
+		writeResult(w, http.StatusBadRequest, errorResult(fmt.Errorf("query is required")))
+		return
+	}
+	if err := h.limits.Check(req.Query, req.OperationName, req.Variables); err != nil {
+		writeResult(w, http.StatusBadRequest, errorResult(err))
+		return
+	}
+
+	params := graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        r.Context(),
+	}
+	if operation, _ := parseOperation(req.Query, req.OperationName); operation != nil && operation.Operation == ast.OperationTypeSubscription {
+		h.subscribe(w, r, params)
+		return
+	}
+	writeResult(w, http.StatusOK, graphql.Do(params))
+}
+
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request, params graphql.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeResult(w, http.StatusInternalServerError, errorResult(fmt.Errorf("streaming not supported")))
+		return
+	}
+	results := graphql.Subscribe(params)
+	// the executor blocks until each result is taken, drain whatever is left once we stop
+	defer func() {
+		for range results {
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(HEARTBEAT_PERIOD)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case result, ok := <-results:
+			if !ok {
+				fmt.Fprint(w, "event: complete\ndata: \n\n")
+				flusher.Flush()
+				return
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				h.logger.ErrorContext(r.Context(), "error encoding subscription result", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
+
+// readRequest takes the query from the URL for GET and from a JSON body for POST
+func readRequest(r *http.Request) (request, error) {
+	req := request{}
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return req, fmt.Errorf("invalid variables: %v", err)
+			}
+		}
+		return req, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, MAX_REQUEST_BYTES+1))
+	if err != nil {
+		return req, fmt.Errorf("error reading request body: %v", err)
+	}
+	if len(body) > MAX_REQUEST_BYTES {
+		return req, fmt.Errorf("request body exceeds %d bytes", MAX_REQUEST_BYTES)
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return req, fmt.Errorf("invalid request body: %v", err)
+	}
+	return req, nil
+}
+
+func errorResult(err error) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}}
+}
+
+func writeResult(w http.ResponseWriter, statusCode int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(result)
+}
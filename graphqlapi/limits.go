@@ -0,0 +1,152 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	DEFAULT_MAX_DEPTH      = 8
+	DEFAULT_MAX_COMPLEXITY = 1000
+)
+
+// Limits bound how deep and how expensive a single query may be
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// LimitsFromEnv reads GRAPHQL_MAX_DEPTH and GRAPHQL_MAX_COMPLEXITY, falling back to the defaults
+func LimitsFromEnv() (Limits, error) {
+	limits := Limits{MaxDepth: DEFAULT_MAX_DEPTH, MaxComplexity: DEFAULT_MAX_COMPLEXITY}
+	for name, target := range map[string]*int{"GRAPHQL_MAX_DEPTH": &limits.MaxDepth, "GRAPHQL_MAX_COMPLEXITY": &limits.MaxComplexity} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			return Limits{}, fmt.Errorf("invalid %s %q", name, raw)
+		}
+		*target = value
+	}
+	return limits, nil
+}
+
+// Check measures the operation the request would execute before anything is resolved. The
+// complexity of a field is 1 plus the cost of its selections, multiplied by its first
+// argument for lists so a page of 100 articles costs 100 times one article.
+func (l Limits) Check(query, operationName string, variables map[string]interface{}) error {
+	operation, fragments := parseOperation(query, operationName)
+	if operation == nil {
+		// the executor reports syntax errors with their locations
+		return nil
+	}
+
+	m := measure{fragments: fragments, variables: variables, visiting: map[string]bool{}}
+	depth, complexity := m.selectionSet(operation.SelectionSet)
+	if depth > l.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, l.MaxDepth)
+	}
+	if complexity > l.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, l.MaxComplexity)
+	}
+	return nil
+}
+
+type measure struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// visiting guards against fragments spreading themselves, the validator rejects those later
+	visiting map[string]bool
+}
+
+func (m measure) selectionSet(set *ast.SelectionSet) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = m.field(selection)
+		case *ast.InlineFragment:
+			d, c = m.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			m.visiting[name] = true
+			d, c = m.selectionSet(fragment.SelectionSet)
+			delete(m.visiting, name)
+		}
+		if d > depth {
+			depth = d
+		}
+		complexity += c
+	}
+	return depth, complexity
+}
+
+func (m measure) field(field *ast.Field) (depth, complexity int) {
+	if field.SelectionSet == nil {
+		return 1, 1
+	}
+	childDepth, childComplexity := m.selectionSet(field.SelectionSet)
+	return childDepth + 1, 1 + m.multiplier(field)*childComplexity
+}
+
+// multiplier is the page size the field asks for, 1 for fields without a first argument
+func (m measure) multiplier(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if first, err := strconv.Atoi(value.Value); err == nil && first > 0 {
+				return first
+			}
+		case *ast.Variable:
+			if first, ok := m.variables[value.Name.Value].(float64); ok && first > 0 {
+				return int(first)
+			}
+		}
+		return 1
+	}
+	// without first the resolvers use their defaults
+	switch field.Name.Value {
+	case "articles":
+		return DEFAULT_PAGE_SIZE
+	case "related":
+		return DEFAULT_RELATED_SIZE
+	}
+	return 1
+}
+
+// parseOperation returns the operation the executor would pick and the document's fragments
+func parseOperation(query, operationName string) (*ast.OperationDefinition, map[string]*ast.FragmentDefinition) {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil, nil
+	}
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operation == nil || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	return operation, fragments
+}
@@ -0,0 +1,431 @@
+// Package graphqlapi serves the articles, clubs and taxonomies as a GraphQL schema on
+// top of the article repository, with new articles pushed through a subscription.
+package graphqlapi
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
+	"alibazlamit/feed-provider/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type resolver struct {
+	articles database.ArticleRepository
+	snapshot *snapshot
+	events   *events.Broker
+}
+
+// NewSchema builds the schema, broker may be nil when there is no subscription source
+func NewSchema(articles database.ArticleRepository, broker *events.Broker) (graphql.Schema, error) {
+	r := &resolver{articles: articles, snapshot: newSnapshot(articles), events: broker}
+
+	var articleType, clubType, taxonomyType *graphql.Object
+	pagingArgs := graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DEFAULT_PAGE_SIZE, Description: fmt.Sprintf("At most %d", MAX_PAGE_SIZE)},
+		"after": &graphql.ArgumentConfig{Type: graphql.String, Description: "endCursor of the previous page"},
+	}
+	filterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ArticleFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"club":            &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"taxonomy":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"search":          &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Matches the title or teaser, ignoring case"},
+			"publishedAfter":  &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"publishedBefore": &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+	mediaType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Media",
+		Fields: graphql.Fields{
+			"thumbnail": &graphql.Field{Type: graphql.String},
+			"gallery":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"video":     &graphql.Field{Type: graphql.String},
+		},
+	})
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticleEdge",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"node":   &graphql.Field{Type: graphql.NewNonNull(articleType)},
+			}
+		}),
+	})
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticleConnection",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"edges": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType)))},
+				"nodes": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(articleType))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						nodes := []models.NewsArticleInformationMongoDB{}
+						for _, e := range p.Source.(connection).Edges {
+							nodes = append(nodes, e.Node)
+						}
+						return nodes, nil
+					},
+				},
+				"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+				"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			}
+		}),
+	})
+
+	articleType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Article",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          articleField(graphql.NewNonNull(graphql.ID), func(a *models.NewsArticleInformationMongoDB) interface{} { return a.ID.Hex() }),
+				"upstreamId":  articleField(graphql.NewNonNull(graphql.Int), func(a *models.NewsArticleInformationMongoDB) interface{} { return a.NewsArticleID }),
+				"slug":        articleField(graphql.NewNonNull(graphql.String), func(a *models.NewsArticleInformationMongoDB) interface{} { return a.Slug }),
+				"url":         articleField(graphql.NewNonNull(graphql.String), func(a *models.NewsArticleInformationMongoDB) interface{} { return a.ArticleURL }),
+				"title":       articleField(graphql.NewNonNull(graphql.String), func(a *models.NewsArticleInformationMongoDB) interface{} { return a.Title }),
+				"subtitle":    articleField(graphql.String, func(a *models.NewsArticleInformationMongoDB) interface{} { return a.Subtitle }),
+				"teaser":      articleField(graphql.String, func(a *models.NewsArticleInformationMongoDB) interface{} { return a.TeaserText }),
+				"content":     articleField(graphql.String, func(a *models.NewsArticleInformationMongoDB) interface{} { return a.BodyText }),
+				"published":   articleField(graphql.NewNonNull(graphql.DateTime), func(a *models.NewsArticleInformationMongoDB) interface{} { return a.PublishDate }),
+				"updated":     articleField(graphql.NewNonNull(graphql.DateTime), func(a *models.NewsArticleInformationMongoDB) interface{} { return a.LastUpdateDate }),
+				"optaMatchId": articleField(graphql.String, func(a *models.NewsArticleInformationMongoDB) interface{} { return a.OptaMatchID }),
+				"club": articleField(graphql.NewNonNull(clubType), func(a *models.NewsArticleInformationMongoDB) interface{} {
+					return club{Name: a.ClubName, Website: a.ClubWebsiteURL}
+				}),
+				"taxonomies": articleField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taxonomyType))), func(a *models.NewsArticleInformationMongoDB) interface{} {
+					list := []taxonomy{}
+					for _, name := range a.TaxonomyList() {
+						list = append(list, taxonomy{Name: name})
+					}
+					return list
+				}),
+				"media": articleField(graphql.NewNonNull(mediaType), func(a *models.NewsArticleInformationMongoDB) interface{} {
+					return map[string]interface{}{"thumbnail": a.ThumbnailImageURL, "gallery": splitList(a.GalleryImageURLs), "video": a.VideoURL}
+				}),
+				"related": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(articleType))),
+					Description: "Articles sharing taxonomies or the club, the most similar first",
+					Args: graphql.FieldConfigArgument{
+						"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DEFAULT_RELATED_SIZE},
+					},
+					Resolve: r.related,
+				},
+			}
+		}),
+	})
+	clubType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Club",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"website": &graphql.Field{Type: graphql.String},
+				"articles": &graphql.Field{
+					Type:    graphql.NewNonNull(connectionType),
+					Args:    pagingArgs,
+					Resolve: r.clubArticles,
+				},
+				"taxonomies": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taxonomyType))),
+					Resolve: r.clubTaxonomies,
+				},
+			}
+		}),
+	})
+	taxonomyType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Taxonomy",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"articles": &graphql.Field{
+					Type:    graphql.NewNonNull(connectionType),
+					Args:    pagingArgs,
+					Resolve: r.taxonomyArticles,
+				},
+			}
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"article": &graphql.Field{
+				Type:        articleType,
+				Description: "Looks an article up by exactly one of its identifiers",
+				Args: graphql.FieldConfigArgument{
+					"id":         &graphql.ArgumentConfig{Type: graphql.ID},
+					"upstreamId": &graphql.ArgumentConfig{Type: graphql.Int},
+					"slug":       &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: r.article,
+			},
+			"articles": &graphql.Field{
+				Type:        graphql.NewNonNull(connectionType),
+				Description: "Published articles, newest first",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: filterType},
+					"first":  pagingArgs["first"],
+					"after":  pagingArgs["after"],
+				},
+				Resolve: r.articlesConnection,
+			},
+			"clubs": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(clubType))),
+				Resolve: r.clubs,
+			},
+			"club": &graphql.Field{
+				Type:    clubType,
+				Args:    graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: r.club,
+			},
+			"taxonomies": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taxonomyType))),
+				Resolve: r.taxonomies,
+			},
+			"taxonomy": &graphql.Field{
+				Type:    taxonomyType,
+				Args:    graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: r.taxonomy,
+			},
+		},
+	})
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"articleAdded": &graphql.Field{
+				Type:        graphql.NewNonNull(articleType),
+				Description: "Every article the reader inserts from now on, optionally for one club or taxonomy",
+				Args: graphql.FieldConfigArgument{
+					"club":     &graphql.ArgumentConfig{Type: graphql.String},
+					"taxonomy": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Subscribe: r.subscribeArticleAdded,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Subscription: subscription})
+}
+
+func articleField(fieldType graphql.Output, value func(a *models.NewsArticleInformationMongoDB) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: fieldType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			article, ok := p.Source.(models.NewsArticleInformationMongoDB)
+			if !ok {
+				return nil, nil
+			}
+			return value(&article), nil
+		},
+	}
+}
+
+func (r *resolver) load(p graphql.ResolveParams) ([]models.NewsArticleInformationMongoDB, error) {
+	articles, err := r.snapshot.load(p.Context)
+	if err != nil {
+		return nil, errors.New("error retrieving articles")
+	}
+	return articles, nil
+}
+
+func (r *resolver) article(p graphql.ResolveParams) (interface{}, error) {
+	var article *models.NewsArticleInformationMongoDB
+	var err error
+	switch {
+	case len(p.Args) != 1:
+		return nil, errors.New("pass exactly one of id, upstreamId and slug")
+	case p.Args["id"] != nil:
+		id, parseErr := primitive.ObjectIDFromHex(p.Args["id"].(string))
+		if parseErr != nil {
+			return nil, errors.New("invalid article id")
+		}
+		article, err = r.articles.GetArticleByID(p.Context, id)
+	case p.Args["upstreamId"] != nil:
+		article, err = r.articles.GetArticleByNewsArticleID(p.Context, p.Args["upstreamId"].(int))
+	default:
+		article, err = r.articles.GetArticleBySlug(p.Context, strings.ToLower(p.Args["slug"].(string)))
+	}
+	if err != nil {
+		return nil, errors.New("error retrieving article")
+	}
+	if article == nil || !article.IsPublished {
+		return nil, nil
+	}
+	return *article, nil
+}
+
+func (r *resolver) articlesConnection(p graphql.ResolveParams) (interface{}, error) {
+	filter := models.ArticleQuery{}
+	if input, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Club, _ = input["club"].(string)
+		filter.Taxonomy, _ = input["taxonomy"].(string)
+		filter.Search, _ = input["search"].(string)
+		if after, ok := input["publishedAfter"].(time.Time); ok {
+			filter.PublishedAfter = after
+		}
+		if before, ok := input["publishedBefore"].(time.Time); ok {
+			filter.PublishedBefore = before
+		}
+	}
+	return r.page(p, filter)
+}
+
+func (r *resolver) clubArticles(p graphql.ResolveParams) (interface{}, error) {
+	return r.page(p, models.ArticleQuery{ArticleFilter: models.ArticleFilter{Club: p.Source.(club).Name}})
+}
+
+func (r *resolver) clubTaxonomies(p graphql.ResolveParams) (interface{}, error) {
+	articles, err := r.load(p)
+	if err != nil {
+		return nil, err
+	}
+	filter := models.ArticleQuery{ArticleFilter: models.ArticleFilter{Club: p.Source.(club).Name}}
+	return taxonomies(selectArticles(articles, filter)), nil
+}
+
+func (r *resolver) taxonomyArticles(p graphql.ResolveParams) (interface{}, error) {
+	return r.page(p, models.ArticleQuery{ArticleFilter: models.ArticleFilter{Taxonomy: p.Source.(taxonomy).Name}})
+}
+
+func (r *resolver) related(p graphql.ResolveParams) (interface{}, error) {
+	articles, err := r.load(p)
+	if err != nil {
+		return nil, err
+	}
+	article := p.Source.(models.NewsArticleInformationMongoDB)
+	first, err := pageSize(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	return related(articles, &article, first), nil
+}
+
+func (r *resolver) clubs(p graphql.ResolveParams) (interface{}, error) {
+	articles, err := r.load(p)
+	if err != nil {
+		return nil, err
+	}
+	return clubs(articles), nil
+}
+
+func (r *resolver) club(p graphql.ResolveParams) (interface{}, error) {
+	articles, err := r.load(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range clubs(articles) {
+		if strings.EqualFold(c.Name, p.Args["name"].(string)) {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *resolver) taxonomies(p graphql.ResolveParams) (interface{}, error) {
+	articles, err := r.load(p)
+	if err != nil {
+		return nil, err
+	}
+	return taxonomies(articles), nil
+}
+
+func (r *resolver) taxonomy(p graphql.ResolveParams) (interface{}, error) {
+	articles, err := r.load(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range taxonomies(articles) {
+		if strings.EqualFold(t.Name, p.Args["name"].(string)) {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// subscribeArticleAdded forwards the broker's published inserts until the request ends
+func (r *resolver) subscribeArticleAdded(p graphql.ResolveParams) (interface{}, error) {
+	if r.events == nil {
+		return nil, errors.New("subscriptions are not available")
+	}
+	filter := models.ArticleFilter{}
+	filter.Club, _ = p.Args["club"].(string)
+	filter.Taxonomy, _ = p.Args["taxonomy"].(string)
+
+	// no replay, only what happens from now on
+	_, subscription, cancel := r.events.Subscribe(^uint64(0))
+	articles := make(chan interface{})
+	go func() {
+		defer close(articles)
+		defer cancel()
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-subscription:
+				if !ok {
+					return
+				}
+				if event.Type != models.ArticleInserted || !event.Article.IsPublished || !filter.Matches(&event.Article) {
+					continue
+				}
+				select {
+				case articles <- event.Article:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return articles, nil
+}
+
+// page returns the page of the published articles matching the query that the paging arguments ask for
+func (r *resolver) page(p graphql.ResolveParams, query models.ArticleQuery) (interface{}, error) {
+	first, err := pageSize(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	after, _ := p.Args["after"].(string)
+	page, err := paginate(p.Context, r.articles, query, first, after)
+	if err == ErrInvalidCursor {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("error retrieving articles")
+	}
+	return page, nil
+}
+
+func pageSize(args map[string]interface{}) (int, error) {
+	first, ok := args["first"].(int)
+	if !ok {
+		return DEFAULT_PAGE_SIZE, nil
+	}
+	if first < 0 || first > MAX_PAGE_SIZE {
+		return 0, fmt.Errorf("first must be between 0 and %d", MAX_PAGE_SIZE)
+	}
+	return first, nil
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
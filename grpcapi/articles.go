@@ -0,0 +1,197 @@
+package grpcapi
+
+import (
+	"alibazlamit/feed-provider/grpcapi/articlespb"
+	"alibazlamit/feed-provider/models"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE     = 100
+	PAGE_TOKEN_PREFIX = "article:"
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+var eventTypes = map[models.SyncOutcome]articlespb.EventType{
+	models.ArticleInserted:    articlespb.EventType_EVENT_TYPE_INSERTED,
+	models.ArticleUpdated:     articlespb.EventType_EVENT_TYPE_UPDATED,
+	models.ArticleUnpublished: articlespb.EventType_EVENT_TYPE_UNPUBLISHED,
+}
+
+func toArticle(article *models.NewsArticleInformationMongoDB) *articlespb.Article {
+	return &articlespb.Article{
+		Id:          article.ID.Hex(),
+		UpstreamId:  int64(article.NewsArticleID),
+		Slug:        article.Slug,
+		Url:         article.ArticleURL,
+		Title:       article.Title,
+		Subtitle:    article.Subtitle,
+		Teaser:      article.TeaserText,
+		Content:     article.BodyText,
+		Published:   timestamppb.New(article.PublishDate),
+		Updated:     timestamppb.New(article.LastUpdateDate),
+		Club:        &articlespb.Club{Name: article.ClubName, Website: article.ClubWebsiteURL},
+		Tags:        article.TaxonomyList(),
+		Media:       &articlespb.Media{Thumbnail: article.ThumbnailImageURL, Gallery: splitList(article.GalleryImageURLs), Video: article.VideoURL},
+		OptaMatchId: article.OptaMatchID,
+		Warnings:    article.Warnings,
+		IsPublished: article.IsPublished,
+	}
+}
+
+func toArticles(articles []models.NewsArticleInformationMongoDB) []*articlespb.Article {
+	list := make([]*articlespb.Article, len(articles))
+	for i := range articles {
+		list[i] = toArticle(&articles[i])
+	}
+	return list
+}
+
+func toEvent(event *models.ArticleEvent) *articlespb.ArticleEvent {
+	return &articlespb.ArticleEvent{
+		Id:         event.ID,
+		Type:       eventTypes[event.Type],
+		OccurredAt: timestamppb.New(event.OccurredAt),
+		Article:    toArticle(&event.Article),
+	}
+}
+
+// articleQuery translates the request's filter, nil lists every published article
+func articleQuery(filter *articlespb.ArticleFilter) models.ArticleQuery {
+	if filter == nil {
+		return models.ArticleQuery{}
+	}
+	query := models.ArticleQuery{
+		ArticleFilter:      models.ArticleFilter{Club: filter.Club, Taxonomy: filter.Taxonomy},
+		IncludeUnpublished: filter.IncludeUnpublished,
+	}
+	if filter.PublishedAfter != nil {
+		query.PublishedAfter = filter.PublishedAfter.AsTime()
+	}
+	if filter.PublishedBefore != nil {
+		query.PublishedBefore = filter.PublishedBefore.AsTime()
+	}
+	return query
+}
+
+// search keeps the articles containing every term and ranks them by where the terms
+// appear, a title match counts 3, a teaser match 2 and a content match 1
+func search(articles []models.NewsArticleInformationMongoDB, query string) []models.NewsArticleInformationMongoDB {
+	terms := strings.Fields(strings.ToLower(query))
+	type scored struct {
+		article models.NewsArticleInformationMongoDB
+		score   int
+	}
+	candidates := []scored{}
+	for _, article := range articles {
+		title, teaser, content := strings.ToLower(article.Title), strings.ToLower(article.TeaserText), strings.ToLower(article.BodyText)
+		score := 0
+		for _, term := range terms {
+			termScore := 0
+			if strings.Contains(title, term) {
+				termScore += 3
+			}
+			if strings.Contains(teaser, term) {
+				termScore += 2
+			}
+			if strings.Contains(content, term) {
+				termScore++
+			}
+			if termScore == 0 {
+				score = 0
+				break
+			}
+			score += termScore
+		}
+		if score > 0 {
+			candidates = append(candidates, scored{article, score})
+		}
+	}
+	// the articles come in newest first, a stable sort keeps that among equal scores
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	ranked := make([]models.NewsArticleInformationMongoDB, len(candidates))
+	for i := range candidates {
+		ranked[i] = candidates[i].article
+	}
+	return ranked
+}
+
+func checkPageSize(pageSize int32) (int32, error) {
+	if pageSize < 0 || pageSize > MAX_PAGE_SIZE {
+		return 0, fmt.Errorf("page_size must be between 0 and %d", MAX_PAGE_SIZE)
+	}
+	if pageSize == 0 {
+		return DEFAULT_PAGE_SIZE, nil
+	}
+	return pageSize, nil
+}
+
+func encodePageToken(article *models.NewsArticleInformationMongoDB) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(PAGE_TOKEN_PREFIX + article.ID.Hex()))
+}
+
+func decodePageToken(pageToken string) (primitive.ObjectID, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil || !strings.HasPrefix(string(decoded), PAGE_TOKEN_PREFIX) {
+		return primitive.NilObjectID, ErrInvalidPageToken
+	}
+	id, err := primitive.ObjectIDFromHex(strings.TrimPrefix(string(decoded), PAGE_TOKEN_PREFIX))
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidPageToken
+	}
+	return id, nil
+}
+
+// paginate pages through ranked search results, returning pageSize articles after the
+// one the token points at, and the token of the next page when there is one
+func paginate(articles []models.NewsArticleInformationMongoDB, pageSize int32, pageToken string) ([]models.NewsArticleInformationMongoDB, string, error) {
+	pageSize, err := checkPageSize(pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	start := 0
+	if pageToken != "" {
+		id, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		start = -1
+		for i := range articles {
+			if articles[i].ID == id {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, "", ErrInvalidPageToken
+		}
+	}
+
+	end := start + int(pageSize)
+	if end >= len(articles) {
+		return articles[start:], "", nil
+	}
+	return articles[start:end], encodePageToken(&articles[end-1]), nil
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,1100 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: articlespb/articles.proto
+
+// Articles for internal consumers, the same data the REST API serves.
+
+package articlespb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED EventType = 0
+	EventType_EVENT_TYPE_INSERTED    EventType = 1
+	EventType_EVENT_TYPE_UPDATED     EventType = 2
+	EventType_EVENT_TYPE_UNPUBLISHED EventType = 3
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_INSERTED",
+		2: "EVENT_TYPE_UPDATED",
+		3: "EVENT_TYPE_UNPUBLISHED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED": 0,
+		"EVENT_TYPE_INSERTED":    1,
+		"EVENT_TYPE_UPDATED":     2,
+		"EVENT_TYPE_UNPUBLISHED": 3,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_articlespb_articles_proto_enumTypes[0].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_articlespb_articles_proto_enumTypes[0]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{0}
+}
+
+type Article struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Database ID.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Upstream NewsArticleID.
+	UpstreamId int64  `protobuf:"varint,2,opt,name=upstream_id,json=upstreamId,proto3" json:"upstream_id,omitempty"`
+	Slug       string `protobuf:"bytes,3,opt,name=slug,proto3" json:"slug,omitempty"`
+	Url        string `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	Title      string `protobuf:"bytes,5,opt,name=title,proto3" json:"title,omitempty"`
+	Subtitle   string `protobuf:"bytes,6,opt,name=subtitle,proto3" json:"subtitle,omitempty"`
+	Teaser     string `protobuf:"bytes,7,opt,name=teaser,proto3" json:"teaser,omitempty"`
+	// HTML body.
+	Content     string                 `protobuf:"bytes,8,opt,name=content,proto3" json:"content,omitempty"`
+	Published   *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=published,proto3" json:"published,omitempty"`
+	Updated     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated,proto3" json:"updated,omitempty"`
+	Club        *Club                  `protobuf:"bytes,11,opt,name=club,proto3" json:"club,omitempty"`
+	Tags        []string               `protobuf:"bytes,12,rep,name=tags,proto3" json:"tags,omitempty"`
+	Media       *Media                 `protobuf:"bytes,13,opt,name=media,proto3" json:"media,omitempty"`
+	OptaMatchId string                 `protobuf:"bytes,14,opt,name=opta_match_id,json=optaMatchId,proto3" json:"opta_match_id,omitempty"`
+	// Validation rules the article broke when stored with warnings.
+	Warnings []string `protobuf:"bytes,15,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// False once the upstream stopped publishing the article.
+	IsPublished   bool `protobuf:"varint,16,opt,name=is_published,json=isPublished,proto3" json:"is_published,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Article) Reset() {
+	*x = Article{}
+	mi := &file_articlespb_articles_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Article) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Article) ProtoMessage() {}
+
+func (x *Article) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Article.ProtoReflect.Descriptor instead.
+func (*Article) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Article) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Article) GetUpstreamId() int64 {
+	if x != nil {
+		return x.UpstreamId
+	}
+	return 0
+}
+
+func (x *Article) GetSlug() string {
+	if x != nil {
+		return x.Slug
+	}
+	return ""
+}
+
+func (x *Article) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Article) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Article) GetSubtitle() string {
+	if x != nil {
+		return x.Subtitle
+	}
+	return ""
+}
+
+func (x *Article) GetTeaser() string {
+	if x != nil {
+		return x.Teaser
+	}
+	return ""
+}
+
+func (x *Article) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Article) GetPublished() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Published
+	}
+	return nil
+}
+
+func (x *Article) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *Article) GetClub() *Club {
+	if x != nil {
+		return x.Club
+	}
+	return nil
+}
+
+func (x *Article) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Article) GetMedia() *Media {
+	if x != nil {
+		return x.Media
+	}
+	return nil
+}
+
+func (x *Article) GetOptaMatchId() string {
+	if x != nil {
+		return x.OptaMatchId
+	}
+	return ""
+}
+
+func (x *Article) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *Article) GetIsPublished() bool {
+	if x != nil {
+		return x.IsPublished
+	}
+	return false
+}
+
+type Club struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Website       string                 `protobuf:"bytes,2,opt,name=website,proto3" json:"website,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Club) Reset() {
+	*x = Club{}
+	mi := &file_articlespb_articles_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Club) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Club) ProtoMessage() {}
+
+func (x *Club) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Club.ProtoReflect.Descriptor instead.
+func (*Club) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Club) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Club) GetWebsite() string {
+	if x != nil {
+		return x.Website
+	}
+	return ""
+}
+
+type Media struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Thumbnail     string                 `protobuf:"bytes,1,opt,name=thumbnail,proto3" json:"thumbnail,omitempty"`
+	Gallery       []string               `protobuf:"bytes,2,rep,name=gallery,proto3" json:"gallery,omitempty"`
+	Video         string                 `protobuf:"bytes,3,opt,name=video,proto3" json:"video,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Media) Reset() {
+	*x = Media{}
+	mi := &file_articlespb_articles_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Media) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Media) ProtoMessage() {}
+
+func (x *Media) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Media.ProtoReflect.Descriptor instead.
+func (*Media) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Media) GetThumbnail() string {
+	if x != nil {
+		return x.Thumbnail
+	}
+	return ""
+}
+
+func (x *Media) GetGallery() []string {
+	if x != nil {
+		return x.Gallery
+	}
+	return nil
+}
+
+func (x *Media) GetVideo() string {
+	if x != nil {
+		return x.Video
+	}
+	return ""
+}
+
+type GetArticleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Key:
+	//
+	//	*GetArticleRequest_Id
+	//	*GetArticleRequest_UpstreamId
+	//	*GetArticleRequest_Slug
+	Key           isGetArticleRequest_Key `protobuf_oneof:"key"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetArticleRequest) Reset() {
+	*x = GetArticleRequest{}
+	mi := &file_articlespb_articles_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetArticleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetArticleRequest) ProtoMessage() {}
+
+func (x *GetArticleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetArticleRequest.ProtoReflect.Descriptor instead.
+func (*GetArticleRequest) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetArticleRequest) GetKey() isGetArticleRequest_Key {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *GetArticleRequest) GetId() string {
+	if x != nil {
+		if x, ok := x.Key.(*GetArticleRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return ""
+}
+
+func (x *GetArticleRequest) GetUpstreamId() int64 {
+	if x != nil {
+		if x, ok := x.Key.(*GetArticleRequest_UpstreamId); ok {
+			return x.UpstreamId
+		}
+	}
+	return 0
+}
+
+func (x *GetArticleRequest) GetSlug() string {
+	if x != nil {
+		if x, ok := x.Key.(*GetArticleRequest_Slug); ok {
+			return x.Slug
+		}
+	}
+	return ""
+}
+
+type isGetArticleRequest_Key interface {
+	isGetArticleRequest_Key()
+}
+
+type GetArticleRequest_Id struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3,oneof"`
+}
+
+type GetArticleRequest_UpstreamId struct {
+	UpstreamId int64 `protobuf:"varint,2,opt,name=upstream_id,json=upstreamId,proto3,oneof"`
+}
+
+type GetArticleRequest_Slug struct {
+	Slug string `protobuf:"bytes,3,opt,name=slug,proto3,oneof"`
+}
+
+func (*GetArticleRequest_Id) isGetArticleRequest_Key() {}
+
+func (*GetArticleRequest_UpstreamId) isGetArticleRequest_Key() {}
+
+func (*GetArticleRequest_Slug) isGetArticleRequest_Key() {}
+
+type ArticleFilter struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Club            string                 `protobuf:"bytes,1,opt,name=club,proto3" json:"club,omitempty"`
+	Taxonomy        string                 `protobuf:"bytes,2,opt,name=taxonomy,proto3" json:"taxonomy,omitempty"`
+	PublishedAfter  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=published_after,json=publishedAfter,proto3" json:"published_after,omitempty"`
+	PublishedBefore *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=published_before,json=publishedBefore,proto3" json:"published_before,omitempty"`
+	// Unpublished articles are left out unless this is set.
+	IncludeUnpublished bool `protobuf:"varint,5,opt,name=include_unpublished,json=includeUnpublished,proto3" json:"include_unpublished,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ArticleFilter) Reset() {
+	*x = ArticleFilter{}
+	mi := &file_articlespb_articles_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArticleFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArticleFilter) ProtoMessage() {}
+
+func (x *ArticleFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArticleFilter.ProtoReflect.Descriptor instead.
+func (*ArticleFilter) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ArticleFilter) GetClub() string {
+	if x != nil {
+		return x.Club
+	}
+	return ""
+}
+
+func (x *ArticleFilter) GetTaxonomy() string {
+	if x != nil {
+		return x.Taxonomy
+	}
+	return ""
+}
+
+func (x *ArticleFilter) GetPublishedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishedAfter
+	}
+	return nil
+}
+
+func (x *ArticleFilter) GetPublishedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishedBefore
+	}
+	return nil
+}
+
+func (x *ArticleFilter) GetIncludeUnpublished() bool {
+	if x != nil {
+		return x.IncludeUnpublished
+	}
+	return false
+}
+
+type ListArticlesRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *ArticleFilter         `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// 20 when unset, at most 100.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page.
+	PageToken     string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListArticlesRequest) Reset() {
+	*x = ListArticlesRequest{}
+	mi := &file_articlespb_articles_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListArticlesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListArticlesRequest) ProtoMessage() {}
+
+func (x *ListArticlesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListArticlesRequest.ProtoReflect.Descriptor instead.
+func (*ListArticlesRequest) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListArticlesRequest) GetFilter() *ArticleFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListArticlesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListArticlesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListArticlesResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Articles []*Article             `protobuf:"bytes,1,rep,name=articles,proto3" json:"articles,omitempty"`
+	// Empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	// Articles matching the filter across every page.
+	TotalSize     int32 `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListArticlesResponse) Reset() {
+	*x = ListArticlesResponse{}
+	mi := &file_articlespb_articles_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListArticlesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListArticlesResponse) ProtoMessage() {}
+
+func (x *ListArticlesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListArticlesResponse.ProtoReflect.Descriptor instead.
+func (*ListArticlesResponse) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListArticlesResponse) GetArticles() []*Article {
+	if x != nil {
+		return x.Articles
+	}
+	return nil
+}
+
+func (x *ListArticlesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *ListArticlesResponse) GetTotalSize() int32 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+type SearchArticlesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Terms matched against the title, teaser and content, ignoring case.
+	Query         string         `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Filter        *ArticleFilter `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+	PageSize      int32          `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string         `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchArticlesRequest) Reset() {
+	*x = SearchArticlesRequest{}
+	mi := &file_articlespb_articles_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchArticlesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchArticlesRequest) ProtoMessage() {}
+
+func (x *SearchArticlesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchArticlesRequest.ProtoReflect.Descriptor instead.
+func (*SearchArticlesRequest) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SearchArticlesRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchArticlesRequest) GetFilter() *ArticleFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *SearchArticlesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *SearchArticlesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type SearchArticlesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Best match first, title matches count the most.
+	Articles      []*Article `protobuf:"bytes,1,rep,name=articles,proto3" json:"articles,omitempty"`
+	NextPageToken string     `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	TotalSize     int32      `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchArticlesResponse) Reset() {
+	*x = SearchArticlesResponse{}
+	mi := &file_articlespb_articles_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchArticlesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchArticlesResponse) ProtoMessage() {}
+
+func (x *SearchArticlesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchArticlesResponse.ProtoReflect.Descriptor instead.
+func (*SearchArticlesResponse) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchArticlesResponse) GetArticles() []*Article {
+	if x != nil {
+		return x.Articles
+	}
+	return nil
+}
+
+func (x *SearchArticlesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *SearchArticlesResponse) GetTotalSize() int32 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+type WatchArticlesRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Club     string                 `protobuf:"bytes,1,opt,name=club,proto3" json:"club,omitempty"`
+	Taxonomy string                 `protobuf:"bytes,2,opt,name=taxonomy,proto3" json:"taxonomy,omitempty"`
+	// Every type when empty.
+	Types         []EventType `protobuf:"varint,3,rep,packed,name=types,proto3,enum=feedprovider.articles.v1.EventType" json:"types,omitempty"`
+	LastEventId   uint64      `protobuf:"varint,4,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchArticlesRequest) Reset() {
+	*x = WatchArticlesRequest{}
+	mi := &file_articlespb_articles_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchArticlesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchArticlesRequest) ProtoMessage() {}
+
+func (x *WatchArticlesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchArticlesRequest.ProtoReflect.Descriptor instead.
+func (*WatchArticlesRequest) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchArticlesRequest) GetClub() string {
+	if x != nil {
+		return x.Club
+	}
+	return ""
+}
+
+func (x *WatchArticlesRequest) GetTaxonomy() string {
+	if x != nil {
+		return x.Taxonomy
+	}
+	return ""
+}
+
+func (x *WatchArticlesRequest) GetTypes() []EventType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *WatchArticlesRequest) GetLastEventId() uint64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+type ArticleEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          EventType              `protobuf:"varint,2,opt,name=type,proto3,enum=feedprovider.articles.v1.EventType" json:"type,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	Article       *Article               `protobuf:"bytes,4,opt,name=article,proto3" json:"article,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArticleEvent) Reset() {
+	*x = ArticleEvent{}
+	mi := &file_articlespb_articles_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArticleEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArticleEvent) ProtoMessage() {}
+
+func (x *ArticleEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_articlespb_articles_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArticleEvent.ProtoReflect.Descriptor instead.
+func (*ArticleEvent) Descriptor() ([]byte, []int) {
+	return file_articlespb_articles_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ArticleEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ArticleEvent) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *ArticleEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *ArticleEvent) GetArticle() *Article {
+	if x != nil {
+		return x.Article
+	}
+	return nil
+}
+
+var File_articlespb_articles_proto protoreflect.FileDescriptor
+
+const file_articlespb_articles_proto_rawDesc = "" +
+	"\n" +
+	"\x19articlespb/articles.proto\x12\x18feedprovider.articles.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x96\x04\n" +
+	"\aArticle\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vupstream_id\x18\x02 \x01(\x03R\n" +
+	"upstreamId\x12\x12\n" +
+	"\x04slug\x18\x03 \x01(\tR\x04slug\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\x12\x14\n" +
+	"\x05title\x18\x05 \x01(\tR\x05title\x12\x1a\n" +
+	"\bsubtitle\x18\x06 \x01(\tR\bsubtitle\x12\x16\n" +
+	"\x06teaser\x18\a \x01(\tR\x06teaser\x12\x18\n" +
+	"\acontent\x18\b \x01(\tR\acontent\x128\n" +
+	"\tpublished\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tpublished\x124\n" +
+	"\aupdated\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\x122\n" +
+	"\x04club\x18\v \x01(\v2\x1e.feedprovider.articles.v1.ClubR\x04club\x12\x12\n" +
+	"\x04tags\x18\f \x03(\tR\x04tags\x125\n" +
+	"\x05media\x18\r \x01(\v2\x1f.feedprovider.articles.v1.MediaR\x05media\x12\"\n" +
+	"\ropta_match_id\x18\x0e \x01(\tR\voptaMatchId\x12\x1a\n" +
+	"\bwarnings\x18\x0f \x03(\tR\bwarnings\x12!\n" +
+	"\fis_published\x18\x10 \x01(\bR\visPublished\"4\n" +
+	"\x04Club\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\awebsite\x18\x02 \x01(\tR\awebsite\"U\n" +
+	"\x05Media\x12\x1c\n" +
+	"\tthumbnail\x18\x01 \x01(\tR\tthumbnail\x12\x18\n" +
+	"\agallery\x18\x02 \x03(\tR\agallery\x12\x14\n" +
+	"\x05video\x18\x03 \x01(\tR\x05video\"e\n" +
+	"\x11GetArticleRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\tH\x00R\x02id\x12!\n" +
+	"\vupstream_id\x18\x02 \x01(\x03H\x00R\n" +
+	"upstreamId\x12\x14\n" +
+	"\x04slug\x18\x03 \x01(\tH\x00R\x04slugB\x05\n" +
+	"\x03key\"\xfc\x01\n" +
+	"\rArticleFilter\x12\x12\n" +
+	"\x04club\x18\x01 \x01(\tR\x04club\x12\x1a\n" +
+	"\btaxonomy\x18\x02 \x01(\tR\btaxonomy\x12C\n" +
+	"\x0fpublished_after\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x0epublishedAfter\x12E\n" +
+	"\x10published_before\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0fpublishedBefore\x12/\n" +
+	"\x13include_unpublished\x18\x05 \x01(\bR\x12includeUnpublished\"\x92\x01\n" +
+	"\x13ListArticlesRequest\x12?\n" +
+	"\x06filter\x18\x01 \x01(\v2'.feedprovider.articles.v1.ArticleFilterR\x06filter\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"\x9c\x01\n" +
+	"\x14ListArticlesResponse\x12=\n" +
+	"\barticles\x18\x01 \x03(\v2!.feedprovider.articles.v1.ArticleR\barticles\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x03 \x01(\x05R\ttotalSize\"\xaa\x01\n" +
+	"\x15SearchArticlesRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12?\n" +
+	"\x06filter\x18\x02 \x01(\v2'.feedprovider.articles.v1.ArticleFilterR\x06filter\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\"\x9e\x01\n" +
+	"\x16SearchArticlesResponse\x12=\n" +
+	"\barticles\x18\x01 \x03(\v2!.feedprovider.articles.v1.ArticleR\barticles\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x03 \x01(\x05R\ttotalSize\"\xa5\x01\n" +
+	"\x14WatchArticlesRequest\x12\x12\n" +
+	"\x04club\x18\x01 \x01(\tR\x04club\x12\x1a\n" +
+	"\btaxonomy\x18\x02 \x01(\tR\btaxonomy\x129\n" +
+	"\x05types\x18\x03 \x03(\x0e2#.feedprovider.articles.v1.EventTypeR\x05types\x12\"\n" +
+	"\rlast_event_id\x18\x04 \x01(\x04R\vlastEventId\"\xd1\x01\n" +
+	"\fArticleEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x127\n" +
+	"\x04type\x18\x02 \x01(\x0e2#.feedprovider.articles.v1.EventTypeR\x04type\x12;\n" +
+	"\voccurred_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12;\n" +
+	"\aarticle\x18\x04 \x01(\v2!.feedprovider.articles.v1.ArticleR\aarticle*t\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13EVENT_TYPE_INSERTED\x10\x01\x12\x16\n" +
+	"\x12EVENT_TYPE_UPDATED\x10\x02\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNPUBLISHED\x10\x032\xbd\x03\n" +
+	"\x0eArticleService\x12\\\n" +
+	"\n" +
+	"GetArticle\x12+.feedprovider.articles.v1.GetArticleRequest\x1a!.feedprovider.articles.v1.Article\x12m\n" +
+	"\fListArticles\x12-.feedprovider.articles.v1.ListArticlesRequest\x1a..feedprovider.articles.v1.ListArticlesResponse\x12s\n" +
+	"\x0eSearchArticles\x12/.feedprovider.articles.v1.SearchArticlesRequest\x1a0.feedprovider.articles.v1.SearchArticlesResponse\x12i\n" +
+	"\rWatchArticles\x12..feedprovider.articles.v1.WatchArticlesRequest\x1a&.feedprovider.articles.v1.ArticleEvent0\x01B.Z,alibazlamit/feed-provider/grpcapi/articlespbb\x06proto3"
+
+var (
+	file_articlespb_articles_proto_rawDescOnce sync.Once
+	file_articlespb_articles_proto_rawDescData []byte
+)
+
+func file_articlespb_articles_proto_rawDescGZIP() []byte {
+	file_articlespb_articles_proto_rawDescOnce.Do(func() {
+		file_articlespb_articles_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_articlespb_articles_proto_rawDesc), len(file_articlespb_articles_proto_rawDesc)))
+	})
+	return file_articlespb_articles_proto_rawDescData
+}
+
+var file_articlespb_articles_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_articlespb_articles_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_articlespb_articles_proto_goTypes = []any{
+	(EventType)(0),                 // 0: feedprovider.articles.v1.EventType
+	(*Article)(nil),                // 1: feedprovider.articles.v1.Article
+	(*Club)(nil),                   // 2: feedprovider.articles.v1.Club
+	(*Media)(nil),                  // 3: feedprovider.articles.v1.Media
+	(*GetArticleRequest)(nil),      // 4: feedprovider.articles.v1.GetArticleRequest
+	(*ArticleFilter)(nil),          // 5: feedprovider.articles.v1.ArticleFilter
+	(*ListArticlesRequest)(nil),    // 6: feedprovider.articles.v1.ListArticlesRequest
+	(*ListArticlesResponse)(nil),   // 7: feedprovider.articles.v1.ListArticlesResponse
+	(*SearchArticlesRequest)(nil),  // 8: feedprovider.articles.v1.SearchArticlesRequest
+	(*SearchArticlesResponse)(nil), // 9: feedprovider.articles.v1.SearchArticlesResponse
+	(*WatchArticlesRequest)(nil),   // 10: feedprovider.articles.v1.WatchArticlesRequest
+	(*ArticleEvent)(nil),           // 11: feedprovider.articles.v1.ArticleEvent
+	(*timestamppb.Timestamp)(nil),  // 12: google.protobuf.Timestamp
+}
+var file_articlespb_articles_proto_depIdxs = []int32{
+	12, // 0: feedprovider.articles.v1.Article.published:type_name -> google.protobuf.Timestamp
+	12, // 1: feedprovider.articles.v1.Article.updated:type_name -> google.protobuf.Timestamp
+	2,  // 2: feedprovider.articles.v1.Article.club:type_name -> feedprovider.articles.v1.Club
+	3,  // 3: feedprovider.articles.v1.Article.media:type_name -> feedprovider.articles.v1.Media
+	12, // 4: feedprovider.articles.v1.ArticleFilter.published_after:type_name -> google.protobuf.Timestamp
+	12, // 5: feedprovider.articles.v1.ArticleFilter.published_before:type_name -> google.protobuf.Timestamp
+	5,  // 6: feedprovider.articles.v1.ListArticlesRequest.filter:type_name -> feedprovider.articles.v1.ArticleFilter
+	1,  // 7: feedprovider.articles.v1.ListArticlesResponse.articles:type_name -> feedprovider.articles.v1.Article
+	5,  // 8: feedprovider.articles.v1.SearchArticlesRequest.filter:type_name -> feedprovider.articles.v1.ArticleFilter
+	1,  // 9: feedprovider.articles.v1.SearchArticlesResponse.articles:type_name -> feedprovider.articles.v1.Article
+	0,  // 10: feedprovider.articles.v1.WatchArticlesRequest.types:type_name -> feedprovider.articles.v1.EventType
+	0,  // 11: feedprovider.articles.v1.ArticleEvent.type:type_name -> feedprovider.articles.v1.EventType
+	12, // 12: feedprovider.articles.v1.ArticleEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	1,  // 13: feedprovider.articles.v1.ArticleEvent.article:type_name -> feedprovider.articles.v1.Article
+	4,  // 14: feedprovider.articles.v1.ArticleService.GetArticle:input_type -> feedprovider.articles.v1.GetArticleRequest
+	6,  // 15: feedprovider.articles.v1.ArticleService.ListArticles:input_type -> feedprovider.articles.v1.ListArticlesRequest
+	8,  // 16: feedprovider.articles.v1.ArticleService.SearchArticles:input_type -> feedprovider.articles.v1.SearchArticlesRequest
+	10, // 17: feedprovider.articles.v1.ArticleService.WatchArticles:input_type -> feedprovider.articles.v1.WatchArticlesRequest
+	1,  // 18: feedprovider.articles.v1.ArticleService.GetArticle:output_type -> feedprovider.articles.v1.Article
+	7,  // 19: feedprovider.articles.v1.ArticleService.ListArticles:output_type -> feedprovider.articles.v1.ListArticlesResponse
+	9,  // 20: feedprovider.articles.v1.ArticleService.SearchArticles:output_type -> feedprovider.articles.v1.SearchArticlesResponse
+	11, // 21: feedprovider.articles.v1.ArticleService.WatchArticles:output_type -> feedprovider.articles.v1.ArticleEvent
+	18, // [18:22] is the sub-list for method output_type
+	14, // [14:18] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_articlespb_articles_proto_init() }
+func file_articlespb_articles_proto_init() {
+	if File_articlespb_articles_proto != nil {
+		return
+	}
+	file_articlespb_articles_proto_msgTypes[3].OneofWrappers = []any{
+		(*GetArticleRequest_Id)(nil),
+		(*GetArticleRequest_UpstreamId)(nil),
+		(*GetArticleRequest_Slug)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_articlespb_articles_proto_rawDesc), len(file_articlespb_articles_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_articlespb_articles_proto_goTypes,
+		DependencyIndexes: file_articlespb_articles_proto_depIdxs,
+		EnumInfos:         file_articlespb_articles_proto_enumTypes,
+		MessageInfos:      file_articlespb_articles_proto_msgTypes,
+	}.Build()
+	File_articlespb_articles_proto = out.File
+	file_articlespb_articles_proto_goTypes = nil
+	file_articlespb_articles_proto_depIdxs = nil
+}
@@ -0,0 +1,226 @@
+package grpcapi
+
+import (
+	"alibazlamit/feed-provider/grpcapi/articlespb"
+	"alibazlamit/feed-provider/logging"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DEFAULT_ADDR = ":9090"
+	// metadata keys are lower case in gRPC
+	API_KEY_METADATA     = "x-api-key"
+	REQUEST_ID_METADATA  = "x-request-id"
+	FORWARDED_METADATA   = "x-forwarded-for"
+	RETRY_AFTER_METADATA = "retry-after"
+	// every method counts against this route of the rate limits
+	RATE_LIMIT_ROUTE = "grpc"
+)
+
+// the health and reflection services are open to everyone, like /ping and /docs
+var publicServices = []string{"/grpc.health.v1.", "/grpc.reflection."}
+
+// KeyAuthenticator checks the API key a call presents, the HTTP API's authenticator does
+type KeyAuthenticator interface {
+	AuthenticateKey(ctx context.Context, presented string) (*models.APIKey, error)
+}
+
+// RateLimiter takes a token from a client's bucket for a route, the HTTP API's limiters do
+type RateLimiter interface {
+	Allow(ctx context.Context, client, route string) (bool, time.Duration)
+}
+
+// RateLimits holds the limiters every call goes through, IP before authentication like
+// the HTTP API so invalid keys are throttled too, and Key after it. Either may be nil
+type RateLimits struct {
+	IP         RateLimiter
+	Key        RateLimiter
+	TrustProxy bool
+}
+
+// AddrFromEnv returns the address from GRPC_ADDR, DEFAULT_ADDR when unset
+func AddrFromEnv() string {
+	if addr := os.Getenv("GRPC_ADDR"); addr != "" {
+		return addr
+	}
+	return DEFAULT_ADDR
+}
+
+// NewGRPCServer registers the article, health and reflection services. The health
+// server reports the article service as serving, callers flip it with SetServing.
+func NewGRPCServer(server *Server, keys KeyAuthenticator, limits RateLimits, logger *slog.Logger) (*grpc.Server, *health.Server) {
+	i := &interceptor{keys: keys, limits: limits, logger: logger}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(i.unary), grpc.StreamInterceptor(i.stream))
+	articlespb.RegisterArticleServiceServer(grpcServer, server)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(articlespb.ArticleService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+	return grpcServer, healthServer
+}
+
+// SetServing reports the server as a whole and the article service as serving or not
+func SetServing(healthServer *health.Server, serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	healthServer.SetServingStatus("", status)
+	healthServer.SetServingStatus(articlespb.ArticleService_ServiceDesc.ServiceName, status)
+}
+
+// interceptor assigns every call a request ID, rate limits it, authenticates it with the
+// articles:read scope and writes a log line per call, in the format of the HTTP request log
+type interceptor struct {
+	keys   KeyAuthenticator
+	limits RateLimits
+	logger *slog.Logger
+}
+
+func (i *interceptor) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx = withRequestID(ctx)
+	keyID, err := i.admit(ctx, info.FullMethod)
+	var resp interface{}
+	if err == nil {
+		resp, err = handler(logging.WithAttrs(ctx, "key_id", keyID), req)
+	}
+	i.log(ctx, info.FullMethod, err, start, keyID)
+	return resp, err
+}
+
+func (i *interceptor) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withRequestID(stream.Context())
+	keyID, err := i.admit(ctx, info.FullMethod)
+	if err == nil {
+		err = handler(srv, &contextStream{ServerStream: stream, ctx: logging.WithAttrs(ctx, "key_id", keyID)})
+	}
+	i.log(ctx, info.FullMethod, err, start, keyID)
+	return err
+}
+
+// withRequestID keeps the x-request-id the client sent, or generates one, and echoes
+// it back in the response header
+func withRequestID(ctx context.Context) context.Context {
+	incoming := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(REQUEST_ID_METADATA); len(values) > 0 {
+			incoming = values[0]
+		}
+	}
+	requestID := logging.RequestIDOrNew(incoming)
+	grpc.SetHeader(ctx, metadata.Pairs(REQUEST_ID_METADATA, requestID))
+	return logging.WithRequestID(ctx, requestID)
+}
+
+// contextStream hands the handler a context carrying the request ID
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// admit rate limits the call by IP, authenticates it and then rate limits it by key
+func (i *interceptor) admit(ctx context.Context, method string) (string, error) {
+	if err := i.allow(ctx, i.limits.IP, "ip:"+clientIP(ctx, i.limits.TrustProxy)); err != nil {
+		return "-", err
+	}
+	keyID, err := i.authenticate(ctx, method)
+	if err != nil || isPublic(method) {
+		return keyID, err
+	}
+	return keyID, i.allow(ctx, i.limits.Key, "key:"+keyID)
+}
+
+func (i *interceptor) allow(ctx context.Context, limiter RateLimiter, client string) error {
+	if limiter == nil {
+		return nil
+	}
+	allowed, retryAfter := limiter.Allow(ctx, client, RATE_LIMIT_ROUTE)
+	if allowed {
+		return nil
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	grpc.SetHeader(ctx, metadata.Pairs(RETRY_AFTER_METADATA, strconv.Itoa(seconds)))
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %ds", seconds)
+}
+
+// clientIP returns the caller's address, x-forwarded-for is only trusted behind a proxy
+// that sets it, otherwise clients could pick their own bucket
+func clientIP(ctx context.Context, trustProxy bool) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && trustProxy {
+		if values := md.Get(FORWARDED_METADATA); len(values) > 0 {
+			first, _, _ := strings.Cut(values[0], ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "-"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func isPublic(method string) bool {
+	for _, prefix := range publicServices {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *interceptor) authenticate(ctx context.Context, method string) (string, error) {
+	if isPublic(method) {
+		return "-", nil
+	}
+	presented := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(API_KEY_METADATA); len(values) > 0 {
+			presented = values[0]
+		}
+	}
+	key, err := i.keys.AuthenticateKey(ctx, presented)
+	if err != nil {
+		return "-", status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !key.HasScope(models.ScopeArticlesRead) {
+		return key.KeyID, status.Error(codes.PermissionDenied, "API key lacks the "+string(models.ScopeArticlesRead)+" scope")
+	}
+	return key.KeyID, nil
+}
+
+func (i *interceptor) log(ctx context.Context, method string, err error, start time.Time, keyID string) {
+	i.logger.InfoContext(ctx, "grpc call",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration_ms", time.Since(start).Milliseconds(),
+		"key_id", keyID,
+	)
+}
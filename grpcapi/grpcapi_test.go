@@ -0,0 +1,236 @@
+package grpcapi
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
+	"alibazlamit/feed-provider/grpcapi/articlespb"
+	"alibazlamit/feed-provider/models"
+	"alibazlamit/feed-provider/ratelimit"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type testKeys map[string]*models.APIKey
+
+func (k testKeys) AuthenticateKey(ctx context.Context, presented string) (*models.APIKey, error) {
+	if key, ok := k[presented]; ok {
+		return key, nil
+	}
+	return nil, errors.New("invalid API key")
+}
+
+func testRepository() *database.MockArticleRepository {
+	repo := database.NewMockArticleRepository()
+	add := func(title, teaser, club, taxonomies string, day int, published bool) {
+		repo.Articles = append(repo.Articles, models.NewsArticleInformationMongoDB{
+			ID:          primitive.NewObjectID(),
+			Title:       title,
+			TeaserText:  teaser,
+			Slug:        strings.ToLower(title),
+			ClubName:    club,
+			Taxonomies:  taxonomies,
+			PublishDate: time.Date(2023, 7, day, 9, 0, 0, 0, time.UTC),
+			IsPublished: published,
+		})
+	}
+	add("Signing", "A new striker joins", "TEST CITY", "First Team, Transfers", 20, true)
+	add("Preview", "The striker starts on Saturday", "TEST CITY", "First Team", 21, true)
+	add("Academy", "Youngsters win the cup", "TEST CITY", "Academy", 22, true)
+	add("Striker on loan", "", "OTHER TOWN", "Transfers", 23, true)
+	add("Draft", "", "TEST CITY", "First Team", 24, false)
+	return repo
+}
+
+func dial(t *testing.T, repo database.ArticleRepository, broker *events.Broker) *grpc.ClientConn {
+	return dialWithLimits(t, repo, broker, RateLimits{})
+}
+
+func dialWithLimits(t *testing.T, repo database.ArticleRepository, broker *events.Broker, limits RateLimits) *grpc.ClientConn {
+	keys := testKeys{
+		"reader": {KeyID: "reader", Scopes: []models.Scope{models.ScopeArticlesRead}},
+		"admin":  {KeyID: "admin", Scopes: []models.Scope{models.ScopeAdminSync}},
+	}
+	server, _ := NewGRPCServer(NewServer(repo, broker, slog.New(slog.DiscardHandler)), keys, limits, slog.New(slog.DiscardHandler))
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), API_KEY_METADATA, key)
+}
+
+func titles(articles []*articlespb.Article) []string {
+	list := []string{}
+	for _, article := range articles {
+		list = append(list, article.Title)
+	}
+	return list
+}
+
+func TestGetArticle(t *testing.T) {
+	repo := testRepository()
+	client := articlespb.NewArticleServiceClient(dial(t, repo, events.NewBroker(10)))
+	ctx := withKey("reader")
+
+	article, err := client.GetArticle(ctx, &articlespb.GetArticleRequest{Key: &articlespb.GetArticleRequest_Slug{Slug: "Signing"}})
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Articles[0].ID.Hex(), article.Id)
+	assert.Equal(t, []string{"First Team", "Transfers"}, article.Tags)
+	assert.Equal(t, "TEST CITY", article.Club.Name)
+
+	_, err = client.GetArticle(ctx, &articlespb.GetArticleRequest{Key: &articlespb.GetArticleRequest_Id{Id: primitive.NewObjectID().Hex()}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetArticle(ctx, &articlespb.GetArticleRequest{Key: &articlespb.GetArticleRequest_Id{Id: "nope"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GetArticle(ctx, &articlespb.GetArticleRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListArticles(t *testing.T) {
+	client := articlespb.NewArticleServiceClient(dial(t, testRepository(), events.NewBroker(10)))
+	ctx := withKey("reader")
+
+	req := &articlespb.ListArticlesRequest{Filter: &articlespb.ArticleFilter{Club: "test city"}, PageSize: 2}
+	res, err := client.ListArticles(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Academy", "Preview"}, titles(res.Articles))
+	assert.Equal(t, int32(3), res.TotalSize)
+
+	req.PageToken = res.NextPageToken
+	res, err = client.ListArticles(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Signing"}, titles(res.Articles))
+	assert.Empty(t, res.NextPageToken)
+
+	res, err = client.ListArticles(ctx, &articlespb.ListArticlesRequest{Filter: &articlespb.ArticleFilter{
+		IncludeUnpublished: true,
+		PublishedAfter:     timestamppb.New(time.Date(2023, 7, 22, 12, 0, 0, 0, time.UTC)),
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Draft", "Striker on loan"}, titles(res.Articles))
+
+	_, err = client.ListArticles(ctx, &articlespb.ListArticlesRequest{PageToken: "bm90LWEtdG9rZW4"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	missing := models.NewsArticleInformationMongoDB{ID: primitive.NewObjectID()}
+	_, err = client.ListArticles(ctx, &articlespb.ListArticlesRequest{PageToken: encodePageToken(&missing)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ListArticles(ctx, &articlespb.ListArticlesRequest{PageSize: MAX_PAGE_SIZE + 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSearchArticles(t *testing.T) {
+	client := articlespb.NewArticleServiceClient(dial(t, testRepository(), events.NewBroker(10)))
+	ctx := withKey("reader")
+
+	// a title match ranks above teaser matches, then the newest first
+	res, err := client.SearchArticles(ctx, &articlespb.SearchArticlesRequest{Query: "STRIKER"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Striker on loan", "Preview", "Signing"}, titles(res.Articles))
+
+	res, err = client.SearchArticles(ctx, &articlespb.SearchArticlesRequest{Query: "striker saturday"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Preview"}, titles(res.Articles))
+
+	_, err = client.SearchArticles(ctx, &articlespb.SearchArticlesRequest{Query: " "})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWatchArticles(t *testing.T) {
+	broker := events.NewBroker(10)
+	broker.Publish(models.ArticleInserted, &models.NewsArticleInformationMongoDB{Title: "Old", ClubName: "OTHER TOWN"})
+	broker.Publish(models.ArticleInserted, &models.NewsArticleInformationMongoDB{Title: "Ignored", ClubName: "TEST CITY"})
+	client := articlespb.NewArticleServiceClient(dial(t, testRepository(), broker))
+
+	ctx, cancel := context.WithCancel(withKey("reader"))
+	defer cancel()
+	stream, err := client.WatchArticles(ctx, &articlespb.WatchArticlesRequest{
+		Club:  "other town",
+		Types: []articlespb.EventType{articlespb.EventType_EVENT_TYPE_INSERTED},
+	})
+	assert.NoError(t, err)
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), event.Id)
+	assert.Equal(t, "Old", event.Article.Title)
+
+	// keep publishing until the subscription is in place
+	go func() {
+		for ctx.Err() == nil {
+			broker.Publish(models.ArticleUpdated, &models.NewsArticleInformationMongoDB{Title: "Updated", ClubName: "OTHER TOWN"})
+			broker.Publish(models.ArticleInserted, &models.NewsArticleInformationMongoDB{Title: "New", ClubName: "OTHER TOWN"})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	event, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, articlespb.EventType_EVENT_TYPE_INSERTED, event.Type)
+	assert.Equal(t, "New", event.Article.Title)
+}
+
+func TestAuthentication(t *testing.T) {
+	conn := dial(t, testRepository(), events.NewBroker(10))
+	client := articlespb.NewArticleServiceClient(conn)
+
+	_, err := client.ListArticles(context.Background(), &articlespb.ListArticlesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.ListArticles(withKey("admin"), &articlespb.ListArticlesRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// health checks need no key
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: articlespb.ArticleService_ServiceDesc.ServiceName})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.Status)
+}
+
+func TestRateLimiting(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limits := RateLimits{
+		IP:  ratelimit.NewIPLimiter(ratelimit.Limit{Rate: 0.001, Burst: 3}, store, false, slog.New(slog.DiscardHandler)),
+		Key: ratelimit.NewLimiter(ratelimit.Limits{RATE_LIMIT_ROUTE: {Rate: 0.001, Burst: 1}}, store, nil, slog.New(slog.DiscardHandler)),
+	}
+	client := articlespb.NewArticleServiceClient(dialWithLimits(t, testRepository(), events.NewBroker(10), limits))
+
+	// the key's bucket runs out first, then the IP's, even for calls without a valid key
+	_, err := client.ListArticles(withKey("reader"), &articlespb.ListArticlesRequest{})
+	assert.NoError(t, err)
+	var header metadata.MD
+	_, err = client.ListArticles(withKey("reader"), &articlespb.ListArticlesRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get(RETRY_AFTER_METADATA))
+	_, err = client.ListArticles(withKey("invalid"), &articlespb.ListArticlesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.ListArticles(withKey("invalid"), &articlespb.ListArticlesRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestAddrFromEnv(t *testing.T) {
+	t.Setenv("GRPC_ADDR", "")
+	assert.Equal(t, DEFAULT_ADDR, AddrFromEnv())
+	t.Setenv("GRPC_ADDR", "127.0.0.1:50051")
+	assert.Equal(t, "127.0.0.1:50051", AddrFromEnv())
+}
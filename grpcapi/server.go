@@ -0,0 +1,142 @@
+// Package grpcapi serves the articles to internal consumers over gRPC, next to the
+// HTTP API and from the same repository and event broker.
+package grpcapi
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
+	"alibazlamit/feed-provider/grpcapi/articlespb"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"log/slog"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the ArticleService
+type Server struct {
+	articlespb.UnimplementedArticleServiceServer
+	articles database.ArticleRepository
+	events   *events.Broker
+	logger   *slog.Logger
+}
+
+func NewServer(articles database.ArticleRepository, broker *events.Broker, logger *slog.Logger) *Server {
+	return &Server{articles: articles, events: broker, logger: logger}
+}
+
+func (s *Server) GetArticle(ctx context.Context, req *articlespb.GetArticleRequest) (*articlespb.Article, error) {
+	var article *models.NewsArticleInformationMongoDB
+	var err error
+	switch key := req.Key.(type) {
+	case *articlespb.GetArticleRequest_Id:
+		id, parseErr := primitive.ObjectIDFromHex(key.Id)
+		if parseErr != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid article id")
+		}
+		article, err = s.articles.GetArticleByID(ctx, id)
+	case *articlespb.GetArticleRequest_UpstreamId:
+		article, err = s.articles.GetArticleByNewsArticleID(ctx, int(key.UpstreamId))
+	case *articlespb.GetArticleRequest_Slug:
+		article, err = s.articles.GetArticleBySlug(ctx, strings.ToLower(key.Slug))
+	default:
+		return nil, status.Error(codes.InvalidArgument, "one of id, upstream_id and slug is required")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error retrieving article", "error", err)
+		return nil, status.Error(codes.Internal, "error retrieving article")
+	}
+	if article == nil {
+		return nil, status.Error(codes.NotFound, "article not found")
+	}
+	return toArticle(article), nil
+}
+
+func (s *Server) ListArticles(ctx context.Context, req *articlespb.ListArticlesRequest) (*articlespb.ListArticlesResponse, error) {
+	pageSize, err := checkPageSize(req.PageSize)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	query := articleQuery(req.Filter)
+	query.Limit = int(pageSize)
+	if req.PageToken != "" {
+		if query.After, err = decodePageToken(req.PageToken); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	page, err := s.articles.FindArticles(ctx, query)
+	if err == database.ErrCursorNotFound {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidPageToken.Error())
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error retrieving articles", "error", err)
+		return nil, status.Error(codes.Internal, "error retrieving articles")
+	}
+	next := ""
+	if page.HasMore {
+		next = encodePageToken(&page.Articles[len(page.Articles)-1])
+	}
+	return &articlespb.ListArticlesResponse{Articles: toArticles(page.Articles), NextPageToken: next, TotalSize: int32(page.TotalCount)}, nil
+}
+
+// SearchArticles only loads the articles containing every term, the ranking
+// needs all of them so they are scored and paged here
+func (s *Server) SearchArticles(ctx context.Context, req *articlespb.SearchArticlesRequest) (*articlespb.SearchArticlesResponse, error) {
+	terms := strings.Fields(req.Query)
+	if len(terms) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	query := articleQuery(req.Filter)
+	query.Terms, query.Limit = terms, -1
+	matches, err := s.articles.FindArticles(ctx, query)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error retrieving articles", "error", err)
+		return nil, status.Error(codes.Internal, "error retrieving articles")
+	}
+	ranked := search(matches.Articles, req.Query)
+	page, next, err := paginate(ranked, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &articlespb.SearchArticlesResponse{Articles: toArticles(page), NextPageToken: next, TotalSize: int32(len(ranked))}, nil
+}
+
+// WatchArticles replays the logged events after last_event_id, then streams new ones
+// until the client goes away or falls behind
+func (s *Server) WatchArticles(req *articlespb.WatchArticlesRequest, stream articlespb.ArticleService_WatchArticlesServer) error {
+	filter := models.ArticleFilter{Club: req.Club, Taxonomy: req.Taxonomy}
+	types := map[articlespb.EventType]bool{}
+	for _, eventType := range req.Types {
+		types[eventType] = true
+	}
+	send := func(event *models.ArticleEvent) error {
+		if !filter.Matches(&event.Article) || (len(types) > 0 && !types[eventTypes[event.Type]]) {
+			return nil
+		}
+		return stream.Send(toEvent(event))
+	}
+
+	replay, subscription, cancel := s.events.Subscribe(req.LastEventId)
+	defer cancel()
+	for i := range replay {
+		if err := send(&replay[i]); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case event, ok := <-subscription:
+			if !ok {
+				return status.Error(codes.Unavailable, "fell behind the article events, resume with last_event_id")
+			}
+			if err := send(&event); err != nil {
+				return err
+			}
+		}
+	}
+}
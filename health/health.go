@@ -0,0 +1,166 @@
+// Package health runs the liveness and readiness checks and reports every component
+// as JSON, so an orchestrator can tell a stuck process from one serving stale data.
+package health
+
+import (
+	"alibazlamit/feed-provider/httpcache"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_TIMEOUT      = 2 * time.Second
+	DEFAULT_MAX_SYNC_AGE = 15 * time.Minute
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Component is the outcome of one check
+type Component struct {
+	Status   Status                 `json:"status"`
+	Message  string                 `json:"message,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Duration string                 `json:"duration"`
+}
+
+// Check reports on one component, it should give up once ctx is done
+type Check func(ctx context.Context) Component
+
+// Report is up when every component is
+type Report struct {
+	Status     Status               `json:"status"`
+	CheckedAt  time.Time            `json:"checkedAt"`
+	Components map[string]Component `json:"components"`
+}
+
+func Up(details map[string]interface{}) Component {
+	return Component{Status: StatusUp, Details: details}
+}
+
+func Down(message string, details map[string]interface{}) Component {
+	return Component{Status: StatusDown, Message: message, Details: details}
+}
+
+// Run runs the checks concurrently, a check still running after timeout is reported down
+func Run(ctx context.Context, checks map[string]Check, timeout time.Duration) Report {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	report := Report{Status: StatusUp, CheckedAt: time.Now().UTC(), Components: map[string]Component{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			start := time.Now()
+			done := make(chan Component, 1)
+			go func() { done <- check(ctx) }()
+
+			var component Component
+			select {
+			case component = <-done:
+			case <-ctx.Done():
+				component = Down("check timed out after "+timeout.String(), nil)
+			}
+			component.Duration = time.Since(start).Round(time.Millisecond).String()
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = component
+			if component.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// Handler answers 200 when every check is up and 503 otherwise, with the report as body
+func Handler(checks map[string]Check, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), checks, timeout)
+		statusCode := http.StatusOK
+		if report.Status != StatusUp {
+			statusCode = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", httpcache.NO_STORE)
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Process reports the process itself, it is up as long as it can answer
+func Process(started time.Time) Check {
+	hostname, _ := os.Hostname()
+	return func(ctx context.Context) Component {
+		return Up(map[string]interface{}{
+			"hostname":   hostname,
+			"startedAt":  started.UTC(),
+			"uptime":     time.Since(started).Round(time.Second).String(),
+			"goroutines": runtime.NumGoroutine(),
+		})
+	}
+}
+
+// Ping reports whether a dependency answers a ping
+func Ping(ping func(ctx context.Context) error) Check {
+	return func(ctx context.Context) Component {
+		if err := ping(ctx); err != nil {
+			return Down(err.Error(), nil)
+		}
+		return Up(nil)
+	}
+}
+
+// Freshness reports whether the last successful sync is at most maxAge old. A replica
+// that never synced is down, its data could be of any age.
+func Freshness(status func() models.SyncStatus, maxAge time.Duration) Check {
+	return func(ctx context.Context) Component {
+		last := status()
+		details := map[string]interface{}{"maxAge": maxAge.String()}
+		if !last.LastRun.IsZero() {
+			details["lastRun"] = last.LastRun.UTC()
+		}
+		if last.LastError != "" {
+			details["lastError"] = last.LastError
+		}
+		if last.LastSuccess.IsZero() {
+			return Down("no successful sync yet", details)
+		}
+		age := time.Since(last.LastSuccess)
+		details["lastSuccess"] = last.LastSuccess.UTC()
+		details["age"] = age.Round(time.Second).String()
+		if age > maxAge {
+			return Down(fmt.Sprintf("last successful sync is older than %s", maxAge), details)
+		}
+		return Up(details)
+	}
+}
+
+// MaxSyncAgeFromEnv reads READY_MAX_SYNC_AGE, DEFAULT_MAX_SYNC_AGE when unset
+func MaxSyncAgeFromEnv() (time.Duration, error) {
+	value := os.Getenv("READY_MAX_SYNC_AGE")
+	if value == "" {
+		return DEFAULT_MAX_SYNC_AGE, nil
+	}
+	maxAge, err := time.ParseDuration(value)
+	if err != nil || maxAge <= 0 {
+		return 0, fmt.Errorf("invalid READY_MAX_SYNC_AGE %q", value)
+	}
+	return maxAge, nil
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"alibazlamit/feed-provider/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	report := Run(context.Background(), map[string]Check{
+		"up":   Ping(func(ctx context.Context) error { return nil }),
+		"down": Ping(func(ctx context.Context) error { return errors.New("connection refused") }),
+		"slow": func(ctx context.Context) Component {
+			<-ctx.Done()
+			return Up(nil)
+		},
+	}, 50*time.Millisecond)
+
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Components["up"].Status)
+	assert.Equal(t, "connection refused", report.Components["down"].Message)
+	assert.Equal(t, "check timed out after 50ms", report.Components["slow"].Message)
+}
+
+func TestHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Handler(map[string]Check{"process": Process(time.Now())}, DEFAULT_TIMEOUT)(rr, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusUp, report.Status)
+	assert.Contains(t, report.Components["process"].Details, "goroutines")
+
+	rr = httptest.NewRecorder()
+	Handler(map[string]Check{"repository": Ping(func(ctx context.Context) error { return errors.New("down") })}, DEFAULT_TIMEOUT)(rr, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestFreshness(t *testing.T) {
+	status := models.SyncStatus{}
+	check := Freshness(func() models.SyncStatus { return status }, time.Minute)
+
+	component := check(context.Background())
+	assert.Equal(t, StatusDown, component.Status)
+	assert.Equal(t, "no successful sync yet", component.Message)
+
+	status = models.SyncStatus{LastRun: time.Now(), LastSuccess: time.Now().Add(-30 * time.Second)}
+	assert.Equal(t, StatusUp, check(context.Background()).Status)
+
+	// a failing upstream doesn't matter until the data gets too old
+	status = models.SyncStatus{LastRun: time.Now(), LastSuccess: time.Now().Add(-2 * time.Minute), LastError: "503 Service Unavailable"}
+	component = check(context.Background())
+	assert.Equal(t, StatusDown, component.Status)
+	assert.Equal(t, "503 Service Unavailable", component.Details["lastError"])
+	assert.Equal(t, "2m0s", component.Details["age"])
+}
+
+func TestMaxSyncAgeFromEnv(t *testing.T) {
+	t.Setenv("READY_MAX_SYNC_AGE", "")
+	maxAge, err := MaxSyncAgeFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, DEFAULT_MAX_SYNC_AGE, maxAge)
+
+	t.Setenv("READY_MAX_SYNC_AGE", "1h")
+	maxAge, _ = MaxSyncAgeFromEnv()
+	assert.Equal(t, time.Hour, maxAge)
+
+	t.Setenv("READY_MAX_SYNC_AGE", "soon")
+	_, err = MaxSyncAgeFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"alibazlamit/feed-provider/grpcapi"
+	"alibazlamit/feed-provider/health"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"net/http"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+)
+
+var startedAt = time.Now()
+
+// how old the last successful poll of the upstream may be before the replica stops taking
+// traffic, overridable through READY_MAX_SYNC_AGE
+var maxSyncAge = health.DEFAULT_MAX_SYNC_AGE
+
+// how often the readiness checks are run for the gRPC health service
+const GRPC_HEALTH_PERIOD = 10 * time.Second
+
+// syncStatus reports the reader's polls, set to the reader's in main
+var syncStatus func() models.SyncStatus
+
+// healthz is the liveness check, it only fails when the process can't answer at all so a
+// Mongo or upstream outage doesn't get every replica restarted
+func healthz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]health.Check{
+		"process": health.Process(startedAt),
+	}
+	health.Handler(checks, health.DEFAULT_TIMEOUT)(w, r)
+}
+
+// readinessChecks fail while the repository is unreachable or the articles are staler
+// than maxSyncAge
+func readinessChecks() map[string]health.Check {
+	return map[string]health.Check{
+		"repository": health.Ping(articleRepository.Ping),
+		"feed":       health.Freshness(syncStatus, maxSyncAge),
+	}
+}
+
+// readyz is the readiness check
+func readyz(w http.ResponseWriter, r *http.Request) {
+	health.Handler(readinessChecks(), health.DEFAULT_TIMEOUT)(w, r)
+}
+
+// updateGRPCHealth runs the readiness checks and reports the outcome through the gRPC
+// health service, so gRPC clients are taken off a replica along with HTTP traffic
+func updateGRPCHealth(ctx context.Context, healthServer *grpchealth.Server) {
+	report := health.Run(ctx, readinessChecks(), health.DEFAULT_TIMEOUT)
+	grpcapi.SetServing(healthServer, report.Status == health.StatusUp)
+}
+
+// watchGRPCHealth updates the gRPC health service every period until ctx is done
+func watchGRPCHealth(ctx context.Context, healthServer *grpchealth.Server, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			updateGRPCHealth(ctx, healthServer)
+		}
+	}
+}
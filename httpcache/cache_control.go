@@ -0,0 +1,52 @@
+package httpcache
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const NO_STORE = "no-store"
+
+// Policies maps route names to the Cache-Control header sent on them
+type Policies map[string]string
+
+// ParsePolicies reads overrides in the form "route=directives;route=directives",
+// e.g. "articles=public, max-age=30;article=public, max-age=300"
+func ParsePolicies(value string) Policies {
+	policies := Policies{}
+	for _, entry := range strings.Split(value, ";") {
+		name, directives, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		policies[strings.TrimSpace(name)] = strings.TrimSpace(directives)
+	}
+	return policies
+}
+
+// Merge returns a copy of p with the overrides applied
+func (p Policies) Merge(overrides Policies) Policies {
+	merged := Policies{}
+	for name, directives := range p {
+		merged[name] = directives
+	}
+	for name, directives := range overrides {
+		merged[name] = directives
+	}
+	return merged
+}
+
+// Middleware sets the Cache-Control header configured for the matched route,
+// handlers can still override it, e.g. with no-store on errors
+func (p Policies) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if directives, ok := p[route.GetName()]; ok {
+				w.Header().Set("Cache-Control", directives)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
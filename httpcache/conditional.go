@@ -0,0 +1,70 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Validators identify a representation for conditional requests
+type Validators struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// StrongETag hashes the given parts into a quoted strong entity tag
+func StrongETag(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+}
+
+// NotModified sets the validator headers and answers with 304 when the request's
+// If-None-Match or If-Modified-Since shows the client already has this representation
+func NotModified(w http.ResponseWriter, r *http.Request, validators Validators) bool {
+	if validators.ETag != "" {
+		w.Header().Set("ETag", validators.ETag)
+	}
+	if !validators.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", validators.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	// If-None-Match takes precedence over If-Modified-Since, RFC 9110 section 13.2.2
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if !etagMatches(ifNoneMatch, validators.ETag) {
+			return false
+		}
+	} else if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !validators.LastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil || validators.LastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// If-None-Match uses the weak comparison
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
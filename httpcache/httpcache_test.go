@@ -0,0 +1,61 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2023, 7, 27, 2, 0, 28, 500, time.UTC)
+	validators := Validators{ETag: StrongETag("a", "b"), LastModified: lastModified}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected bool
+	}{
+		{"no validators", map[string]string{}, false},
+		{"matching etag", map[string]string{"If-None-Match": `"other", ` + validators.ETag}, true},
+		{"weak matching etag", map[string]string{"If-None-Match": "W/" + validators.ETag}, true},
+		{"stale etag wins over date", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified.Format(http.TimeFormat)}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, true},
+		{"modified since", map[string]string{"If-Modified-Since": lastModified.Add(-time.Minute).Format(http.TimeFormat)}, false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/articles", nil)
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+
+		assert.Equal(t, test.expected, NotModified(rr, req, validators), test.name)
+		assert.Equal(t, validators.ETag, rr.Header().Get("ETag"), test.name)
+		assert.Equal(t, "Thu, 27 Jul 2023 02:00:28 GMT", rr.Header().Get("Last-Modified"), test.name)
+	}
+}
+
+func TestPoliciesMiddleware(t *testing.T) {
+	policies := Policies{"articles": "public, max-age=60", "article": "public, max-age=300"}.
+		Merge(ParsePolicies("articles=private, max-age=5; bogus"))
+
+	router := mux.NewRouter()
+	router.Use(policies.Middleware)
+	router.HandleFunc("/articles", func(w http.ResponseWriter, r *http.Request) {}).Name("articles")
+	router.HandleFunc("/articles/{id}", func(w http.ResponseWriter, r *http.Request) {}).Name("article")
+	router.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {})
+
+	for path, expected := range map[string]string{
+		"/articles":   "private, max-age=5",
+		"/articles/1": "public, max-age=300",
+		"/ping":       "",
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, expected, rr.Header().Get("Cache-Control"), path)
+	}
+}
@@ -0,0 +1,158 @@
+// Package logging builds the structured loggers every package writes to. Records carry
+// the attributes stored in their context, so a request ID set by the HTTP middleware
+// shows up in the repository's logs for the same request.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	REQUEST_ID_HEADER = "X-Request-ID"
+	REQUEST_ID_KEY    = "request_id"
+	FORMAT_JSON       = "json"
+	FORMAT_TEXT       = "text"
+)
+
+// an incoming request ID is kept only when it is short and safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type Config struct {
+	Level  slog.Level
+	Format string
+}
+
+// ConfigFromEnv reads LOG_LEVEL (debug, info, warn or error) and LOG_FORMAT (json or text)
+func ConfigFromEnv() (Config, error) {
+	config := Config{Level: slog.LevelInfo, Format: FORMAT_JSON}
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		level, err := ParseLevel(value)
+		if err != nil {
+			return Config{}, err
+		}
+		config.Level = level
+	}
+	if value := os.Getenv("LOG_FORMAT"); value != "" {
+		format := strings.ToLower(value)
+		if format != FORMAT_JSON && format != FORMAT_TEXT {
+			return Config{}, fmt.Errorf("invalid LOG_FORMAT %q", value)
+		}
+		config.Format = format
+	}
+	return config, nil
+}
+
+// ParseLevel accepts the level names in any case, plus offsets such as debug+2
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", value)
+	}
+	return level, nil
+}
+
+// New returns a logger writing to w and the level it filters at, changing the level
+// takes effect at once for every logger derived from it
+func New(w io.Writer, config Config) (*slog.Logger, *slog.LevelVar) {
+	level := new(slog.LevelVar)
+	level.Set(config.Level)
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewJSONHandler(w, options)
+	if config.Format == FORMAT_TEXT {
+		handler = slog.NewTextHandler(w, options)
+	}
+	return slog.New(contextHandler{handler}), level
+}
+
+type contextKey struct{}
+
+type contextAttrs struct {
+	requestID string
+	attrs     []slog.Attr
+}
+
+// WithAttrs returns a context whose log records carry the attributes, on top of the
+// ones the context already had
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, contextKey{}, extend(ctx, args))
+}
+
+// WithRequestID tags the context's log records with the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	next := extend(ctx, []any{REQUEST_ID_KEY, requestID})
+	next.requestID = requestID
+	return context.WithValue(ctx, contextKey{}, next)
+}
+
+func extend(ctx context.Context, args []any) *contextAttrs {
+	next := &contextAttrs{}
+	if current, ok := ctx.Value(contextKey{}).(*contextAttrs); ok {
+		next.requestID = current.requestID
+		next.attrs = append(next.attrs, current.attrs...)
+	}
+	next.attrs = append(next.attrs, argsToAttrs(args)...)
+	return next
+}
+
+// RequestID returns the ID WithRequestID stored, empty outside a request
+func RequestID(ctx context.Context) string {
+	if current, ok := ctx.Value(contextKey{}).(*contextAttrs); ok {
+		return current.requestID
+	}
+	return ""
+}
+
+// RequestIDOrNew keeps a well-formed incoming ID so a caller's logs can be matched
+// with ours, and makes up a new one otherwise
+func RequestIDOrNew(incoming string) string {
+	if validRequestID.MatchString(incoming) {
+		return incoming
+	}
+	return NewID()
+}
+
+// NewID returns a random 16 character hex ID
+func NewID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// contextHandler adds the attributes of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if current, ok := ctx.Value(contextKey{}).(*contextAttrs); ok {
+		record.AddAttrs(current.attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	record := slog.Record{}
+	record.Add(args...)
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return attrs
+}
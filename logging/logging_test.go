@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	config, err := ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Level: slog.LevelInfo, Format: FORMAT_JSON}, config)
+
+	t.Setenv("LOG_LEVEL", "DEBUG")
+	t.Setenv("LOG_FORMAT", "text")
+	config, err = ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Level: slog.LevelDebug, Format: FORMAT_TEXT}, config)
+
+	t.Setenv("LOG_LEVEL", "verbose")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, `invalid log level "verbose"`)
+
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "xml")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, `invalid LOG_FORMAT "xml"`)
+}
+
+func TestContextAttrs(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, _ := New(out, Config{Level: slog.LevelInfo, Format: FORMAT_JSON})
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithAttrs(ctx, "key_id", "abc")
+	logger.InfoContext(ctx, "article synced", "article_id", 42)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "article synced", record["msg"])
+	assert.Equal(t, "req-1", record[REQUEST_ID_KEY])
+	assert.Equal(t, "abc", record["key_id"])
+	assert.Equal(t, float64(42), record["article_id"])
+	assert.Equal(t, "req-1", RequestID(ctx))
+	assert.Equal(t, "", RequestID(context.Background()))
+}
+
+func TestLevelChangesAtRuntime(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, level := New(out, Config{Level: slog.LevelInfo, Format: FORMAT_TEXT})
+	derived := logger.With("component", "reader")
+
+	derived.Debug("hidden")
+	assert.Empty(t, out.String())
+
+	level.Set(slog.LevelDebug)
+	derived.Debug("shown")
+	assert.Contains(t, out.String(), "msg=shown component=reader")
+}
+
+func TestRequestIDOrNew(t *testing.T) {
+	assert.Equal(t, "3f2a-17:b.c_d", RequestIDOrNew("3f2a-17:b.c_d"))
+
+	for _, incoming := range []string{"", "has space", "line\nbreak", strings.Repeat("a", 129)} {
+		id := RequestIDOrNew(incoming)
+		assert.NotEqual(t, incoming, id)
+		assert.Len(t, id, 16)
+	}
+	assert.NotEqual(t, NewID(), NewID())
+}
@@ -1,15 +1,34 @@
 package main
 
 import (
+	"alibazlamit/feed-provider/apidocs"
+	"alibazlamit/feed-provider/auth"
+	"alibazlamit/feed-provider/cassette"
+	"alibazlamit/feed-provider/cors"
 	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
 	reader "alibazlamit/feed-provider/feed-reader"
+	"alibazlamit/feed-provider/graphqlapi"
+	"alibazlamit/feed-provider/grpcapi"
+	"alibazlamit/feed-provider/health"
+	"alibazlamit/feed-provider/httpcache"
+	"alibazlamit/feed-provider/logging"
 	"alibazlamit/feed-provider/models"
+	"alibazlamit/feed-provider/ratelimit"
+	"alibazlamit/feed-provider/requestlog"
+	"alibazlamit/feed-provider/upstream"
+	"alibazlamit/feed-provider/validation"
+	"alibazlamit/feed-provider/webhooks"
 	"context"
 	"encoding/json"
+	"expvar"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -19,77 +38,372 @@ import (
 )
 
 var ctx = context.TODO()
-var logger *log.Logger
+var logger *slog.Logger
+var logLevel *slog.LevelVar
 var articleRepository database.ArticleRepository
+var webhookRepository database.WebhookRepository
+var qualityRepository database.QualityRepository
+var apiKeyRepository database.APIKeyRepository
+var feedReader *reader.Reader
+var articleEvents = events.NewBroker(EVENT_LOG_SIZE)
+var graphqlHandler *graphqlapi.Handler
+
+// Cache-Control per named route, overridable through the CACHE_CONTROL environment variable.
+// The routes need the articles:read scope, so only the client's own cache may keep them.
+// A diff without ?to= is against the latest revision, so it changes with the next edit
+var defaultCachePolicies = httpcache.Policies{
+	"articles":          "private, max-age=60",
+	"article":           "private, max-age=300",
+	"article-by-source": "private, max-age=300",
+	"article-by-slug":   "private, max-age=300",
+	"revisions":         "private, max-age=60",
+	"revision":          "private, max-age=86400, immutable",
+	"diff":              "private, max-age=60",
+	"feed":              "private, max-age=300",
+	"club-feed":         "private, max-age=300",
+	"taxonomy-feed":     "private, max-age=300",
+}
+
+// token buckets per client, the full article list is the expensive route.
+// Overridable through the RATE_LIMITS environment variable
+var defaultRateLimits = ratelimit.Limits{
+	ratelimit.DEFAULT_ROUTE: {Rate: 10, Burst: 20},
+	"articles":              {Rate: 1, Burst: 5},
+	ratelimit.IP_BUCKET:     {Rate: 50, Burst: 100},
+}
+
+// scope required by each named route, a route missing here answers 403
+var routeScopes = auth.RouteScopes{
+	"ping":                auth.PUBLIC,
+	"healthz":             auth.PUBLIC,
+	"readyz":              auth.PUBLIC,
+	"openapi":             auth.PUBLIC,
+	"docs":                auth.PUBLIC,
+	"debug-vars":          models.ScopeAdminSync,
+	"articles":            models.ScopeArticlesRead,
+	"stream":              models.ScopeArticlesRead,
+	"article-by-source":   models.ScopeArticlesRead,
+	"article-by-slug":     models.ScopeArticlesRead,
+	"article":             models.ScopeArticlesRead,
+	"revisions":           models.ScopeArticlesRead,
+	"revision":            models.ScopeArticlesRead,
+	"diff":                models.ScopeArticlesRead,
+	"feed":                models.ScopeArticlesRead,
+	"club-feed":           models.ScopeArticlesRead,
+	"taxonomy-feed":       models.ScopeArticlesRead,
+	"start-archive-crawl": models.ScopeAdminSync,
+	"archive-crawl":       models.ScopeAdminSync,
+	"data-quality":        models.ScopeAdminSync,
+	"log-level":           models.ScopeAdminSync,
+	"set-log-level":       models.ScopeAdminSync,
+	"create-api-key":      models.ScopeAdminKeys,
+	"api-keys":            models.ScopeAdminKeys,
+	"rotate-api-key":      models.ScopeAdminKeys,
+	"revoke-api-key":      models.ScopeAdminKeys,
+	"create-webhook":      models.ScopeWebhooksManage,
+	"webhooks":            models.ScopeWebhooksManage,
+	"delete-webhook":      models.ScopeWebhooksManage,
+	"webhook-deliveries":  models.ScopeWebhooksManage,
+	"graphql":             models.ScopeArticlesRead,
+}
+
+const (
+	EVENT_LOG_SIZE       = 1000
+	SSE_HEARTBEAT_PERIOD = 15 * time.Second
+	// reasons of the resync event, the client reloads the articles instead of resuming
+	SSE_RESYNC_RESTARTED = "restarted"
+	SSE_RESYNC_MISSED    = "missed"
+)
 
 func main() {
-	logger := log.New(os.Stdout, "", log.LstdFlags)
+	logConfig, err := logging.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring logging: %v\n", err)
+		os.Exit(1)
+	}
+	logger, logLevel = logging.New(os.Stdout, logConfig)
 
 	mongoURI := os.Getenv("MONGO_URI")
 	if mongoURI == "" {
-		logger.Fatal("MONGO_URI environment variable is not set")
-		return
+		fatal("MONGO_URI environment variable is not set", nil)
 	}
 	// Initialize MongoDB client or connection pool
 	clientOptions := options.Client().ApplyURI(mongoURI)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Fatal(err)
+		fatal("error connecting to MongoDB", err)
 	}
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatal(err)
+		fatal("error pinging MongoDB", err)
 	}
 	//init collection and document
 	collection := client.Database("news_feed").Collection("news")
-	articleRepository = &database.MongoDBArticleRepository{
-		Collection: collection,
+	revisionsCollection := client.Database("news_feed").Collection("news_revisions")
+	mongoRepository := &database.MongoDBArticleRepository{
+		Collection:           collection,
+		RevisionsCollection:  revisionsCollection,
+		MigrationsCollection: client.Database("news_feed").Collection("migrations"),
+		Logger:               logger,
+	}
+	err = mongoRepository.CreateIndexes(ctx)
+	if err != nil {
+		fatal("error creating indexes", err)
+	}
+	articleRepository = mongoRepository
+	webhookStore := &database.MongoDBWebhookRepository{
+		SubscriptionsCollection: client.Database("news_feed").Collection("webhook_subscriptions"),
+		DeliveriesCollection:    client.Database("news_feed").Collection("webhook_deliveries"),
+		Logger:                  logger,
+	}
+	err = webhookStore.CreateIndexes(ctx)
+	if err != nil {
+		fatal("error creating indexes", err)
+	}
+	webhookRepository = webhookStore
+	apiKeys := &database.MongoDBAPIKeyRepository{
+		Collection: client.Database("news_feed").Collection("api_keys"),
 		Logger:     logger,
 	}
+	err = apiKeys.CreateIndexes(ctx)
+	if err != nil {
+		fatal("error creating indexes", err)
+	}
+	apiKeyRepository = apiKeys
+
+	//build the upstream client from the UPSTREAM_* settings and pass it to the feed reader
+	upstreamConfig, err := upstream.ConfigFromEnv()
+	if err != nil {
+		fatal("error configuring upstream client", err)
+	}
+	upstreamClient, err := upstream.NewClient(upstreamConfig)
+	if err != nil {
+		fatal("error configuring upstream client", err)
+	}
+	// optionally record the upstream traffic to a cassette, or replay one instead of calling the upstream
+	cassetteConfig, err := cassette.ConfigFromEnv()
+	if err != nil {
+		fatal("error configuring cassette", err)
+	}
+	readerClient, err := cassette.Wrap(cassetteConfig, upstreamClient)
+	if err != nil {
+		fatal("error loading cassette", err)
+	}
+	timeFormat, err := models.ParseTimeFormat(os.Getenv("FEED_TIME_LAYOUTS"), os.Getenv("FEED_TIMEZONE"))
+	if err != nil {
+		fatal("error configuring feed time format", err)
+	}
+	// correct dates stored before they were normalised to UTC, before the first sync sees them
+	migrated, err := mongoRepository.MigrateDatesToUTC(ctx, timeFormat.Location)
+	if err != nil {
+		fatal("error migrating article dates", err)
+	}
+	if migrated > 0 {
+		logger.InfoContext(ctx, "article dates migrated to UTC", "articles", migrated)
+	}
+	validationRules, err := validation.RulesFromEnv()
+	if err != nil {
+		fatal("error configuring validation", err)
+	}
+	qualityStore := &database.MongoDBQualityRepository{
+		ViolationsCollection: client.Database("news_feed").Collection("data_quality_violations"),
+		QuarantineCollection: client.Database("news_feed").Collection("quarantined_articles"),
+		Logger:               logger,
+	}
+	err = qualityStore.CreateIndexes(ctx)
+	if err != nil {
+		fatal("error creating indexes", err)
+	}
+	qualityRepository = qualityStore
+	checkpointRepository := &database.MongoDBCheckpointRepository{
+		Collection: client.Database("news_feed").Collection("crawl_checkpoints"),
+		Logger:     logger,
+	}
+	poolConfig, err := reader.PoolConfigFromEnv()
+	if err != nil {
+		fatal("error configuring reader pool", err)
+	}
+	listLimits, err := reader.ListLimitsFromEnv()
+	if err != nil {
+		fatal("error configuring list limits", err)
+	}
+	archiveConfig, err := reader.ArchiveConfigFromEnv()
+	if err != nil {
+		fatal("error configuring archive crawl", err)
+	}
+	feedReader = reader.NewReader(articleRepository, logger, readerClient,
+		reader.WithBaseURL(upstreamConfig.BaseURL),
+		reader.WithEventPublisher(articleEvents),
+		reader.WithPoolConfig(poolConfig),
+		reader.WithListLimits(listLimits),
+		reader.WithTimeFormat(timeFormat),
+		reader.WithValidation(os.Getenv("FEED_NAME"), validationRules, qualityRepository),
+		reader.WithArchive(archiveConfig, checkpointRepository),
+	)
+
+	syncStatus = feedReader.SyncStatus
+	if maxSyncAge, err = health.MaxSyncAgeFromEnv(); err != nil {
+		fatal("error configuring readiness", err)
+	}
 
-	//set timeout on default http client and pass it to the feed reader
-	http.DefaultClient.Timeout = 4 * time.Second
-	r := reader.NewReader(articleRepository, logger, http.DefaultClient)
+	//deliver article events to webhook subscribers
+	dispatcher := webhooks.NewDispatcher(webhookRepository, logger, &http.Client{Timeout: 10 * time.Second})
+	go dispatcher.Run(ctx, articleEvents)
 
 	//run our cron job to poll data from feed
-	err = r.RunCronFeedReader()
+	err = feedReader.RunCronFeedReader()
 	if err != nil {
-		logger.Fatalf("Error running cron feed reader: %v", err)
+		fatal("error running cron feed reader", err)
 	}
 
-	router := mux.NewRouter()
+	authenticator := auth.NewAuthenticator(apiKeyRepository, routeScopes, logger)
+	authenticator.SetBootstrapKey(os.Getenv("API_KEY_BOOTSTRAP"))
+	jwtConfig, err := auth.JWTConfigFromEnv()
+	if err != nil {
+		fatal("error configuring JWT validation", err)
+	}
+	if jwtConfig.Enabled() {
+		tokens, err := auth.NewTokenValidatorFromConfig(jwtConfig)
+		if err != nil {
+			fatal("error loading JWKS", err)
+		}
+		authenticator.SetTokenValidator(tokens)
+	}
+	cachePolicies := defaultCachePolicies.Merge(httpcache.ParsePolicies(os.Getenv("CACHE_CONTROL")))
+	rateLimits, err := ratelimit.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		fatal("error configuring rate limits", err)
+	}
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if os.Getenv("RATE_LIMIT_BACKEND") == "mongo" {
+		mongoStore := &ratelimit.MongoStore{Collection: client.Database("news_feed").Collection("rate_limits")}
+		if err = mongoStore.CreateIndexes(); err != nil {
+			fatal("error creating indexes", err)
+		}
+		rateLimitStore = mongoStore
+	}
+	trustProxy := os.Getenv("RATE_LIMIT_TRUST_PROXY") == "true"
+	limits := defaultRateLimits.Merge(rateLimits)
+	ipLimiter := ratelimit.NewIPLimiter(limits[ratelimit.IP_BUCKET], rateLimitStore, trustProxy, logger)
+	limiter := ratelimit.NewLimiter(limits, rateLimitStore, func(r *http.Request) string {
+		if key := auth.KeyFromContext(r.Context()); key != nil {
+			return "key:" + key.KeyID
+		}
+		return "ip:" + ratelimit.ClientIP(r, trustProxy)
+	}, logger)
+	if value := os.Getenv("API_SUNSET"); value != "" {
+		if unversionedSunset, err = time.Parse(SUNSET_LAYOUT, value); err != nil {
+			fatal("error parsing API_SUNSET", err)
+		}
+	}
+	graphqlLimits, err := graphqlapi.LimitsFromEnv()
+	if err != nil {
+		fatal("error configuring GraphQL limits", err)
+	}
+	graphqlSchema, err := graphqlapi.NewSchema(articleRepository, articleEvents)
+	if err != nil {
+		fatal("error building GraphQL schema", err)
+	}
+	graphqlHandler = graphqlapi.NewHandler(graphqlSchema, graphqlLimits, logger)
+	// every IP is held to the ip bucket before authentication so invalid keys are throttled,
+	// the route limiter runs after it so callers with a key get their own bucket
+	router := newRouter(ipLimiter.Middleware, authenticator.Middleware, limiter.Middleware, cachePolicies.Middleware)
 
-	// API endpoints
-	router.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprint(w, "PONG")
-	})
-	router.HandleFunc("/articles", getAllArticles).Methods("GET")
-	router.HandleFunc("/articles/{id}", getArticleByID).Methods("GET")
+	corsConfig, err := cors.ConfigFromEnv()
+	if err != nil {
+		fatal("error configuring CORS", err)
+	}
+
+	// serve internal consumers over gRPC next to the HTTP API
+	grpcLimits := grpcapi.RateLimits{IP: ipLimiter, Key: limiter, TrustProxy: trustProxy}
+	grpcServer, grpcHealth := grpcapi.NewGRPCServer(grpcapi.NewServer(articleRepository, articleEvents, logger), authenticator, grpcLimits, logger)
+	updateGRPCHealth(ctx, grpcHealth)
+	go watchGRPCHealth(ctx, grpcHealth, GRPC_HEALTH_PERIOD)
+	grpcAddr := grpcapi.AddrFromEnv()
+	grpcListener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		fatal("error listening for gRPC on "+grpcAddr, err)
+	}
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			fatal("error serving gRPC", err)
+		}
+	}()
+	logger.Info("gRPC server listening", "addr", grpcAddr)
 
 	// Start the HTTP server on port 8080
-	fmt.Println("Server listening on http://localhost:8080")
-	err = http.ListenAndServe(":8080", router)
+	logger.Info("server listening", "url", "http://localhost:8080")
+	err = http.ListenAndServe(":8080", requestlog.Middleware(logger)(cors.Middleware(corsConfig)(router)))
 	if err != nil {
-		logger.Fatalf("Error: %v", err)
+		fatal("error serving HTTP", err)
 	}
 
 }
 
+// fatal logs the startup error and exits
+func fatal(message string, err error) {
+	if err != nil {
+		logger.Error(message, "error", err)
+	} else {
+		logger.Error(message)
+	}
+	os.Exit(1)
+}
+
+// newRouter registers every API route, each one named so the middlewares can look up its settings
+func newRouter(middlewares ...mux.MiddlewareFunc) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(middlewares...)
+
+	// API endpoints
+	router.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "PONG")
+	}).Name("ping")
+	router.HandleFunc("/healthz", healthz).Methods("GET").Name("healthz")
+	router.HandleFunc("/readyz", readyz).Methods("GET").Name("readyz")
+	router.HandleFunc("/openapi.json", apidocs.SpecHandler).Methods("GET").Name("openapi")
+	router.HandleFunc("/docs", apidocs.DocsHandler).Methods("GET").Name("docs")
+	router.Handle("/debug/vars", expvar.Handler()).Methods("GET").Name("debug-vars")
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.Use(withAPIVersion(API_V1))
+	registerArticleRoutes(v1)
+	v2 := router.PathPrefix("/v2").Subrouter()
+	v2.Use(withAPIVersion(API_V2))
+	registerArticleRoutes(v2)
+	unversioned := router.NewRoute().Subrouter()
+	unversioned.Use(deprecatedAlias)
+	registerArticleRoutes(unversioned)
+	router.HandleFunc("/graphql", serveGraphQL).Methods("GET", "POST").Name("graphql")
+	router.HandleFunc("/feeds/{format:rss|atom}.xml", getFeed).Methods("GET").Name("feed")
+	router.HandleFunc("/feeds/clubs/{club}/{format:rss|atom}.xml", getFeed).Methods("GET").Name("club-feed")
+	router.HandleFunc("/feeds/taxonomies/{taxonomy}/{format:rss|atom}.xml", getFeed).Methods("GET").Name("taxonomy-feed")
+	router.HandleFunc("/admin/archive-crawl", startArchiveCrawl).Methods("POST").Name("start-archive-crawl")
+	router.HandleFunc("/admin/archive-crawl", getArchiveCrawl).Methods("GET").Name("archive-crawl")
+	router.HandleFunc("/admin/data-quality", getDataQuality).Methods("GET").Name("data-quality")
+	router.HandleFunc("/admin/log-level", getLogLevel).Methods("GET").Name("log-level")
+	router.HandleFunc("/admin/log-level", setLogLevel).Methods("POST").Name("set-log-level")
+	router.HandleFunc("/admin/api-keys", createAPIKey).Methods("POST").Name("create-api-key")
+	router.HandleFunc("/admin/api-keys", getAPIKeys).Methods("GET").Name("api-keys")
+	router.HandleFunc("/admin/api-keys/{keyId}/rotate", rotateAPIKey).Methods("POST").Name("rotate-api-key")
+	router.HandleFunc("/admin/api-keys/{keyId}", revokeAPIKey).Methods("DELETE").Name("revoke-api-key")
+	router.HandleFunc("/webhooks", createWebhook).Methods("POST").Name("create-webhook")
+	router.HandleFunc("/webhooks", getWebhooks).Methods("GET").Name("webhooks")
+	router.HandleFunc("/webhooks/{id}", deleteWebhook).Methods("DELETE").Name("delete-webhook")
+	router.HandleFunc("/webhooks/{id}/deliveries", getWebhookDeliveries).Methods("GET").Name("webhook-deliveries")
+	return router
+}
+
 // GetAllArticles returns all articles from the MongoDB database in JSON format
 func getAllArticles(w http.ResponseWriter, r *http.Request) {
-	responseObj := models.NewsArticlesResponse{
-		Status: string(models.Failure),
-	}
-	articles, err := articleRepository.GetAllArticles()
+	articles, err := articleRepository.GetAllArticles(r.Context())
 	if err != nil {
-		handleError(w, http.StatusBadRequest, "Error retrieving articles", err)
+		handleError(w, r, http.StatusBadRequest, "Error retrieving articles", err)
+		return
 	}
-	responseObj = models.NewsArticlesResponse{
-		Data:   articles,
-		Status: string(models.Success),
+	if httpcache.NotModified(w, r, articleValidators(articles...)) {
+		return
 	}
-
-	handleSuccess(w, http.StatusOK, responseObj)
+	writeArticles(w, articles)
 }
 
 // GetArticleByID returns the article with the specified ID from the MongoDB database
@@ -98,35 +412,287 @@ func getArticleByID(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		handleError(w, http.StatusBadRequest, "Invalid article ID", err)
+		handleError(w, r, http.StatusBadRequest, "Invalid article ID", err)
 		return
 	}
 
-	article, err := articleRepository.GetArticleByID(objectID)
+	article, err := articleRepository.GetArticleByID(r.Context(), objectID)
+	writeArticle(w, r, article, err, id)
+}
+
+// getArticleByNewsArticleID returns the article with the specified upstream NewsArticleID,
+// unlike the ObjectID it stays the same across database rebuilds
+func getArticleByNewsArticleID(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["newsArticleID"]
+	newsArticleID, err := strconv.Atoi(id)
 	if err != nil {
-		handleError(w, http.StatusBadRequest, "Error retrieving article", err)
+		handleError(w, r, http.StatusBadRequest, "Invalid news article ID", err)
+		return
 	}
 
-	responseObj := models.NewsArticleResponse{
-		Status: string(models.Success),
-		Data:   *article,
+	article, err := articleRepository.GetArticleByNewsArticleID(r.Context(), newsArticleID)
+	writeArticle(w, r, article, err, id)
+}
+
+// getArticleBySlug returns the article whose ArticleURL ends with the specified slug
+func getArticleBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := strings.ToLower(mux.Vars(r)["slug"])
+	article, err := articleRepository.GetArticleBySlug(r.Context(), slug)
+	writeArticle(w, r, article, err, slug)
+}
+
+// writeArticle writes the result of a single article lookup
+func writeArticle(w http.ResponseWriter, r *http.Request, article *models.NewsArticleInformationMongoDB, err error, lookup string) {
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Error retrieving article", err)
+		return
+	}
+	if article == nil {
+		handleError(w, r, http.StatusNotFound, "Article not found", fmt.Errorf("article %s not found", lookup))
+		return
 	}
-	handleSuccess(w, http.StatusOK, responseObj)
+	if httpcache.NotModified(w, r, articleValidators(*article)) {
+		return
+	}
+	writeArticleData(w, article)
 }
 
-// generic error handler
-func handleError(w http.ResponseWriter, statusCode int, message string, err error) {
-	logger.Printf("Error: %v", err)
-	responseObj := models.NewsArticlesResponse{
-		Error:  message,
-		Status: string(models.Failure),
+// contentHash falls back to hashing articles synced before content hashes were stored
+func contentHash(article *models.NewsArticleInformationMongoDB) string {
+	if article.ContentHash != "" {
+		return article.ContentHash
 	}
+	return article.ComputeContentHash()
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(responseObj); err != nil {
-		w.Write([]byte(message))
+// articleValidators derives a strong ETag from the articles' content hashes
+// and Last-Modified from the newest LastUpdateDate
+func articleValidators(articles ...models.NewsArticleInformationMongoDB) httpcache.Validators {
+	validators := httpcache.Validators{}
+	parts := []string{}
+	for _, article := range articles {
+		// the slug isn't content but is in the body, it changes when a collision is resolved
+		parts = append(parts, article.ID.Hex(), contentHash(&article), article.Slug)
+		if article.LastUpdateDate.After(validators.LastModified) {
+			validators.LastModified = article.LastUpdateDate
+		}
+	}
+	validators.ETag = httpcache.StrongETag(parts...)
+	return validators
+}
+
+// getArticleRevisions returns every stored revision of an article, oldest first
+func getArticleRevisions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid article ID", err)
+		return
+	}
+
+	revisions, err := articleRepository.GetArticleRevisions(r.Context(), objectID)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Error retrieving revisions", err)
+		return
+	}
+	if revisions == nil {
+		handleError(w, r, http.StatusNotFound, "Article not found", fmt.Errorf("article %s not found", id))
+		return
 	}
+
+	writeRevisions(w, revisions)
+}
+
+// getArticleRevision returns the article as it was stored at the given revision
+func getArticleRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	objectID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid article ID", err)
+		return
+	}
+	rev, err := strconv.Atoi(vars["rev"])
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid revision", err)
+		return
+	}
+
+	revision, err := findRevision(w, r, objectID, rev)
+	if revision == nil || err != nil {
+		return
+	}
+
+	writeRevision(w, revision)
+}
+
+// getArticleDiff returns the field-level changes between two revisions of an article,
+// ?from= and ?to= default to the previous and the latest revision
+func getArticleDiff(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid article ID", err)
+		return
+	}
+
+	revisions, err := articleRepository.GetArticleRevisions(r.Context(), objectID)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Error retrieving revisions", err)
+		return
+	}
+	if len(revisions) == 0 {
+		handleError(w, r, http.StatusNotFound, "Article not found", fmt.Errorf("article %s not found", objectID.Hex()))
+		return
+	}
+
+	to := revisions[len(revisions)-1].Revision
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = strconv.Atoi(value); err != nil {
+			handleError(w, r, http.StatusBadRequest, "Invalid revision", err)
+			return
+		}
+	}
+	from := to - 1
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = strconv.Atoi(value); err != nil {
+			handleError(w, r, http.StatusBadRequest, "Invalid revision", err)
+			return
+		}
+	}
+
+	fromRevision, err := findRevision(w, r, objectID, from)
+	if fromRevision == nil || err != nil {
+		return
+	}
+	toRevision, err := findRevision(w, r, objectID, to)
+	if toRevision == nil || err != nil {
+		return
+	}
+
+	writeDiff(w, models.DiffRevisions(fromRevision, toRevision))
+}
+
+// findRevision looks up a revision and writes the error response if it can't be served
+func findRevision(w http.ResponseWriter, r *http.Request, id primitive.ObjectID, rev int) (*models.NewsArticleRevision, error) {
+	revision, err := articleRepository.GetArticleRevision(r.Context(), id, rev)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Error retrieving revision", err)
+		return nil, err
+	}
+	if revision == nil {
+		handleError(w, r, http.StatusNotFound, "Revision not found", fmt.Errorf("revision %d of article %s not found", rev, id.Hex()))
+	}
+	return revision, nil
+}
+
+// streamArticles pushes article events as Server-Sent Events, clients resume with the
+// Last-Event-ID header and can narrow the stream down with ?club= and ?taxonomy=. When
+// the events after Last-Event-ID can't be replayed, a resync event comes first
+func streamArticles(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleError(w, r, http.StatusInternalServerError, "Streaming not supported", fmt.Errorf("response writer can't flush"))
+		return
+	}
+
+	var lastEventID uint64
+	resync := ""
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		id, sameEpoch, err := parseEventID(value)
+		if err != nil {
+			handleError(w, r, http.StatusBadRequest, "Invalid Last-Event-ID", err)
+			return
+		}
+		lastEventID = id
+		if !sameEpoch {
+			// another replica or a previous run numbered the event, its IDs mean nothing here
+			lastEventID, resync = articleEvents.LastID(), SSE_RESYNC_RESTARTED
+		}
+	}
+	filter := models.ArticleFilter{
+		Club:     r.URL.Query().Get("club"),
+		Taxonomy: r.URL.Query().Get("taxonomy"),
+	}
+
+	replay, subscription, cancel := articleEvents.Subscribe(lastEventID)
+	defer cancel()
+	if resync == "" && lastEventID > 0 && len(replay) > 0 && replay[0].ID > lastEventID+1 {
+		// the log has dropped events the client hasn't seen, carry on after the newest one
+		lastEventID, resync, replay = replay[len(replay)-1].ID, SSE_RESYNC_MISSED, nil
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if resync != "" {
+		fmt.Fprintf(w, "id: %s\nevent: resync\ndata: {\"reason\":%q}\n\n", formatEventID(lastEventID), resync)
+	}
+	for _, event := range replay {
+		writeEvent(w, r, filter, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(SSE_HEARTBEAT_PERIOD)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-subscription:
+			if !ok {
+				// dropped for falling behind, the client reconnects with Last-Event-ID
+				return
+			}
+			writeEvent(w, r, filter, event)
+		}
+		flusher.Flush()
+	}
+}
+
+// formatEventID prefixes the broker's counter with its epoch, the counter restarts
+// with every process so the number alone doesn't say which events came before
+func formatEventID(id uint64) string {
+	return articleEvents.Epoch() + "-" + strconv.FormatUint(id, 10)
+}
+
+// parseEventID reads an ID written by formatEventID and reports whether it is of this
+// broker's epoch. A bare number, as sent before IDs had an epoch, is of no epoch
+func parseEventID(value string) (uint64, bool, error) {
+	epoch, counter, hasEpoch := strings.Cut(value, "-")
+	if !hasEpoch {
+		counter = value
+	}
+	id, err := strconv.ParseUint(counter, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return id, hasEpoch && epoch == articleEvents.Epoch(), nil
+}
+
+func writeEvent(w http.ResponseWriter, r *http.Request, filter models.ArticleFilter, event models.ArticleEvent) {
+	if !filter.Matches(&event.Article) {
+		return
+	}
+	data, err := encodeEvent(w, &event)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "error encoding event", "event_id", event.ID, "error", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", formatEventID(event.ID), event.Type, data)
+}
+
+// serveGraphQL answers queries and streams subscriptions against the article repository
+func serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	graphqlHandler.ServeHTTP(w, r)
+}
+
+// generic error handler
+func handleError(w http.ResponseWriter, r *http.Request, statusCode int, message string, err error) {
+	logger.ErrorContext(r.Context(), message, "status", statusCode, "error", err)
+	writeErrorBody(w, statusCode, message)
 }
 
 // generic success handler
@@ -134,7 +700,7 @@ func handleSuccess(w http.ResponseWriter, statusCode int, responseObj interface{
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(responseObj); err != nil {
-		handleError(w, http.StatusInternalServerError, "Error encoding response", err)
-		return
+		logger.Error("Error encoding response", "error", err)
+		writeErrorBody(w, http.StatusInternalServerError, "Error encoding response")
 	}
 }
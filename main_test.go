@@ -1,16 +1,35 @@
 package main
 
 import (
+	"alibazlamit/feed-provider/apidocs"
+	"alibazlamit/feed-provider/apiv2"
 	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
+	"alibazlamit/feed-provider/grpcapi/articlespb"
+	"alibazlamit/feed-provider/health"
+	"alibazlamit/feed-provider/logging"
 	"alibazlamit/feed-provider/models"
+	"alibazlamit/feed-provider/requestlog"
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestGetAllArticles(t *testing.T) {
@@ -109,3 +128,700 @@ func TestGetArticleByID(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v", responseObj, expected)
 	}
 }
+
+func TestGetArticleRevisionsAndDiff(t *testing.T) {
+	mockRepo := database.NewMockArticleRepository()
+	articleRepository = mockRepo
+
+	articleXml := &models.NewsArticleInformationXML{
+		ClubName: "TEST CITY",
+		NewsArticle: models.NewsArticle{
+			NewsArticleID: 1,
+			Title:         "Original title",
+			BodyText:      "Original body",
+		},
+	}
+	mockRepo.AddOrUpdateArticle(context.Background(), 1, articleXml)
+	// syncing unchanged content must not create a revision
+	mockRepo.AddOrUpdateArticle(context.Background(), 1, articleXml)
+	articleXml.NewsArticle.Title = "Corrected title"
+	mockRepo.AddOrUpdateArticle(context.Background(), 1, articleXml)
+
+	id := mockRepo.Articles[0].ID
+	router := mux.NewRouter()
+	router.HandleFunc("/articles/{id}/revisions", getArticleRevisions).Methods("GET")
+	router.HandleFunc("/articles/{id}/revisions/{rev}", getArticleRevision).Methods("GET")
+	router.HandleFunc("/articles/{id}/diff", getArticleDiff).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/articles/%s/revisions", id.Hex()), nil)
+	router.ServeHTTP(rr, req)
+	var revisionsResponse models.NewsArticleRevisionsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &revisionsResponse); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisionsResponse.Data) != 2 {
+		t.Fatalf("Expected 2 revisions, but got %d", len(revisionsResponse.Data))
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/articles/%s/revisions/1", id.Hex()), nil)
+	router.ServeHTTP(rr, req)
+	var revisionResponse models.NewsArticleRevisionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &revisionResponse); err != nil {
+		t.Fatal(err)
+	}
+	if revisionResponse.Data.Article.Title != "Original title" {
+		t.Errorf("Expected revision 1 title %s, but got %s", "Original title", revisionResponse.Data.Article.Title)
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/articles/%s/diff?from=1&to=2", id.Hex()), nil)
+	router.ServeHTTP(rr, req)
+	var diffResponse models.NewsArticleDiffResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &diffResponse); err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.FieldDiff{{Field: "Title", From: "Original title", To: "Corrected title"}}
+	if !reflect.DeepEqual(diffResponse.Data.Changes, expected) {
+		t.Errorf("Expected changes %v, but got %v", expected, diffResponse.Data.Changes)
+	}
+}
+
+func TestGetArticleBySourceAndSlug(t *testing.T) {
+	mockRepo := database.NewMockArticleRepository()
+	articleRepository = mockRepo
+	mockRepo.AddOrUpdateArticle(context.Background(), 42, &models.NewsArticleInformationXML{
+		NewsArticle: models.NewsArticle{
+			NewsArticleID: 42,
+			ArticleURL:    "https://www.htafc.com/news/2023/july/26/Match-Report",
+			Title:         "Match report",
+		},
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/articles/by-source/{newsArticleID}", getArticleByNewsArticleID).Methods("GET")
+	router.HandleFunc("/articles/by-slug/{slug}", getArticleBySlug).Methods("GET")
+
+	for _, path := range []string{"/articles/by-source/42", "/articles/by-slug/news-2023-july-26-match-report"} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected status code %d, but got %d", path, http.StatusOK, rr.Code)
+		}
+		var responseObj models.NewsArticleResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &responseObj); err != nil {
+			t.Fatal(err)
+		}
+		if responseObj.Data.NewsArticleID != 42 || responseObj.Data.Slug != "news-2023-july-26-match-report" {
+			t.Errorf("%s: unexpected article %v", path, responseObj.Data)
+		}
+	}
+}
+
+// readStream opens the article stream with the Last-Event-ID and returns its first lines
+func readStream(t *testing.T, url, lastEventID string, count int) []string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(streamArticles))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+url, nil)
+	req.Header.Set("Last-Event-ID", lastEventID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected content type text/event-stream, but got %s", contentType)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	lines := []string{}
+	for len(lines) < count && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func TestStreamArticlesResumesFromLastEventID(t *testing.T) {
+	articleEvents = events.NewBroker(10)
+	articleEvents.Publish(models.ArticleInserted, &models.NewsArticleInformationMongoDB{ClubName: "TEST CITY", Title: "Old"})
+	articleEvents.Publish(models.ArticleInserted, &models.NewsArticleInformationMongoDB{ClubName: "OTHER TOWN", Title: "Other"})
+	articleEvents.Publish(models.ArticleUpdated, &models.NewsArticleInformationMongoDB{ClubName: "TEST CITY", Title: "New"})
+
+	lines := readStream(t, "?club=test%20city", formatEventID(1), 3)
+	if lines[0] != "id: "+formatEventID(3) || lines[1] != "event: updated" || !strings.Contains(lines[2], `"title":"New"`) {
+		t.Errorf("Unexpected event %v", lines)
+	}
+}
+
+func TestStreamArticlesAsksForResync(t *testing.T) {
+	articleEvents = events.NewBroker(2)
+	for _, title := range []string{"First", "Second", "Third", "Fourth"} {
+		articleEvents.Publish(models.ArticleInserted, &models.NewsArticleInformationMongoDB{Title: title})
+	}
+	expected := []string{"id: " + formatEventID(4), "event: resync"}
+
+	// the ID of a previous run, and one from before IDs had an epoch, can't be resumed
+	for _, lastEventID := range []string{"0123456789abcdef-2", "2"} {
+		lines := readStream(t, "", lastEventID, 3)
+		if lines[0] != expected[0] || lines[1] != expected[1] || lines[2] != `data: {"reason":"restarted"}` {
+			t.Errorf("%s: unexpected event %v", lastEventID, lines)
+		}
+	}
+
+	// the log only holds the third and fourth events, the second is lost
+	lines := readStream(t, "", formatEventID(1), 3)
+	if lines[0] != expected[0] || lines[1] != expected[1] || lines[2] != `data: {"reason":"missed"}` {
+		t.Errorf("Unexpected event %v", lines)
+	}
+
+	// the second is the last event not in the log, the replay is complete
+	lines = readStream(t, "", formatEventID(2), 2)
+	if lines[0] != "id: "+formatEventID(3) || lines[1] != "event: inserted" {
+		t.Errorf("Unexpected event %v", lines)
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	logger = slog.New(slog.DiscardHandler)
+	mockRepo := database.NewMockWebhookRepository()
+	webhookRepository = mockRepo
+
+	router := mux.NewRouter()
+	router.HandleFunc("/webhooks", createWebhook).Methods("POST")
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(`{"url":"ftp://example.com","secret":"0123456789abcdef"}`))
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["unpublished"],"secret":"0123456789abcdef"}`))
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, rr.Code)
+	}
+	if strings.Contains(rr.Body.String(), "0123456789abcdef") {
+		t.Errorf("Expected the secret not to be returned, got %s", rr.Body.String())
+	}
+	if len(mockRepo.Subscriptions) != 1 || mockRepo.Subscriptions[0].Secret != "0123456789abcdef" {
+		t.Errorf("Expected the subscription to be stored with its secret, got %v", mockRepo.Subscriptions)
+	}
+}
+
+func TestGetFeedConditionalGet(t *testing.T) {
+	mockRepo := database.NewMockArticleRepository()
+	articleRepository = mockRepo
+	mockRepo.AddOrUpdateArticle(context.Background(), 1, &models.NewsArticleInformationXML{
+		ClubName: "TEST CITY",
+		NewsArticle: models.NewsArticle{
+			NewsArticleID:  1,
+			Title:          "Published",
+			IsPublished:    true,
+			LastUpdateDate: models.CustomTime{Time: time.Date(2023, 7, 27, 2, 0, 28, 0, time.UTC)},
+		},
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/feeds/clubs/{club}/{format:rss|atom}.xml", getFeed).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/feeds/clubs/TEST CITY/rss.xml", nil)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "<title>Published</title>") {
+		t.Errorf("Expected the article in the feed, got %s", rr.Body.String())
+	}
+	etag := rr.Header().Get("ETag")
+	if rr.Header().Get("Last-Modified") != "Thu, 27 Jul 2023 02:00:28 GMT" {
+		t.Errorf("Unexpected Last-Modified %s", rr.Header().Get("Last-Modified"))
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/feeds/clubs/TEST CITY/rss.xml", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotModified, rr.Code)
+	}
+
+	// the self link differs on another host
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "http://mirror.example/feeds/clubs/TEST CITY/rss.xml", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status code %d on another host, but got %d", http.StatusOK, rr.Code)
+	}
+
+	// articles stored without a content hash still change the tag when edited
+	mockRepo.Articles[0].ContentHash = ""
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/feeds/clubs/TEST CITY/rss.xml", nil)
+	router.ServeHTTP(rr, req)
+	unhashed := rr.Header().Get("ETag")
+	mockRepo.Articles[0].Title = "Edited"
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/feeds/clubs/TEST CITY/rss.xml", nil)
+	req.Header.Set("If-None-Match", unhashed)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "<title>Edited</title>") {
+		t.Errorf("Expected the edited article, but got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/feeds/clubs/TEST CITY/rss.xml", nil)
+	req.Header.Set("If-Modified-Since", "Thu, 27 Jul 2023 02:00:28 GMT")
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotModified, rr.Code)
+	}
+}
+
+func TestGetAllArticlesConditionalGet(t *testing.T) {
+	mockRepo := database.NewMockArticleRepository()
+	articleRepository = mockRepo
+	mockRepo.Articles = []models.NewsArticleInformationMongoDB{
+		{ID: primitive.NewObjectID(), Title: "Article 1", LastUpdateDate: time.Date(2023, 7, 26, 9, 45, 0, 0, time.UTC)},
+		{ID: primitive.NewObjectID(), Title: "Article 2", LastUpdateDate: time.Date(2023, 7, 27, 2, 0, 28, 0, time.UTC)},
+	}
+
+	rr := httptest.NewRecorder()
+	getAllArticles(rr, httptest.NewRequest("GET", "/articles", nil))
+	etag := rr.Header().Get("ETag")
+	if etag == "" || rr.Header().Get("Last-Modified") != "Thu, 27 Jul 2023 02:00:28 GMT" {
+		t.Fatalf("Unexpected validators ETag=%s Last-Modified=%s", etag, rr.Header().Get("Last-Modified"))
+	}
+
+	req := httptest.NewRequest("GET", "/articles", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	getAllArticles(rr, req)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty %d, but got %d", http.StatusNotModified, rr.Code)
+	}
+
+	// an edit changes the content hash and with it the ETag
+	mockRepo.Articles[0].Title = "Article 1 corrected"
+	rr = httptest.NewRecorder()
+	getAllArticles(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, rr.Code)
+	}
+
+	// so does a new slug, which isn't content
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	mockRepo.Articles[0].Slug = "news-article-1-1"
+	rr = httptest.NewRecorder()
+	getAllArticles(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status code %d after a slug change, but got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestGetDataQuality(t *testing.T) {
+	logger = slog.New(slog.DiscardHandler)
+	mockRepo := database.NewMockQualityRepository()
+	qualityRepository = mockRepo
+	now := time.Now().UTC()
+	mockRepo.AddViolations(context.Background(), []models.ViolationRecord{
+		{Feed: "htafc", NewsArticleID: 1, Rule: "required", Action: models.PolicyReject, ContentHash: "h1", OccurredAt: now},
+		{Feed: "htafc", NewsArticleID: 2, Rule: "url", Action: models.PolicyReject, ContentHash: "h2", OccurredAt: now},
+		{Feed: "htafc", NewsArticleID: 2, Rule: "url", Action: models.PolicyQuarantine, ContentHash: "h3", OccurredAt: now.AddDate(0, 0, -1)},
+		{Feed: "htafc", NewsArticleID: 3, Rule: "url", Action: models.PolicyReject, ContentHash: "h4", OccurredAt: now.AddDate(0, 0, -40)},
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/data-quality", getDataQuality).Methods("GET")
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/data-quality", nil)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, rr.Code)
+	}
+	var response models.DataQualityResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if len(response.Data.Feeds) != 1 {
+		t.Fatalf("Expected one feed, got %v", response.Data.Feeds)
+	}
+	feed := response.Data.Feeds[0]
+	if feed.Total != 3 || feed.ByRule["url"] != 2 || feed.ByAction[models.PolicyReject] != 2 || len(feed.Days) != 2 {
+		t.Errorf("Unexpected report %+v", feed)
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/data-quality?days=0", nil)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	logger = slog.New(slog.DiscardHandler)
+	mockRepo := database.NewMockAPIKeyRepository()
+	apiKeyRepository = mockRepo
+
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/api-keys", createAPIKey).Methods("POST")
+	router.HandleFunc("/admin/api-keys", getAPIKeys).Methods("GET")
+	router.HandleFunc("/admin/api-keys/{keyId}/rotate", rotateAPIKey).Methods("POST")
+	router.HandleFunc("/admin/api-keys/{keyId}", revokeAPIKey).Methods("DELETE")
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"name":"partner","scopes":["articles:read","everything"]}`))
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"name":"partner","scopes":["articles:read"]}`))
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, rr.Code)
+	}
+	var created models.APIKeyResponse
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Key, "fp_"+created.Data.KeyID+"_") {
+		t.Errorf("Expected a key for %s, got %q", created.Data.KeyID, created.Key)
+	}
+	if strings.Contains(rr.Body.String(), mockRepo.Keys[0].Hash) {
+		t.Errorf("Expected the hash not to be returned, got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/api-keys/"+created.Data.KeyID+"/rotate?grace=1m", nil)
+	router.ServeHTTP(rr, req)
+	var rotated models.APIKeyResponse
+	json.Unmarshal(rr.Body.Bytes(), &rotated)
+	if rr.Code != http.StatusOK || rotated.Key == created.Key || rotated.Data.PreviousExpiresAt == nil {
+		t.Errorf("Expected a rotated key, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/admin/api-keys/"+created.Data.KeyID, nil)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !mockRepo.Keys[0].Revoked() {
+		t.Errorf("Expected the key to be revoked, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/api-keys/"+created.Data.KeyID+"/rotate", nil)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, but got %d", http.StatusConflict, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/admin/api-keys/unknown", nil)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestEveryRouteHasAScope(t *testing.T) {
+	err := newRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			// a subrouter, its routes are walked on their own
+			return nil
+		}
+		template, _ := route.GetPathTemplate()
+		if _, ok := routeScopes[route.GetName()]; !ok {
+			t.Errorf("Route %s (%q) has no scope in routeScopes", template, route.GetName())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRoutesMatchOpenAPISpec fails when a route is added, removed or changes method
+// without apidocs/openapi.json following
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+	documented, err := apidocs.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the spec has no regexps in its path parameters
+	pattern := regexp.MustCompile(`\{(\w+):[^}]*\}`)
+	registered := map[string][]string{}
+	err = newRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		path := pattern.ReplaceAllString(template, "{$1}")
+		methods, err := route.GetMethods()
+		if err != nil {
+			// routes taking any method are documented as GET
+			methods = []string{"GET"}
+		}
+		registered[path] = append(registered[path], methods...)
+		sort.Strings(registered[path])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, methods := range registered {
+		if !reflect.DeepEqual(methods, documented[path]) {
+			t.Errorf("Route %s serves %v but the spec documents %v", path, methods, documented[path])
+		}
+	}
+	for path, methods := range documented {
+		if _, ok := registered[path]; !ok {
+			t.Errorf("Spec documents %s %v which no route serves", path, methods)
+		}
+	}
+}
+
+func TestServeOpenAPISpec(t *testing.T) {
+	router := newRouter()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, rr.Code)
+	}
+	var spec struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Error decoding the spec: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, but got %q", spec.OpenAPI)
+	}
+	for _, schema := range []string{"NewsArticlesResponse", "NewsArticleResponse"} {
+		if _, ok := spec.Components.Schemas[schema]; !ok {
+			t.Errorf("Expected the %s schema", schema)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/docs", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "openapi.json") {
+		t.Errorf("Expected the docs page, but got %d", rr.Code)
+	}
+}
+
+func TestVersionedArticleRoutes(t *testing.T) {
+	logger = slog.New(slog.DiscardHandler)
+	mockRepo := database.NewMockArticleRepository()
+	articleRepository = mockRepo
+	id := primitive.NewObjectID()
+	mockRepo.Articles = []models.NewsArticleInformationMongoDB{{
+		ID:                id,
+		ClubName:          "TEST CITY",
+		ClubWebsiteURL:    "https://www.example.com",
+		NewsArticleID:     611120,
+		Title:             "Test Article",
+		Taxonomies:        "First Team, Academy",
+		ThumbnailImageURL: "https://www.example.com/thumb.jpg",
+		GalleryImageURLs:  "https://www.example.com/1.jpg,https://www.example.com/2.jpg",
+	}}
+	router := newRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/v1/articles/" + id.Hex())
+	var v1 map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &v1)
+	data, _ := v1["data"].(map[string]interface{})
+	if rr.Code != http.StatusOK || v1["status"] != "success" || data["newsArticleId"] != float64(611120) || data["clubName"] != "TEST CITY" {
+		t.Errorf("Unexpected v1 response %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get(API_VERSION_HEADER) != API_V1 || rr.Header().Get("Deprecation") != "" {
+		t.Errorf("Unexpected v1 headers %v", rr.Header())
+	}
+
+	rr = get("/v2/articles")
+	var v2 apiv2.Envelope[[]apiv2.Article]
+	if err := json.Unmarshal(rr.Body.Bytes(), &v2); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusOK || v2.Meta.APIVersion != API_V2 || v2.Meta.Count == nil || *v2.Meta.Count != 1 {
+		t.Fatalf("Unexpected v2 response %d: %s", rr.Code, rr.Body.String())
+	}
+	article := (*v2.Data)[0]
+	if article.UpstreamID != 611120 || article.Club.Name != "TEST CITY" || !reflect.DeepEqual(article.Tags, []string{"First Team", "Academy"}) ||
+		len(article.Media.Gallery) != 2 || article.Media.Thumbnail != "https://www.example.com/thumb.jpg" {
+		t.Errorf("Unexpected v2 article %+v", article)
+	}
+
+	rr = get("/v2/articles/" + primitive.NewObjectID().Hex())
+	var v2Error apiv2.Envelope[struct{}]
+	json.Unmarshal(rr.Body.Bytes(), &v2Error)
+	if rr.Code != http.StatusNotFound || v2Error.Error == nil || v2Error.Error.Status != http.StatusNotFound || v2Error.Meta.APIVersion != API_V2 {
+		t.Errorf("Unexpected v2 error %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// the unversioned alias answers like v1 and announces its deprecation
+	rr = get("/articles/" + id.Hex())
+	aliasBody := rr.Body.String()
+	if rr.Code != http.StatusOK || aliasBody != get("/v1/articles/"+id.Hex()).Body.String() {
+		t.Errorf("Expected the alias to answer like v1, but got %d: %s", rr.Code, aliasBody)
+	}
+	if rr.Header().Get("Deprecation") != "@1792368000" {
+		t.Errorf("Unexpected Deprecation header %q", rr.Header().Get("Deprecation"))
+	}
+	if links := rr.Header().Values("Link"); len(links) != 2 || links[0] != "</v1/articles/"+id.Hex()+`>; rel="successor-version"` {
+		t.Errorf("Unexpected Link headers %v", links)
+	}
+}
+
+func TestHealthChecks(t *testing.T) {
+	mockRepo := database.NewMockArticleRepository()
+	articleRepository = mockRepo
+	lastSync := models.SyncStatus{LastRun: time.Now(), LastSuccess: time.Now()}
+	syncStatus = func() models.SyncStatus { return lastSync }
+	router := newRouter()
+	get := func(path string) (int, health.Report) {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var report health.Report
+		if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		return rr.Code, report
+	}
+
+	status, report := get("/readyz")
+	if status != http.StatusOK || report.Components["repository"].Status != health.StatusUp || report.Components["feed"].Status != health.StatusUp {
+		t.Errorf("Expected ready, but got %d: %+v", status, report)
+	}
+
+	// stale data or an unreachable repository take the replica out, liveness stays up
+	lastSync.LastSuccess = time.Now().Add(-maxSyncAge - time.Minute)
+	mockRepo.PingErr = fmt.Errorf("server selection timeout")
+	status, report = get("/readyz")
+	if status != http.StatusServiceUnavailable || report.Status != health.StatusDown {
+		t.Errorf("Expected not ready, but got %d: %+v", status, report)
+	}
+	if report.Components["repository"].Message != "server selection timeout" || report.Components["feed"].Status != health.StatusDown {
+		t.Errorf("Unexpected components %+v", report.Components)
+	}
+	status, report = get("/healthz")
+	if status != http.StatusOK || report.Components["process"].Status != health.StatusUp {
+		t.Errorf("Expected live, but got %d: %+v", status, report)
+	}
+}
+
+func TestGRPCHealthFollowsReadiness(t *testing.T) {
+	mockRepo := database.NewMockArticleRepository()
+	articleRepository = mockRepo
+	syncStatus = func() models.SyncStatus { return models.SyncStatus{LastRun: time.Now(), LastSuccess: time.Now()} }
+	healthServer := grpchealth.NewServer()
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		response, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.Status
+	}
+
+	updateGRPCHealth(context.Background(), healthServer)
+	if status := check(articlespb.ArticleService_ServiceDesc.ServiceName); status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected SERVING, but got %v", status)
+	}
+
+	mockRepo.PingErr = fmt.Errorf("server selection timeout")
+	updateGRPCHealth(context.Background(), healthServer)
+	for _, service := range []string{"", articlespb.ArticleService_ServiceDesc.ServiceName} {
+		if status := check(service); status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("Expected %q to be NOT_SERVING, but got %v", service, status)
+		}
+	}
+}
+
+func TestRequestIDTagsLogs(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logger, logLevel = logging.New(logs, logging.Config{Level: slog.LevelInfo, Format: logging.FORMAT_JSON})
+	articleRepository = database.NewMockArticleRepository()
+	router := requestlog.Middleware(logger)(newRouter())
+
+	req, _ := http.NewRequest("GET", "/v1/articles/not-an-id", nil)
+	req.Header.Set(logging.REQUEST_ID_HEADER, "client-42")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Header().Get(logging.REQUEST_ID_HEADER) != "client-42" {
+		t.Errorf("Expected the client's request ID back, but got %q", rr.Header().Get(logging.REQUEST_ID_HEADER))
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected the error and the access line, but got %v", lines)
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record[logging.REQUEST_ID_KEY] != "client-42" {
+			t.Errorf("Expected request_id client-42 in %s", line)
+		}
+	}
+
+	// an ID unsafe to log is replaced
+	req, _ = http.NewRequest("GET", "/ping", nil)
+	req.Header.Set(logging.REQUEST_ID_HEADER, "two words")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if id := rr.Header().Get(logging.REQUEST_ID_HEADER); id == "" || id == "two words" {
+		t.Errorf("Expected a generated request ID, but got %q", id)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	logger, logLevel = logging.New(io.Discard, logging.Config{Level: slog.LevelInfo, Format: logging.FORMAT_JSON})
+	router := newRouter()
+	call := func(method, body string) (int, models.LogLevelResponse) {
+		req, _ := http.NewRequest(method, "/admin/log-level", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response models.LogLevelResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+
+	if status, response := call("GET", ""); status != http.StatusOK || response.Data.Level != "info" {
+		t.Errorf("Expected info, but got %d: %+v", status, response)
+	}
+	if status, response := call("POST", `{"level":"debug"}`); status != http.StatusOK || response.Data.Level != "debug" {
+		t.Errorf("Expected debug, but got %d: %+v", status, response)
+	}
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("Expected the logger to log debug records")
+	}
+	if status, _ := call("POST", `{"level":"verbose"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, status)
+	}
+	if logLevel.Level() != slog.LevelDebug {
+		t.Errorf("Expected an invalid level to leave debug in place, got %v", logLevel.Level())
+	}
+}
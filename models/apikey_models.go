@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scope grants an API key access to a group of routes
+type Scope string
+
+const (
+	ScopeArticlesRead   Scope = "articles:read"
+	ScopeAdminSync      Scope = "admin:sync"
+	ScopeWebhooksManage Scope = "webhooks:manage"
+	ScopeAdminKeys      Scope = "admin:keys"
+)
+
+// Scopes lists every scope a key can be given
+var Scopes = []Scope{ScopeArticlesRead, ScopeAdminSync, ScopeWebhooksManage, ScopeAdminKeys}
+
+// APIKey is stored without its secret, only the secret's hash is kept. KeyID is the
+// public part of the key and identifies the caller in the request logs.
+type APIKey struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	KeyID     string             `bson:"keyId" json:"keyId"`
+	Name      string             `bson:"name" json:"name"`
+	Hash      string             `bson:"hash" json:"-"`
+	Scopes    []Scope            `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	// the secret replaced by the last rotation keeps working until PreviousExpiresAt
+	PreviousHash      string     `bson:"previousHash,omitempty" json:"-"`
+	PreviousExpiresAt *time.Time `bson:"previousExpiresAt,omitempty" json:"previousExpiresAt,omitempty"`
+	RotatedAt         *time.Time `bson:"rotatedAt,omitempty" json:"rotatedAt,omitempty"`
+	RevokedAt         *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+type APIKeyRequest struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+// APIKeyResponse carries the plain key right after it is created or rotated, it can't be read back later
+type APIKeyResponse struct {
+	Status string `json:"status"`
+	Data   APIKey `json:"data"`
+	Key    string `json:"key,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type APIKeysResponse struct {
+	Status string   `json:"status"`
+	Data   []APIKey `json:"data"`
+	Error  string   `json:"error,omitempty"`
+}
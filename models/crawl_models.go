@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// CrawlCheckpoint records how far an archive crawl got so an interrupted crawl can resume
+type CrawlCheckpoint struct {
+	Name              string    `bson:"_id" json:"name"`
+	Offset            int       `bson:"offset" json:"offset"`
+	PagesCrawled      int       `bson:"pagesCrawled" json:"pagesCrawled"`
+	ArticlesSynced    int       `bson:"articlesSynced" json:"articlesSynced"`
+	FailedArticleIDs  []int     `bson:"failedArticleIds" json:"failedArticleIds,omitempty"`
+	OldestPublishDate time.Time `bson:"oldestPublishDate" json:"oldestPublishDate"`
+	Cutoff            time.Time `bson:"cutoff" json:"cutoff"`
+	Running           bool      `bson:"-" json:"running"`
+	Completed         bool      `bson:"completed" json:"completed"`
+	StopReason        string    `bson:"stopReason" json:"stopReason,omitempty"`
+	LastError         string    `bson:"lastError" json:"lastError,omitempty"`
+	UpdatedAt         time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+type CrawlCheckpointResponse struct {
+	Status string          `json:"status"`
+	Data   CrawlCheckpoint `json:"data"`
+	Error  string          `json:"error,omitempty"`
+}
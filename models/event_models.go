@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// SyncOutcome describes what happened to an article when the reader synced it
+type SyncOutcome string
+
+const (
+	ArticleInserted    SyncOutcome = "inserted"
+	ArticleUpdated     SyncOutcome = "updated"
+	ArticleUnpublished SyncOutcome = "unpublished"
+	ArticleUnchanged   SyncOutcome = "unchanged"
+)
+
+type ArticleEvent struct {
+	ID         uint64                        `json:"id"`
+	Type       SyncOutcome                   `json:"type"`
+	OccurredAt time.Time                     `json:"occurredAt"`
+	Article    NewsArticleInformationMongoDB `json:"article"`
+}
+
+// ArticleFilter narrows articles down by club and taxonomy, empty fields match everything
+type ArticleFilter struct {
+	Club     string
+	Taxonomy string
+}
+
+func (f ArticleFilter) Matches(article *NewsArticleInformationMongoDB) bool {
+	if f.Club != "" && !strings.EqualFold(f.Club, article.ClubName) {
+		return false
+	}
+	if f.Taxonomy != "" && !article.HasTaxonomy(f.Taxonomy) {
+		return false
+	}
+	return true
+}
+
+// TaxonomyList splits the comma separated Taxonomies field
+func (a *NewsArticleInformationMongoDB) TaxonomyList() []string {
+	taxonomies := []string{}
+	for _, taxonomy := range strings.Split(a.Taxonomies, ",") {
+		taxonomy = strings.TrimSpace(taxonomy)
+		if taxonomy != "" {
+			taxonomies = append(taxonomies, taxonomy)
+		}
+	}
+	return taxonomies
+}
+
+func (a *NewsArticleInformationMongoDB) HasTaxonomy(taxonomy string) bool {
+	for _, t := range a.TaxonomyList() {
+		if strings.EqualFold(t, taxonomy) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,9 @@ package models
 import (
 	"encoding/xml"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -30,6 +33,8 @@ type NewsArticleInformationXML struct {
 	ClubName       string      `xml:"ClubName"`
 	ClubWebsiteURL string      `xml:"ClubWebsiteURL"`
 	NewsArticle    NewsArticle `xml:"NewsArticle"`
+	// validation warnings of an article stored despite breaking rules
+	Warnings []string `xml:"-"`
 }
 
 type NewsArticle struct {
@@ -54,7 +59,8 @@ type NewsArticleInformationMongoDB struct {
 	ClubName          string             `bson:"clubName" json:"clubName"`
 	ClubWebsiteURL    string             `bson:"clubWebsiteURL" json:"-"`
 	ArticleURL        string             `bson:"url" json:"url"`
-	NewsArticleID     int                `bson:"NewsArticleID" json:"-"`
+	NewsArticleID     int                `bson:"NewsArticleID" json:"newsArticleId"`
+	Slug              string             `bson:"slug" json:"slug"`
 	PublishDate       time.Time          `bson:"publishDate" json:"published"`
 	Taxonomies        string             `bson:"taxonomies" json:"-"`
 	TeaserText        string             `bson:"teaser" json:"teaser"`
@@ -67,6 +73,9 @@ type NewsArticleInformationMongoDB struct {
 	OptaMatchID       string             `bson:"optaMatchId" json:"optaMatchId"`
 	LastUpdateDate    time.Time          `bson:"lastUpdateDate" json:"-"`
 	IsPublished       bool               `bson:"published" json:"-"`
+	ContentHash       string             `bson:"contentHash" json:"-"`
+	Warnings          []string           `bson:"warnings,omitempty" json:"warnings,omitempty"`
+	DatesUTC          bool               `bson:"datesUTC" json:"-"`
 	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 }
 
@@ -90,28 +99,73 @@ type NewsArticlesResponse struct {
 	Error string `json:"error,omitempty"`
 }
 
+// CustomTime keeps the raw upstream value next to the parsed time so the reader
+// can re-parse it with the feed's own layouts and timezone
 type CustomTime struct {
 	time.Time
+	Raw string
 }
 
+// UnmarshalXML parses with DefaultTimeFormat, values it can't parse are left zero
+// with their Raw text for the reader's feed specific TimeFormat to report
 func (ct *CustomTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	const layout = "2006-01-02 15:04:05"
 	var v string
-	d.DecodeElement(&v, &start)
-	t, err := time.Parse(layout, v)
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return fmt.Errorf("error decoding custom time: %v", err)
+	}
+	*ct = CustomTime{Raw: strings.TrimSpace(v)}
+	if t, err := DefaultTimeFormat.Parse(ct.Raw); err == nil {
+		ct.Time = t
+	}
+	return nil
+}
+
+// ApplyTimeFormat re-parses the raw value with the given format, empty values stay zero
+func (ct *CustomTime) ApplyTimeFormat(format TimeFormat) error {
+	t, err := format.Parse(ct.Raw)
 	if err != nil {
-		return fmt.Errorf("error parsing custom time: %v", err)
+		return err
+	}
+	ct.Time = t
+	return nil
+}
+
+// ApplyTimeFormat re-parses the article's dates with the feed's TimeFormat
+func (a *NewsArticle) ApplyTimeFormat(format TimeFormat) error {
+	if err := a.PublishDate.ApplyTimeFormat(format); err != nil {
+		return fmt.Errorf("invalid PublishDate: %v", err)
+	}
+	if err := a.LastUpdateDate.ApplyTimeFormat(format); err != nil {
+		return fmt.Errorf("invalid LastUpdateDate: %v", err)
 	}
-	*ct = CustomTime{t}
 	return nil
 }
 
+// SlugFromURL joins the path segments of an article URL, the last segment alone is
+// reused across months, e.g. https://www.htafc.com/news/2023/july/26/match-report ->
+// news-2023-july-26-match-report
+func SlugFromURL(articleURL string) string {
+	parsed, err := url.Parse(articleURL)
+	if err != nil {
+		return ""
+	}
+	segments := strings.FieldsFunc(parsed.Path, func(r rune) bool { return r == '/' })
+	return strings.ToLower(strings.Join(segments, "-"))
+}
+
+// SlugWithID suffixes the NewsArticleID to a slug another article already has, joining
+// the segments makes e.g. /news/a-b and /news-a/b collide
+func SlugWithID(slug string, newsArticleID int) string {
+	return slug + "-" + strconv.Itoa(newsArticleID)
+}
+
 func ConvertToMongoDB(newsArticleInfo *NewsArticleInformationXML) *NewsArticleInformationMongoDB {
 	newsArticleInfoMongoDB := NewsArticleInformationMongoDB{
 		ClubName:          newsArticleInfo.ClubName,
 		ClubWebsiteURL:    newsArticleInfo.ClubWebsiteURL,
 		ArticleURL:        newsArticleInfo.NewsArticle.ArticleURL,
 		NewsArticleID:     newsArticleInfo.NewsArticle.NewsArticleID,
+		Slug:              SlugFromURL(newsArticleInfo.NewsArticle.ArticleURL),
 		PublishDate:       newsArticleInfo.NewsArticle.PublishDate.Time,
 		Taxonomies:        newsArticleInfo.NewsArticle.Taxonomies,
 		TeaserText:        newsArticleInfo.NewsArticle.TeaserText,
@@ -124,6 +178,9 @@ func ConvertToMongoDB(newsArticleInfo *NewsArticleInformationXML) *NewsArticleIn
 		OptaMatchID:       newsArticleInfo.NewsArticle.OptaMatchID,
 		LastUpdateDate:    newsArticleInfo.NewsArticle.LastUpdateDate.Time,
 		IsPublished:       newsArticleInfo.NewsArticle.IsPublished,
+		Warnings:          newsArticleInfo.Warnings,
+		DatesUTC:          true,
 	}
+	newsArticleInfoMongoDB.ContentHash = newsArticleInfoMongoDB.ComputeContentHash()
 	return &newsArticleInfoMongoDB
 }
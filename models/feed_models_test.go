@@ -0,0 +1,15 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugFromURL(t *testing.T) {
+	assert.Equal(t, "news-2023-july-26-match-report", SlugFromURL("https://www.htafc.com/news/2023/july/26/Match-Report/"))
+	// the same last segment in another month is another article
+	assert.NotEqual(t, SlugFromURL("https://www.htafc.com/2024/01/match-report"), SlugFromURL("https://www.htafc.com/2024/02/match-report"))
+	assert.Equal(t, "", SlugFromURL("https://www.htafc.com"))
+	assert.Equal(t, "", SlugFromURL("://bad"))
+}
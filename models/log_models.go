@@ -0,0 +1,12 @@
+package models
+
+// LogLevel is the minimum level the service logs at, one of debug, info, warn and error
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+type LogLevelResponse struct {
+	Status string   `json:"status"`
+	Data   LogLevel `json:"data"`
+	Error  string   `json:"error,omitempty"`
+}
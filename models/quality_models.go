@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ValidationPolicy decides what happens to an article that breaks a validation rule
+type ValidationPolicy string
+
+const (
+	PolicyReject            ValidationPolicy = "reject"
+	PolicyQuarantine        ValidationPolicy = "quarantine"
+	PolicyStoreWithWarnings ValidationPolicy = "store-with-warnings"
+)
+
+type Violation struct {
+	Field   string `bson:"field" json:"field"`
+	Rule    string `bson:"rule" json:"rule"`
+	Message string `bson:"message" json:"message"`
+}
+
+// One rule broken by one article during a sync
+type ViolationRecord struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Feed          string             `bson:"feed" json:"feed"`
+	NewsArticleID int                `bson:"NewsArticleID" json:"newsArticleId"`
+	Field         string             `bson:"field" json:"field"`
+	Rule          string             `bson:"rule" json:"rule"`
+	Message       string             `bson:"message" json:"message"`
+	Action        ValidationPolicy   `bson:"action" json:"action"`
+	ContentHash   string             `bson:"contentHash" json:"contentHash"`
+	OccurredAt    time.Time          `bson:"occurredAt" json:"occurredAt"`
+}
+
+type QuarantinedArticle struct {
+	Feed          string                        `bson:"feed" json:"feed"`
+	NewsArticleID int                           `bson:"NewsArticleID" json:"newsArticleId"`
+	Article       NewsArticleInformationMongoDB `bson:"article" json:"article"`
+	Violations    []Violation                   `bson:"violations" json:"violations"`
+	QuarantinedAt time.Time                     `bson:"quarantinedAt" json:"quarantinedAt"`
+}
+
+// Number of violations of one rule in one feed on one day
+type ViolationBucket struct {
+	Feed   string           `bson:"feed" json:"feed"`
+	Day    string           `bson:"day" json:"day"`
+	Rule   string           `bson:"rule" json:"rule"`
+	Action ValidationPolicy `bson:"action" json:"action"`
+	Count  int              `bson:"count" json:"count"`
+}
+
+type DataQualityDay struct {
+	Day    string         `json:"day"`
+	Total  int            `json:"total"`
+	ByRule map[string]int `json:"byRule"`
+}
+
+type DataQualityFeed struct {
+	Feed     string                   `json:"feed"`
+	Total    int                      `json:"total"`
+	ByRule   map[string]int           `json:"byRule"`
+	ByAction map[ValidationPolicy]int `json:"byAction"`
+	Days     []DataQualityDay         `json:"days"`
+}
+
+type DataQualityReport struct {
+	Since time.Time         `json:"since"`
+	Feeds []DataQualityFeed `json:"feeds"`
+}
+
+type DataQualityResponse struct {
+	Status string            `json:"status"`
+	Data   DataQualityReport `json:"data"`
+	Error  string            `json:"error,omitempty"`
+}
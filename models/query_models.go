@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ArticleQuery selects one page of the articles, newest first, so the APIs don't
+// have to load the whole collection to filter and page it themselves
+type ArticleQuery struct {
+	ArticleFilter
+	// matches the title or the teaser, ignoring case
+	Search string
+	// every term must appear in the title, the teaser or the content, ignoring case
+	Terms              []string
+	PublishedAfter     time.Time
+	PublishedBefore    time.Time
+	IncludeUnpublished bool
+	// the page starts after this article, at the newest one when zero
+	After primitive.ObjectID
+	// at most this many articles, every matching one when negative
+	Limit int
+}
+
+// ArticlePage is one page of the articles matching a query
+type ArticlePage struct {
+	Articles []NewsArticleInformationMongoDB
+	// matching articles across every page
+	TotalCount int
+	HasMore    bool
+}
+
+// Matches applies the query's filter, but not its paging
+func (q *ArticleQuery) Matches(article *NewsArticleInformationMongoDB) bool {
+	if !article.IsPublished && !q.IncludeUnpublished {
+		return false
+	}
+	if !q.ArticleFilter.Matches(article) {
+		return false
+	}
+	title, teaser := strings.ToLower(article.Title), strings.ToLower(article.TeaserText)
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(title, search) && !strings.Contains(teaser, search) {
+			return false
+		}
+	}
+	content := strings.ToLower(article.BodyText)
+	for _, term := range q.Terms {
+		term = strings.ToLower(term)
+		if !strings.Contains(title, term) && !strings.Contains(teaser, term) && !strings.Contains(content, term) {
+			return false
+		}
+	}
+	if !q.PublishedAfter.IsZero() && !article.PublishDate.After(q.PublishedAfter) {
+		return false
+	}
+	if !q.PublishedBefore.IsZero() && !article.PublishDate.Before(q.PublishedBefore) {
+		return false
+	}
+	return true
+}
+
+// Newer reports whether a comes before b in a query's newest first order
+func Newer(a, b *NewsArticleInformationMongoDB) bool {
+	if !a.PublishDate.Equal(b.PublishDate) {
+		return a.PublishDate.After(b.PublishDate)
+	}
+	return a.ID.Hex() > b.ID.Hex()
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Snapshot of an article taken every time its content hash changes
+type NewsArticleRevision struct {
+	ID            primitive.ObjectID            `bson:"_id,omitempty" json:"-"`
+	NewsArticleID int                           `bson:"NewsArticleID" json:"-"`
+	Revision      int                           `bson:"revision" json:"revision"`
+	ContentHash   string                        `bson:"contentHash" json:"contentHash"`
+	CapturedAt    time.Time                     `bson:"capturedAt" json:"capturedAt"`
+	Article       NewsArticleInformationMongoDB `bson:"article" json:"article"`
+}
+
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type NewsArticleDiff struct {
+	FromRevision int         `json:"fromRevision"`
+	ToRevision   int         `json:"toRevision"`
+	Changes      []FieldDiff `json:"changes"`
+}
+
+type NewsArticleRevisionsResponse struct {
+	Status string                `json:"status"`
+	Data   []NewsArticleRevision `json:"data"`
+	Error  string                `json:"error,omitempty"`
+}
+
+type NewsArticleRevisionResponse struct {
+	Status string              `json:"status"`
+	Data   NewsArticleRevision `json:"data"`
+	Error  string              `json:"error,omitempty"`
+}
+
+type NewsArticleDiffResponse struct {
+	Status string          `json:"status"`
+	Data   NewsArticleDiff `json:"data"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// fields that are bookkeeping rather than article content
+var nonContentFields = map[string]bool{
+	"ID":             true,
+	"ContentHash":    true,
+	"Slug":           true,
+	"Warnings":       true,
+	"DatesUTC":       true,
+	"LastUpdateDate": true,
+}
+
+// ComputeContentHash returns a sha256 over the editorial content of the article,
+// LastUpdateDate is left out so a bump without an actual edit doesn't create a revision
+func (a *NewsArticleInformationMongoDB) ComputeContentHash() string {
+	content := map[string]interface{}{}
+	v := reflect.ValueOf(*a)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if nonContentFields[name] {
+			continue
+		}
+		content[name] = v.Field(i).Interface()
+	}
+	// json sorts map keys so the encoding is stable
+	encoded, _ := json.Marshal(content)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffRevisions returns the field-level changes going from one revision to another
+func DiffRevisions(from, to *NewsArticleRevision) NewsArticleDiff {
+	diff := NewsArticleDiff{
+		FromRevision: from.Revision,
+		ToRevision:   to.Revision,
+		Changes:      []FieldDiff{},
+	}
+	fromValue := reflect.ValueOf(from.Article)
+	toValue := reflect.ValueOf(to.Article)
+	for i := 0; i < fromValue.NumField(); i++ {
+		name := fromValue.Type().Field(i).Name
+		if nonContentFields[name] {
+			continue
+		}
+		before := fmt.Sprint(fromValue.Field(i).Interface())
+		after := fmt.Sprint(toValue.Field(i).Interface())
+		if before != after {
+			diff.Changes = append(diff.Changes, FieldDiff{Field: name, From: before, To: after})
+		}
+	}
+	return diff
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// SyncStatus describes the reader's polls of the upstream news list. A poll succeeds
+// when the list could be read, even if some of its articles failed to sync.
+type SyncStatus struct {
+	LastRun     time.Time `json:"lastRun"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastError   string    `json:"lastError,omitempty"`
+}
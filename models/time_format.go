@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// embed the timezone database, the alpine image ships without one
+	_ "time/tzdata"
+)
+
+const (
+	UPSTREAM_TIME_LAYOUT = "2006-01-02 15:04:05"
+	UPSTREAM_TIMEZONE    = "Europe/London"
+)
+
+// TimeFormat describes how a feed writes its dates: the layouts to try in
+// order and the timezone of values that carry no offset
+type TimeFormat struct {
+	Layouts  []string
+	Location *time.Location
+}
+
+// DefaultTimeFormat matches the club's feed, local London time without an offset
+var DefaultTimeFormat = NewTimeFormat(nil, nil)
+
+// NewTimeFormat falls back to the upstream layouts and Europe/London when
+// layouts is empty or location is nil
+func NewTimeFormat(layouts []string, location *time.Location) TimeFormat {
+	if len(layouts) == 0 {
+		layouts = []string{
+			UPSTREAM_TIME_LAYOUT,
+			"2006-01-02T15:04:05",
+			time.RFC3339,
+			time.RFC1123Z,
+			time.RFC1123,
+		}
+	}
+	if location == nil {
+		london, err := time.LoadLocation(UPSTREAM_TIMEZONE)
+		if err != nil {
+			london = time.UTC
+		}
+		location = london
+	}
+	return TimeFormat{Layouts: layouts, Location: location}
+}
+
+// ParseTimeFormat builds a TimeFormat from "|" separated layouts and an IANA timezone name
+func ParseTimeFormat(layouts string, timezone string) (TimeFormat, error) {
+	var location *time.Location
+	if timezone != "" {
+		loaded, err := time.LoadLocation(timezone)
+		if err != nil {
+			return TimeFormat{}, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+		}
+		location = loaded
+	}
+	parsedLayouts := []string{}
+	for _, layout := range strings.Split(layouts, "|") {
+		if layout = strings.TrimSpace(layout); layout != "" {
+			parsedLayouts = append(parsedLayouts, layout)
+		}
+	}
+	return NewTimeFormat(parsedLayouts, location), nil
+}
+
+// Parse returns the value as UTC, empty values give the zero time without an error
+func (f TimeFormat) Parse(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range f.Layouts {
+		t, err := time.ParseInLocation(layout, value, f.Location)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("error parsing custom time %q: no layout matched", value)
+}
+
+// NormaliseDates converts the dates of an article stored before dates were normalised to
+// UTC, which kept the feed's wall clock in location labelled as UTC, and recomputes its
+// content hash. It reports false and changes nothing when the article is already flagged
+// DatesUTC, so the conversion is never applied twice
+func (a *NewsArticleInformationMongoDB) NormaliseDates(location *time.Location) bool {
+	if a.DatesUTC {
+		return false
+	}
+	a.PublishDate = wallClockIn(a.PublishDate, location)
+	a.LastUpdateDate = wallClockIn(a.LastUpdateDate, location)
+	a.ContentHash = a.ComputeContentHash()
+	a.DatesUTC = true
+	return true
+}
+
+// wallClockIn reads the UTC clock reading of value as a time in location
+func wallClockIn(value time.Time, location *time.Location) time.Time {
+	if value.IsZero() {
+		return value
+	}
+	value = value.UTC()
+	return time.Date(value.Year(), value.Month(), value.Day(), value.Hour(), value.Minute(),
+		value.Second(), value.Nanosecond(), location).UTC()
+}
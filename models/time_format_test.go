@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeFormatParse(t *testing.T) {
+	format := DefaultTimeFormat
+
+	// BST is UTC+1, GMT is UTC+0
+	summer, err := format.Parse("2023-07-26 09:45:00")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 7, 26, 8, 45, 0, 0, time.UTC), summer)
+
+	winter, err := format.Parse(" 2023-01-26 09:45:00 ")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 1, 26, 9, 45, 0, 0, time.UTC), winter)
+
+	withOffset, err := format.Parse("2023-07-26T09:45:00+02:00")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 7, 26, 7, 45, 0, 0, time.UTC), withOffset)
+
+	empty, err := format.Parse("")
+	assert.NoError(t, err)
+	assert.True(t, empty.IsZero())
+
+	_, err = format.Parse("yesterday")
+	assert.Error(t, err)
+}
+
+func TestParseTimeFormat(t *testing.T) {
+	format, err := ParseTimeFormat("02/01/2006 15:04 | 2006-01-02", "America/New_York")
+	assert.NoError(t, err)
+	parsed, err := format.Parse("26/07/2023 09:45")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 7, 26, 13, 45, 0, 0, time.UTC), parsed)
+
+	_, err = ParseTimeFormat("", "Mars/Olympus_Mons")
+	assert.Error(t, err)
+}
+
+func TestCustomTimeUnmarshalXML(t *testing.T) {
+	var article NewsArticle
+	err := xml.Unmarshal([]byte(`<NewsArticle><PublishDate/><LastUpdateDate>26/07/2023 09:45</LastUpdateDate></NewsArticle>`), &article)
+	assert.NoError(t, err)
+	assert.True(t, article.PublishDate.IsZero())
+	assert.True(t, article.LastUpdateDate.IsZero())
+	assert.Equal(t, "26/07/2023 09:45", article.LastUpdateDate.Raw)
+
+	// the feed's own format picks up what the default couldn't parse
+	assert.Error(t, article.ApplyTimeFormat(DefaultTimeFormat))
+	format, _ := ParseTimeFormat("02/01/2006 15:04", "Europe/London")
+	assert.NoError(t, article.ApplyTimeFormat(format))
+	assert.Equal(t, time.Date(2023, 7, 26, 8, 45, 0, 0, time.UTC), article.LastUpdateDate.Time)
+}
+
+func FuzzTimeFormatParse(f *testing.F) {
+	for _, seed := range []string{"2023-07-26 09:45:00", "2023-03-26 01:30:00", "2023-10-29 01:30:00", "2023-07-26T09:45:00Z", "", "  ", "0000-00-00 00:00:00"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		parsed, err := DefaultTimeFormat.Parse(value)
+		if err != nil {
+			if !parsed.IsZero() {
+				t.Errorf("Expected the zero time on error, got %v", parsed)
+			}
+			return
+		}
+		if parsed.Location() != time.UTC {
+			t.Errorf("Expected %q to be normalised to UTC, got %v", value, parsed.Location())
+		}
+	})
+}
+
+func FuzzCustomTimeUnmarshalXML(f *testing.F) {
+	for _, seed := range []string{"2023-07-26 09:45:00", "", "<b>bold</b>", "&amp;", "2023-07-26 09:45:00<x/>"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		var ct CustomTime
+		// must never panic, and only fail on XML that can't be decoded at all
+		err := xml.Unmarshal([]byte("<PublishDate>"+value+"</PublishDate>"), &ct)
+		if err == nil && !ct.IsZero() && ct.Location() != time.UTC {
+			t.Errorf("Expected %q to be normalised to UTC, got %v", value, ct.Location())
+		}
+	})
+}
+
+func TestNormaliseDates(t *testing.T) {
+	london, _ := time.LoadLocation(UPSTREAM_TIMEZONE)
+	// stored before the normalisation, 09:45 London wall clock labelled as UTC
+	article := NewsArticleInformationMongoDB{
+		NewsArticleID:  1,
+		Title:          "Match report",
+		PublishDate:    time.Date(2023, 7, 26, 9, 45, 0, 0, time.UTC),
+		LastUpdateDate: time.Date(2023, 1, 26, 9, 45, 0, 0, time.UTC),
+	}
+	article.ContentHash = article.ComputeContentHash()
+	old := article.ContentHash
+
+	assert.True(t, article.NormaliseDates(london))
+	assert.Equal(t, time.Date(2023, 7, 26, 8, 45, 0, 0, time.UTC), article.PublishDate)
+	assert.Equal(t, time.Date(2023, 1, 26, 9, 45, 0, 0, time.UTC), article.LastUpdateDate)
+	assert.True(t, article.DatesUTC)
+	assert.NotEqual(t, old, article.ContentHash)
+
+	// converting again, e.g. after an interrupted migration, changes nothing
+	converted := article
+	assert.False(t, article.NormaliseDates(london))
+	assert.Equal(t, converted, article)
+
+	// the hash matches what a sync of the same upstream article computes
+	fresh := ConvertToMongoDB(&NewsArticleInformationXML{NewsArticle: NewsArticle{
+		NewsArticleID:  1,
+		Title:          "Match report",
+		PublishDate:    CustomTime{Time: article.PublishDate},
+		LastUpdateDate: CustomTime{Time: article.LastUpdateDate},
+	}})
+	assert.True(t, fresh.DatesUTC)
+	assert.Equal(t, fresh.ContentHash, article.ContentHash)
+}
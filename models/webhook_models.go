@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookSubscription struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL       string             `bson:"url" json:"url"`
+	Events    []SyncOutcome      `bson:"events" json:"events"`
+	Club      string             `bson:"club" json:"club,omitempty"`
+	Taxonomy  string             `bson:"taxonomy" json:"taxonomy,omitempty"`
+	Secret    string             `bson:"secret" json:"secret,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Wants reports whether the event passes the subscription's event and article filter
+func (s *WebhookSubscription) Wants(event *ArticleEvent) bool {
+	filter := ArticleFilter{Club: s.Club, Taxonomy: s.Taxonomy}
+	if !filter.Matches(&event.Article) {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, eventType := range s.Events {
+		if eventType == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// One attempt at delivering an event to a subscription
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SubscriptionID primitive.ObjectID `bson:"subscriptionId" json:"subscriptionId"`
+	EventID        uint64             `bson:"eventId" json:"eventId"`
+	EventType      SyncOutcome        `bson:"eventType" json:"eventType"`
+	Attempt        int                `bson:"attempt" json:"attempt"`
+	StatusCode     int                `bson:"statusCode" json:"statusCode,omitempty"`
+	Error          string             `bson:"error" json:"error,omitempty"`
+	Success        bool               `bson:"success" json:"success"`
+	DeliveredAt    time.Time          `bson:"deliveredAt" json:"deliveredAt"`
+	DurationMs     int64              `bson:"durationMs" json:"durationMs"`
+}
+
+type WebhookSubscriptionRequest struct {
+	URL      string        `json:"url"`
+	Events   []SyncOutcome `json:"events"`
+	Club     string        `json:"club"`
+	Taxonomy string        `json:"taxonomy"`
+	Secret   string        `json:"secret"`
+}
+
+type WebhookSubscriptionResponse struct {
+	Status string              `json:"status"`
+	Data   WebhookSubscription `json:"data"`
+	Error  string              `json:"error,omitempty"`
+}
+
+type WebhookSubscriptionsResponse struct {
+	Status string                `json:"status"`
+	Data   []WebhookSubscription `json:"data"`
+	Error  string                `json:"error,omitempty"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Status string            `json:"status"`
+	Data   []WebhookDelivery `json:"data"`
+	Error  string            `json:"error,omitempty"`
+}
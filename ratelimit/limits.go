@@ -0,0 +1,100 @@
+// Package ratelimit throttles each client with a token bucket per route. The
+// buckets live in a Store, in memory by default or shared between replicas.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DEFAULT_ROUTE holds the limit of every route without its own
+	DEFAULT_ROUTE = "default"
+	// IP_BUCKET holds the limit every IP is held to before authentication
+	IP_BUCKET = "ip"
+)
+
+// Limit refills Rate tokens per second up to Burst, every request takes one
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limits maps route names to their limit
+type Limits map[string]Limit
+
+// ParseLimits reads limits in the form "route=rate/unit,burst;...", e.g.
+// "default=10/s,20;articles=30/m,5", the burst defaults to the rate rounded up
+func ParseLimits(value string) (Limits, error) {
+	limits := Limits{}
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q", entry)
+		}
+		limit, err := parseLimit(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit for %s: %v", strings.TrimSpace(name), err)
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits, nil
+}
+
+func parseLimit(spec string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(spec, ",")
+	count, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not rate/unit", rate)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate %q", count)
+	}
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("unknown unit %q, use s, m or h", unit)
+	}
+	limit := Limit{Rate: n / per.Seconds(), Burst: int(n + 0.999)}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst))
+		if err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("invalid burst %q", burst)
+		}
+	}
+	return limit, nil
+}
+
+// Merge returns a copy of l with the overrides applied
+func (l Limits) Merge(overrides Limits) Limits {
+	merged := Limits{}
+	for name, limit := range l {
+		merged[name] = limit
+	}
+	for name, limit := range overrides {
+		merged[name] = limit
+	}
+	return merged
+}
+
+// For returns the route's limit and the bucket it counts against, routes
+// without their own limit share the default bucket
+func (l Limits) For(route string) (Limit, string, bool) {
+	if limit, ok := l[route]; ok {
+		return limit, route, true
+	}
+	limit, ok := l[DEFAULT_ROUTE]
+	return limit, DEFAULT_ROUTE, ok
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"alibazlamit/feed-provider/httpcache"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ClientFunc names the client a request counts against
+type ClientFunc func(r *http.Request) string
+
+type Limiter struct {
+	limits Limits
+	store  Store
+	client ClientFunc
+	logger *slog.Logger
+	now    func() time.Time
+	// every route counts against this bucket when set
+	bucket string
+}
+
+func NewLimiter(limits Limits, store Store, client ClientFunc, logger *slog.Logger) *Limiter {
+	return &Limiter{limits: limits, store: store, client: client, logger: logger, now: time.Now}
+}
+
+// NewIPLimiter counts every request against its IP's IP_BUCKET whatever the route. It runs
+// in front of authentication, so requests with invalid keys are throttled too and can't
+// flood the key lookups
+func NewIPLimiter(limit Limit, store Store, trustProxy bool, logger *slog.Logger) *Limiter {
+	client := func(r *http.Request) string {
+		return "ip:" + ClientIP(r, trustProxy)
+	}
+	limiter := NewLimiter(Limits{DEFAULT_ROUTE: limit}, store, client, logger)
+	limiter.bucket = IP_BUCKET
+	return limiter
+}
+
+// Middleware takes a token from the client's bucket for the matched route and
+// answers 429 once it is empty. A failing store lets the request through.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			name = route.GetName()
+		}
+		limit, result, ok := l.take(r.Context(), l.client(r), name)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", httpcache.NO_STORE)
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(models.NewsArticlesResponse{
+				Status: string(models.Failure),
+				Error:  "Rate limit exceeded",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Allow takes a token from the client's bucket for the route outside of HTTP, such as for
+// a gRPC call, and says how long to wait when it is empty. A failing store allows the call
+func (l *Limiter) Allow(ctx context.Context, client, route string) (bool, time.Duration) {
+	_, result, ok := l.take(ctx, client, route)
+	if !ok || result.Allowed {
+		return true, 0
+	}
+	return false, result.RetryAfter
+}
+
+// take returns false when the route isn't limited or the store failed
+func (l *Limiter) take(ctx context.Context, client, route string) (Limit, Result, bool) {
+	limit, bucket, ok := l.limits.For(route)
+	if !ok {
+		return limit, Result{}, false
+	}
+	if l.bucket != "" {
+		bucket = l.bucket
+	}
+	result, err := l.store.Take(client+"|"+bucket, limit, l.now())
+	if err != nil {
+		l.logger.ErrorContext(ctx, "error taking rate limit token", "error", err)
+		return limit, Result{}, false
+	}
+	return limit, result, true
+}
+
+// ClientIP returns the caller's address, X-Forwarded-For is only trusted behind a proxy
+// that sets it, otherwise clients could pick their own bucket
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const MONGO_TIMEOUT = time.Second
+
+// MongoStore shares the buckets between replicas through a collection, every
+// take is a single pipeline update so concurrent replicas can't overdraw a bucket
+type MongoStore struct {
+	Collection *mongo.Collection
+}
+
+type mongoBucket struct {
+	Tokens  float64   `bson:"tokens"`
+	Allowed bool      `bson:"allowed"`
+	FullAt  time.Time `bson:"fullAt"`
+}
+
+// CreateIndexes lets Mongo drop the buckets once they are full again
+func (s *MongoStore) CreateIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*MONGO_TIMEOUT)
+	defer cancel()
+	_, err := s.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "fullAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (s *MongoStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), MONGO_TIMEOUT)
+	defer cancel()
+
+	burst := float64(limit.Burst)
+	refilled := bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", burst}},
+		bson.M{"$multiply": bson.A{
+			bson.M{"$divide": bson.A{bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated", now}}}}}}, 1000}},
+			limit.Rate,
+		}},
+	}}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled, "updated": now}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+			"tokens":  bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$tokens", 1}}, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"fullAt": bson.M{"$add": bson.A{now, bson.M{"$multiply": bson.A{bson.M{"$subtract": bson.A{burst, "$tokens"}}, 1000 / limit.Rate}}}},
+		}}},
+	}
+	var b mongoBucket
+	err := s.Collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&b)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Allowed: b.Allowed, Remaining: int(b.Tokens)}
+	if !b.Allowed {
+		result.RetryAfter = time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+	}
+	result.Reset = time.Duration((burst - b.Tokens) / limit.Rate * float64(time.Second))
+	return result, nil
+}
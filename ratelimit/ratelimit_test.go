@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("default=10/s,20; articles=30/m ;feed=1.5/h,3")
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{
+		"default":  {Rate: 10, Burst: 20},
+		"articles": {Rate: 0.5, Burst: 30},
+		"feed":     {Rate: 1.5 / 3600, Burst: 3},
+	}, limits)
+
+	for _, value := range []string{"articles", "articles=10", "articles=0/s", "articles=10/d", "articles=10/s,0"} {
+		_, err := ParseLimits(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	limits := Limits{DEFAULT_ROUTE: {Rate: 1, Burst: 1}, "articles": {Rate: 2, Burst: 2}}
+
+	limit, bucket, ok := limits.For("articles")
+	assert.True(t, ok)
+	assert.Equal(t, "articles", bucket)
+	assert.Equal(t, 2, limit.Burst)
+
+	_, bucket, ok = limits.For("feed")
+	assert.True(t, ok)
+	assert.Equal(t, DEFAULT_ROUTE, bucket)
+
+	_, _, ok = Limits{}.For("feed")
+	assert.False(t, ok)
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 2, Burst: 2}
+	now := time.Date(2023, 7, 27, 2, 0, 0, 0, time.UTC)
+
+	for i := 1; i >= 0; i-- {
+		result, _ := store.Take("client", limit, now)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+	result, _ := store.Take("client", limit, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, time.Second, result.Reset)
+
+	// other clients have their own bucket
+	result, _ = store.Take("other", limit, now)
+	assert.True(t, result.Allowed)
+
+	result, _ = store.Take("client", limit, now.Add(500*time.Millisecond))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 5}
+	now := time.Date(2023, 7, 27, 2, 0, 0, 0, time.UTC)
+
+	store.Take("idle", limit, now)
+	store.Take("busy", Limit{Rate: 1.0 / 3600, Burst: 5}, now)
+	store.Take("trigger", limit, now.Add(2*SWEEP_INTERVAL))
+
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "busy")
+	assert.Contains(t, store.buckets, "trigger")
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func newTestRouter(limiter *Limiter) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(limiter.Middleware)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/articles", ok).Name("articles")
+	router.HandleFunc("/ping", ok).Name("ping")
+	return router
+}
+
+func TestMiddleware(t *testing.T) {
+	limits := Limits{DEFAULT_ROUTE: {Rate: 10, Burst: 10}, "articles": {Rate: 1, Burst: 1}}
+	limiter := NewLimiter(limits, NewMemoryStore(), func(r *http.Request) string {
+		return ClientIP(r, false)
+	}, slog.New(slog.DiscardHandler))
+	now := time.Date(2023, 7, 27, 2, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	router := newTestRouter(limiter)
+
+	get := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/articles", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Reset"))
+
+	rr = get("/articles", "10.0.0.1:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	assert.Contains(t, rr.Body.String(), "Rate limit exceeded")
+
+	// the default bucket and other clients are unaffected
+	assert.Equal(t, http.StatusOK, get("/ping", "10.0.0.1:1234").Code)
+	assert.Equal(t, "10", get("/ping", "10.0.0.2:1234").Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, http.StatusOK, get("/articles", "10.0.0.2:1234").Code)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, get("/articles", "10.0.0.1:1234").Code)
+}
+
+func TestMiddlewareLetsRequestsThroughWhenTheStoreFails(t *testing.T) {
+	limiter := NewLimiter(Limits{DEFAULT_ROUTE: {Rate: 1, Burst: 1}}, failingStore{}, func(r *http.Request) string {
+		return "client"
+	}, slog.New(slog.DiscardHandler))
+	router := newTestRouter(limiter)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/ping", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/articles", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	assert.Equal(t, "10.0.0.1", ClientIP(req, false))
+	assert.Equal(t, "203.0.113.7", ClientIP(req, true))
+}
+
+func TestIPLimiterCountsEveryRoute(t *testing.T) {
+	limiter := NewIPLimiter(Limit{Rate: 1, Burst: 2}, NewMemoryStore(), false, slog.New(slog.DiscardHandler))
+	limiter.now = func() time.Time { return time.Date(2023, 7, 27, 2, 0, 0, 0, time.UTC) }
+	router := mux.NewRouter()
+	router.Use(limiter.Middleware)
+	unauthorized := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) }
+	router.HandleFunc("/articles", unauthorized).Name("articles")
+	router.HandleFunc("/feed", unauthorized).Name("feed")
+
+	codes := []int{}
+	for _, path := range []string{"/articles", "/feed", "/articles"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		router.ServeHTTP(rr, req)
+		codes = append(codes, rr.Code)
+	}
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+}
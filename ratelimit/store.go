@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// how often the memory store drops buckets that filled up again
+	SWEEP_INTERVAL = time.Minute
+)
+
+// Result is the state of a bucket after taking a token
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token, zero when allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps the buckets, implementations must take tokens atomically so
+// concurrent requests of one client can't overdraw its bucket
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// when the bucket is full again and can be forgotten
+	fullAt time.Time
+}
+
+// take refills the bucket up to now and takes a token when there is one
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second))
+	b.fullAt = now.Add(result.Reset)
+	return result
+}
+
+// MemoryStore keeps the buckets of this process only
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > SWEEP_INTERVAL {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	return b.take(limit, now), nil
+}
+
+// sweep drops the buckets that filled up again, a missing bucket starts full anyway
+func (s *MemoryStore) sweep(now time.Time) {
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.After(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package requestlog assigns every request an ID and writes one access log line per
+// request. Handlers further down the chain add to the line through the request's
+// context, e.g. the API key ID.
+package requestlog
+
+import (
+	"alibazlamit/feed-provider/logging"
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type contextKey struct{}
+
+// fields is shared between the middleware and the handlers it wraps
+type fields struct {
+	mu    sync.Mutex
+	keyID string
+}
+
+// Middleware keeps the X-Request-ID the client sent, or generates one, echoes it in the
+// response and logs the method, path, status, size, duration and the caller's key ID.
+// The query string is left out as it can carry credentials.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := logging.RequestIDOrNew(r.Header.Get(logging.REQUEST_ID_HEADER))
+			w.Header().Set(logging.REQUEST_ID_HEADER, requestID)
+			ctx := logging.WithRequestID(r.Context(), requestID)
+
+			f := &fields{}
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(ctx, contextKey{}, f)))
+
+			f.mu.Lock()
+			keyID := f.keyID
+			f.mu.Unlock()
+			if keyID == "" {
+				keyID = "-"
+			}
+			logger.InfoContext(ctx, "request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", recorder.status,
+				"bytes", recorder.bytes,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"key_id", keyID,
+			)
+		})
+	}
+}
+
+// SetKeyID attributes the request to an API key in its log line
+func SetKeyID(r *http.Request, keyID string) {
+	if f, ok := r.Context().Value(contextKey{}).(*fields); ok {
+		f.mu.Lock()
+		f.keyID = keyID
+		f.mu.Unlock()
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Flush keeps the article stream working through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,224 @@
+package upstream
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client is the HTTP client used for every request to the upstream feed, it is safe
+// to share between the reader's workers so the limits hold across all of them
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	mu         sync.Mutex
+	hosts      map[string]*hostLimiter
+}
+
+func NewClient(config Config) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	for _, encoding := range config.AcceptEncoding {
+		if encoding != "gzip" && encoding != "deflate" {
+			return nil, fmt.Errorf("unsupported encoding %q", encoding)
+		}
+	}
+	// we decode the encodings we asked for ourselves
+	transport.DisableCompression = len(config.AcceptEncoding) > 0
+
+	return &Client{
+		config: config,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+		hosts: map[string]*hostLimiter{},
+	}, nil
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	limiter := c.limiter(req.URL.Host)
+	if err := limiter.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	if len(c.config.AcceptEncoding) > 0 {
+		req.Header.Set("Accept-Encoding", strings.Join(c.config.AcceptEncoding, ", "))
+	}
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		limiter.release()
+		return nil, err
+	}
+	if err = decompress(response); err != nil {
+		response.Body.Close()
+		limiter.release()
+		return nil, err
+	}
+	// the request counts against the host's concurrency until its body is closed
+	response.Body = &releasingBody{ReadCloser: response.Body, release: limiter.release}
+	return response, nil
+}
+
+func (c *Client) limiter(host string) *hostLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	limiter, ok := c.hosts[host]
+	if !ok {
+		limiter = newHostLimiter(c.config.MaxConcurrentPerHost, c.config.RequestsPerSecond)
+		c.hosts[host] = limiter
+	}
+	return limiter
+}
+
+func decompress(response *http.Response) error {
+	var body io.ReadCloser
+	switch strings.ToLower(response.Header.Get("Content-Encoding")) {
+	case "gzip":
+		reader, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return fmt.Errorf("error reading gzip body: %v", err)
+		}
+		body = reader
+	case "deflate":
+		body = deflateReader(response.Body)
+	default:
+		return nil
+	}
+	response.Body = &decodedBody{ReadCloser: body, raw: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return nil
+}
+
+// deflateReader reads the zlib stream HTTP calls deflate, and raw deflate from the
+// servers that send it without the zlib header
+func deflateReader(body io.Reader) io.ReadCloser {
+	buffered := bufio.NewReader(body)
+	header, _ := buffered.Peek(2)
+	// a zlib header names the deflate method and is a multiple of 31
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if reader, err := zlib.NewReader(buffered); err == nil {
+			return reader
+		}
+	}
+	return flate.NewReader(buffered)
+}
+
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// hostLimiter combines a concurrency cap with a token bucket refilled at the
+// requests per second rate, holding at most one second worth of tokens
+type hostLimiter struct {
+	slots  chan struct{}
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newHostLimiter(maxConcurrent int, rate float64) *hostLimiter {
+	limiter := &hostLimiter{rate: rate, tokens: rate, last: time.Now()}
+	if maxConcurrent > 0 {
+		limiter.slots = make(chan struct{}, maxConcurrent)
+	}
+	return limiter
+}
+
+func (l *hostLimiter) acquire(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := l.waitForToken(ctx); err != nil {
+		l.release()
+		return err
+	}
+	return nil
+}
+
+func (l *hostLimiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+func (l *hostLimiter) waitForToken(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if burst := maxFloat(l.rate, 1); l.tokens > burst {
+			l.tokens = burst
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+package upstream
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientSendsUserAgentAndDecodesGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte("<NewListInformation/>"))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.UserAgent = "test-agent"
+	config.AcceptEncoding = []string{"gzip"}
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	response, err := client.Do(req)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "<NewListInformation/>", string(body))
+	assert.Empty(t, response.Header.Get("Content-Encoding"))
+}
+
+func TestClientDecodesDeflate(t *testing.T) {
+	raw := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "deflate")
+		var writer io.WriteCloser = zlib.NewWriter(w)
+		if raw {
+			writer, _ = flate.NewWriter(w, flate.DefaultCompression)
+		}
+		writer.Write([]byte("<NewListInformation/>"))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.AcceptEncoding = []string{"deflate"}
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	// HTTP deflate is zlib wrapped, some servers send raw deflate anyway
+	for _, raw = range []bool{false, true} {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		response, err := client.Do(req)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, "<NewListInformation/>", string(body), "raw deflate: %v", raw)
+	}
+}
+
+func TestClientCapsConcurrencyPerHost(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.MaxConcurrentPerHost = 2
+	config.RequestsPerSecond = 0
+	client, _ := NewClient(config)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			response, err := client.Do(req)
+			if assert.NoError(t, err) {
+				response.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxInFlight, int32(2))
+}
+
+func TestClientLimitsRequestsPerSecond(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.RequestsPerSecond = 20
+	client, _ := NewClient(config)
+
+	// the bucket starts full with 20 tokens, the next 10 requests take about half a second
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		response, err := client.Do(req)
+		assert.NoError(t, err)
+		response.Body.Close()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("UPSTREAM_TIMEOUT", "5s")
+	t.Setenv("UPSTREAM_MAX_CONCURRENT_PER_HOST", "0")
+	config, err := ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, config.Timeout)
+	assert.Equal(t, 0, config.MaxConcurrentPerHost)
+
+	t.Setenv("UPSTREAM_TIMEOUT", "5")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, `invalid UPSTREAM_TIMEOUT "5"`)
+
+	t.Setenv("UPSTREAM_TIMEOUT", "")
+	t.Setenv("UPSTREAM_REQUESTS_PER_SECOND", "-1")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, `invalid UPSTREAM_REQUESTS_PER_SECOND "-1"`)
+}
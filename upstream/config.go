@@ -0,0 +1,108 @@
+package upstream
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_USER_AGENT              = "feed-provider/1.0"
+	DEFAULT_TIMEOUT                 = 4 * time.Second
+	DEFAULT_MAX_IDLE_CONNS          = 20
+	DEFAULT_MAX_IDLE_CONNS_PER_HOST = 5
+	DEFAULT_IDLE_CONN_TIMEOUT       = 90 * time.Second
+	DEFAULT_MAX_CONCURRENT_PER_HOST = 5
+	DEFAULT_REQUESTS_PER_SECOND     = 10
+)
+
+// Config describes how the upstream client talks to the club's API
+type Config struct {
+	// BaseURL replaces https://www.htafc.com in the feed URLs, e.g. to read from the fake upstream
+	BaseURL   string
+	UserAgent string
+	// ProxyURL is used for every request when set, otherwise HTTP_PROXY/HTTPS_PROXY apply
+	ProxyURL            string
+	Timeout             time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// AcceptEncoding lists the compressions we ask for and decode ourselves, supported are
+	// gzip and deflate, when empty Go's transport negotiates gzip transparently
+	AcceptEncoding []string
+	// MaxConcurrentPerHost caps the requests in flight to a single host, 0 disables the cap
+	MaxConcurrentPerHost int
+	// RequestsPerSecond limits the request rate to a single host, 0 disables the limit
+	RequestsPerSecond float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		UserAgent:            DEFAULT_USER_AGENT,
+		Timeout:              DEFAULT_TIMEOUT,
+		MaxIdleConns:         DEFAULT_MAX_IDLE_CONNS,
+		MaxIdleConnsPerHost:  DEFAULT_MAX_IDLE_CONNS_PER_HOST,
+		IdleConnTimeout:      DEFAULT_IDLE_CONN_TIMEOUT,
+		AcceptEncoding:       []string{"gzip", "deflate"},
+		MaxConcurrentPerHost: DEFAULT_MAX_CONCURRENT_PER_HOST,
+		RequestsPerSecond:    DEFAULT_REQUESTS_PER_SECOND,
+	}
+}
+
+// ConfigFromEnv overrides the defaults with the UPSTREAM_* environment variables,
+// a value that doesn't parse is an error rather than a silent fallback
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	config.BaseURL = os.Getenv("UPSTREAM_BASE_URL")
+	if value := os.Getenv("UPSTREAM_USER_AGENT"); value != "" {
+		config.UserAgent = value
+	}
+	config.ProxyURL = os.Getenv("UPSTREAM_PROXY_URL")
+	for name, target := range map[string]*time.Duration{
+		"UPSTREAM_TIMEOUT":           &config.Timeout,
+		"UPSTREAM_IDLE_CONN_TIMEOUT": &config.IdleConnTimeout,
+	} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		value, err := time.ParseDuration(raw)
+		if err != nil || value < 0 {
+			return Config{}, fmt.Errorf("invalid %s %q", name, raw)
+		}
+		*target = value
+	}
+	for name, target := range map[string]*int{
+		"UPSTREAM_MAX_IDLE_CONNS":          &config.MaxIdleConns,
+		"UPSTREAM_MAX_IDLE_CONNS_PER_HOST": &config.MaxIdleConnsPerHost,
+		"UPSTREAM_MAX_CONCURRENT_PER_HOST": &config.MaxConcurrentPerHost,
+	} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return Config{}, fmt.Errorf("invalid %s %q", name, raw)
+		}
+		*target = value
+	}
+	if value, ok := os.LookupEnv("UPSTREAM_ACCEPT_ENCODING"); ok {
+		config.AcceptEncoding = []string{}
+		for _, encoding := range strings.Split(value, ",") {
+			if encoding = strings.TrimSpace(encoding); encoding != "" {
+				config.AcceptEncoding = append(config.AcceptEncoding, encoding)
+			}
+		}
+	}
+	if raw := os.Getenv("UPSTREAM_REQUESTS_PER_SECOND"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 {
+			return Config{}, fmt.Errorf("invalid UPSTREAM_REQUESTS_PER_SECOND %q", raw)
+		}
+		config.RequestsPerSecond = value
+	}
+	return config, nil
+}
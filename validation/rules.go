@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"alibazlamit/feed-provider/models"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	RULE_REQUIRED     = "required"
+	RULE_URL          = "url"
+	RULE_DATE_RANGE   = "date-range"
+	RULE_DATE_ORDER   = "date-order"
+	RULE_BODY_LENGTH  = "body-length"
+	MIN_BODY_LENGTH   = 1
+	MAX_BODY_LENGTH   = 200000
+	MAX_CLOCK_SKEW    = 24 * time.Hour
+	DEFAULT_POLICY    = models.PolicyStoreWithWarnings
+	EARLIEST_ARTICLES = "2000-01-01"
+)
+
+// Rules are the checks applied to every article the reader fetches
+type Rules struct {
+	MinBodyLength int
+	MaxBodyLength int
+	// publish dates before this are treated as broken
+	EarliestDate time.Time
+	// how far in the future a publish date may be
+	MaxClockSkew time.Duration
+	Policy       models.ValidationPolicy
+}
+
+func DefaultRules() Rules {
+	earliest, _ := time.Parse("2006-01-02", EARLIEST_ARTICLES)
+	return Rules{
+		MinBodyLength: MIN_BODY_LENGTH,
+		MaxBodyLength: MAX_BODY_LENGTH,
+		EarliestDate:  earliest,
+		MaxClockSkew:  MAX_CLOCK_SKEW,
+		Policy:        DEFAULT_POLICY,
+	}
+}
+
+// RulesFromEnv overrides the defaults with the VALIDATION_* environment variables
+func RulesFromEnv() (Rules, error) {
+	rules := DefaultRules()
+	for name, target := range map[string]*int{
+		"VALIDATION_MIN_BODY_LENGTH": &rules.MinBodyLength,
+		"VALIDATION_MAX_BODY_LENGTH": &rules.MaxBodyLength,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value < 0 {
+				return rules, fmt.Errorf("invalid %s %q", name, raw)
+			}
+			*target = value
+		}
+	}
+	if rules.MinBodyLength > rules.MaxBodyLength {
+		return rules, fmt.Errorf("VALIDATION_MIN_BODY_LENGTH %d is greater than VALIDATION_MAX_BODY_LENGTH %d", rules.MinBodyLength, rules.MaxBodyLength)
+	}
+	if raw := os.Getenv("VALIDATION_EARLIEST_DATE"); raw != "" {
+		value, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return rules, fmt.Errorf("invalid VALIDATION_EARLIEST_DATE %q", raw)
+		}
+		rules.EarliestDate = value
+	}
+	if raw := os.Getenv("VALIDATION_MAX_CLOCK_SKEW"); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil || value < 0 {
+			return rules, fmt.Errorf("invalid VALIDATION_MAX_CLOCK_SKEW %q", raw)
+		}
+		rules.MaxClockSkew = value
+	}
+	if value := os.Getenv("VALIDATION_POLICY"); value != "" {
+		switch policy := models.ValidationPolicy(value); policy {
+		case models.PolicyReject, models.PolicyQuarantine, models.PolicyStoreWithWarnings:
+			rules.Policy = policy
+		default:
+			return rules, fmt.Errorf("unknown validation policy %q", value)
+		}
+	}
+	return rules, nil
+}
+
+// Validate returns every rule the article breaks, now anchors the future date check
+func (rules Rules) Validate(article *models.NewsArticleInformationXML, now time.Time) []models.Violation {
+	violations := []models.Violation{}
+	add := func(field, rule, message string, args ...interface{}) {
+		violations = append(violations, models.Violation{Field: field, Rule: rule, Message: fmt.Sprintf(message, args...)})
+	}
+	news := &article.NewsArticle
+
+	if strings.TrimSpace(news.Title) == "" {
+		add("Title", RULE_REQUIRED, "title is empty")
+	}
+	if strings.TrimSpace(news.ArticleURL) == "" {
+		add("ArticleURL", RULE_REQUIRED, "article URL is empty")
+	} else if !validURL(news.ArticleURL) {
+		add("ArticleURL", RULE_URL, "%q is not an absolute http(s) URL", news.ArticleURL)
+	}
+	optionalURLs := map[string]string{
+		"ClubWebsiteURL":    article.ClubWebsiteURL,
+		"ThumbnailImageURL": news.ThumbnailImageURL,
+		"VideoURL":          news.VideoURL,
+	}
+	for _, field := range []string{"ClubWebsiteURL", "ThumbnailImageURL", "VideoURL"} {
+		if value := optionalURLs[field]; value != "" && !validURL(value) {
+			add(field, RULE_URL, "%q is not an absolute http(s) URL", value)
+		}
+	}
+
+	switch published := news.PublishDate.Time; {
+	case published.IsZero():
+		add("PublishDate", RULE_REQUIRED, "publish date is empty")
+	case published.Before(rules.EarliestDate):
+		add("PublishDate", RULE_DATE_RANGE, "publish date %s is before %s", published.Format(time.RFC3339), rules.EarliestDate.Format("2006-01-02"))
+	case published.After(now.Add(rules.MaxClockSkew)):
+		add("PublishDate", RULE_DATE_RANGE, "publish date %s is in the future", published.Format(time.RFC3339))
+	}
+	if updated := news.LastUpdateDate.Time; !updated.IsZero() && updated.Before(news.PublishDate.Time) {
+		add("LastUpdateDate", RULE_DATE_ORDER, "last update %s is before the publish date", updated.Format(time.RFC3339))
+	}
+
+	length := utf8.RuneCountInString(strings.TrimSpace(news.BodyText))
+	if length < rules.MinBodyLength {
+		add("BodyText", RULE_BODY_LENGTH, "body has %d characters, the minimum is %d", length, rules.MinBodyLength)
+	} else if rules.MaxBodyLength > 0 && length > rules.MaxBodyLength {
+		add("BodyText", RULE_BODY_LENGTH, "body has %d characters, the maximum is %d", length, rules.MaxBodyLength)
+	}
+	return violations
+}
+
+func validURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"alibazlamit/feed-provider/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validArticle() *models.NewsArticleInformationXML {
+	article := &models.NewsArticleInformationXML{ClubWebsiteURL: "https://www.htafc.com"}
+	article.NewsArticle.Title = "Town win"
+	article.NewsArticle.ArticleURL = "https://www.htafc.com/news/town-win"
+	article.NewsArticle.BodyText = "<p>Report</p>"
+	article.NewsArticle.PublishDate.Time = time.Date(2023, 7, 26, 8, 45, 0, 0, time.UTC)
+	article.NewsArticle.LastUpdateDate.Time = time.Date(2023, 7, 27, 1, 0, 0, 0, time.UTC)
+	return article
+}
+
+func TestValidateAcceptsAValidArticle(t *testing.T) {
+	now := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	assert.Empty(t, DefaultRules().Validate(validArticle(), now))
+}
+
+func TestValidateReportsEveryBrokenRule(t *testing.T) {
+	now := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	article := validArticle()
+	article.NewsArticle.Title = " "
+	article.NewsArticle.ArticleURL = "/news/town-win"
+	article.NewsArticle.ThumbnailImageURL = "image.png"
+	article.NewsArticle.BodyText = ""
+	article.NewsArticle.PublishDate.Time = now.Add(48 * time.Hour)
+
+	rules := []string{}
+	for _, violation := range DefaultRules().Validate(article, now) {
+		rules = append(rules, violation.Field+"/"+violation.Rule)
+	}
+	assert.ElementsMatch(t, []string{
+		"Title/required",
+		"ArticleURL/url",
+		"ThumbnailImageURL/url",
+		"PublishDate/date-range",
+		"LastUpdateDate/date-order",
+		"BodyText/body-length",
+	}, rules)
+}
+
+func TestValidateDateRange(t *testing.T) {
+	now := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	article := validArticle()
+	article.NewsArticle.PublishDate.Time = time.Time{}
+	article.NewsArticle.LastUpdateDate.Time = time.Time{}
+	violations := DefaultRules().Validate(article, now)
+	assert.Equal(t, 1, len(violations))
+	assert.Equal(t, RULE_REQUIRED, violations[0].Rule)
+
+	article.NewsArticle.PublishDate.Time = time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)
+	violations = DefaultRules().Validate(article, now)
+	assert.Equal(t, 1, len(violations))
+	assert.Equal(t, RULE_DATE_RANGE, violations[0].Rule)
+
+	// within the allowed clock skew
+	article.NewsArticle.PublishDate.Time = now.Add(time.Hour)
+	assert.Empty(t, DefaultRules().Validate(article, now))
+}
+
+func TestRulesFromEnv(t *testing.T) {
+	t.Setenv("VALIDATION_MIN_BODY_LENGTH", "20")
+	t.Setenv("VALIDATION_MAX_CLOCK_SKEW", "1h")
+	t.Setenv("VALIDATION_POLICY", "quarantine")
+	rules, err := RulesFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, 20, rules.MinBodyLength)
+	assert.Equal(t, MAX_BODY_LENGTH, rules.MaxBodyLength)
+	assert.Equal(t, time.Hour, rules.MaxClockSkew)
+	assert.Equal(t, models.PolicyQuarantine, rules.Policy)
+
+	t.Setenv("VALIDATION_POLICY", "drop")
+	_, err = RulesFromEnv()
+	assert.NotNil(t, err)
+
+	t.Setenv("VALIDATION_POLICY", "")
+	t.Setenv("VALIDATION_EARLIEST_DATE", "01/01/2000")
+	_, err = RulesFromEnv()
+	assert.EqualError(t, err, `invalid VALIDATION_EARLIEST_DATE "01/01/2000"`)
+
+	t.Setenv("VALIDATION_EARLIEST_DATE", "")
+	t.Setenv("VALIDATION_MAX_CLOCK_SKEW", "1 day")
+	_, err = RulesFromEnv()
+	assert.EqualError(t, err, `invalid VALIDATION_MAX_CLOCK_SKEW "1 day"`)
+
+	t.Setenv("VALIDATION_MAX_CLOCK_SKEW", "")
+	t.Setenv("VALIDATION_MAX_BODY_LENGTH", "10")
+	_, err = RulesFromEnv()
+	assert.EqualError(t, err, "VALIDATION_MIN_BODY_LENGTH 20 is greater than VALIDATION_MAX_BODY_LENGTH 10")
+}
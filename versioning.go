@@ -0,0 +1,153 @@
+package main
+
+import (
+	"alibazlamit/feed-provider/apiv1"
+	"alibazlamit/feed-provider/apiv2"
+	"alibazlamit/feed-provider/httpcache"
+	"alibazlamit/feed-provider/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// every versioned response names its version in this header, the writers below pick
+	// the response shape from it
+	API_VERSION_HEADER = "API-Version"
+	API_V1             = "1"
+	API_V2             = apiv2.VERSION
+	// the day the /v1 routes were added, since then the unversioned ones are deprecated
+	UNVERSIONED_DEPRECATED_AT = "2026-10-19"
+	SUNSET_LAYOUT             = "2006-01-02"
+)
+
+// when the unversioned article routes stop answering, read from API_SUNSET, zero while undecided
+var unversionedSunset time.Time
+
+// registerArticleRoutes adds the article routes, once per version and once unversioned.
+// The names are the same under every prefix so scopes, caching and rate limits apply alike.
+func registerArticleRoutes(router *mux.Router) {
+	router.HandleFunc("/articles", getAllArticles).Methods("GET").Name("articles")
+	router.HandleFunc("/articles/stream", streamArticles).Methods("GET").Name("stream")
+	router.HandleFunc("/articles/by-source/{newsArticleID}", getArticleByNewsArticleID).Methods("GET").Name("article-by-source")
+	router.HandleFunc("/articles/by-slug/{slug}", getArticleBySlug).Methods("GET").Name("article-by-slug")
+	router.HandleFunc("/articles/{id}", getArticleByID).Methods("GET").Name("article")
+	router.HandleFunc("/articles/{id}/revisions", getArticleRevisions).Methods("GET").Name("revisions")
+	router.HandleFunc("/articles/{id}/revisions/{rev}", getArticleRevision).Methods("GET").Name("revision")
+	router.HandleFunc("/articles/{id}/diff", getArticleDiff).Methods("GET").Name("diff")
+}
+
+func withAPIVersion(version string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(API_VERSION_HEADER, version)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deprecatedAlias answers with the /v1 shape and points clients at the /v1 route (RFC 9745, RFC 8594)
+func deprecatedAlias(next http.Handler) http.Handler {
+	deprecatedAt, _ := time.Parse(SUNSET_LAYOUT, UNVERSIONED_DEPRECATED_AT)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+		if !unversionedSunset.IsZero() {
+			w.Header().Set("Sunset", unversionedSunset.Format(http.TimeFormat))
+		}
+		w.Header().Add("Link", fmt.Sprintf(`</v1%s>; rel="successor-version"`, r.URL.EscapedPath()))
+		w.Header().Add("Link", `</docs>; rel="deprecation"; type="text/html"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiVersion returns the version the response is written in, the unversioned routes use v1
+func apiVersion(w http.ResponseWriter) string {
+	if version := w.Header().Get(API_VERSION_HEADER); version != "" {
+		return version
+	}
+	return API_V1
+}
+
+func writeArticles(w http.ResponseWriter, articles []models.NewsArticleInformationMongoDB) {
+	if apiVersion(w) == API_V2 {
+		handleSuccess(w, http.StatusOK, apiv2.NewEnvelope(apiv2.FromArticles(articles), len(articles)))
+		return
+	}
+	handleSuccess(w, http.StatusOK, apiv1.ArticlesResponse{
+		Status: string(models.Success),
+		Data:   apiv1.FromArticles(articles),
+	})
+}
+
+func writeArticleData(w http.ResponseWriter, article *models.NewsArticleInformationMongoDB) {
+	if apiVersion(w) == API_V2 {
+		handleSuccess(w, http.StatusOK, apiv2.NewEnvelope(apiv2.FromArticle(article)))
+		return
+	}
+	handleSuccess(w, http.StatusOK, apiv1.ArticleResponse{
+		Status: string(models.Success),
+		Data:   apiv1.FromArticle(article),
+	})
+}
+
+func writeRevisions(w http.ResponseWriter, revisions []models.NewsArticleRevision) {
+	if apiVersion(w) == API_V2 {
+		handleSuccess(w, http.StatusOK, apiv2.NewEnvelope(apiv2.FromRevisions(revisions), len(revisions)))
+		return
+	}
+	handleSuccess(w, http.StatusOK, apiv1.RevisionsResponse{
+		Status: string(models.Success),
+		Data:   apiv1.FromRevisions(revisions),
+	})
+}
+
+func writeRevision(w http.ResponseWriter, revision *models.NewsArticleRevision) {
+	if apiVersion(w) == API_V2 {
+		handleSuccess(w, http.StatusOK, apiv2.NewEnvelope(apiv2.FromRevision(revision)))
+		return
+	}
+	handleSuccess(w, http.StatusOK, apiv1.RevisionResponse{
+		Status: string(models.Success),
+		Data:   apiv1.FromRevision(revision),
+	})
+}
+
+func writeDiff(w http.ResponseWriter, diff models.NewsArticleDiff) {
+	if apiVersion(w) == API_V2 {
+		handleSuccess(w, http.StatusOK, apiv2.NewEnvelope(apiv2.FromDiff(diff)))
+		return
+	}
+	handleSuccess(w, http.StatusOK, apiv1.DiffResponse{
+		Status: string(models.Success),
+		Data:   apiv1.FromDiff(diff),
+	})
+}
+
+func encodeEvent(w http.ResponseWriter, event *models.ArticleEvent) ([]byte, error) {
+	if apiVersion(w) == API_V2 {
+		return json.Marshal(apiv2.FromEvent(event))
+	}
+	return json.Marshal(apiv1.FromEvent(event))
+}
+
+// writeErrorBody writes the failure in the response's version
+func writeErrorBody(w http.ResponseWriter, statusCode int, message string) {
+	var responseObj interface{} = models.NewsArticlesResponse{
+		Error:  message,
+		Status: string(models.Failure),
+	}
+	if apiVersion(w) == API_V2 {
+		responseObj = apiv2.NewError(statusCode, message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", httpcache.NO_STORE)
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(responseObj); err != nil {
+		w.Write([]byte(message))
+	}
+}
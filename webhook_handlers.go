@@ -0,0 +1,140 @@
+package main
+
+import (
+	"alibazlamit/feed-provider/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MIN_WEBHOOK_SECRET_LENGTH = 16
+	WEBHOOK_DELIVERIES_LIMIT  = 100
+)
+
+// createWebhook registers a subscription for article events
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	var request models.WebhookSubscriptionRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid webhook subscription", err)
+		return
+	}
+	if err = validateWebhookRequest(&request); err != nil {
+		handleError(w, r, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	subscription := models.WebhookSubscription{
+		URL:       request.URL,
+		Events:    request.Events,
+		Club:      request.Club,
+		Taxonomy:  request.Taxonomy,
+		Secret:    request.Secret,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = webhookRepository.AddSubscription(r.Context(), &subscription)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error saving webhook subscription", err)
+		return
+	}
+
+	// the secret is never sent back once stored
+	subscription.Secret = ""
+	responseObj := models.WebhookSubscriptionResponse{
+		Status: string(models.Success),
+		Data:   subscription,
+	}
+	handleSuccess(w, http.StatusCreated, responseObj)
+}
+
+func validateWebhookRequest(request *models.WebhookSubscriptionRequest) error {
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if len(request.Secret) < MIN_WEBHOOK_SECRET_LENGTH {
+		return fmt.Errorf("secret must be at least %d characters", MIN_WEBHOOK_SECRET_LENGTH)
+	}
+	for _, eventType := range request.Events {
+		switch eventType {
+		case models.ArticleInserted, models.ArticleUpdated, models.ArticleUnpublished:
+		default:
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
+
+func getWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := webhookRepository.GetSubscriptions(r.Context())
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving webhook subscriptions", err)
+		return
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	responseObj := models.WebhookSubscriptionsResponse{
+		Status: string(models.Success),
+		Data:   subscriptions,
+	}
+	handleSuccess(w, http.StatusOK, responseObj)
+}
+
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid webhook ID", err)
+		return
+	}
+
+	deleted, err := webhookRepository.DeleteSubscription(r.Context(), objectID)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error deleting webhook subscription", err)
+		return
+	}
+	if !deleted {
+		handleError(w, r, http.StatusNotFound, "Webhook not found", fmt.Errorf("webhook %s not found", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWebhookDeliveries returns the most recent delivery attempts of a subscription
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		handleError(w, r, http.StatusBadRequest, "Invalid webhook ID", err)
+		return
+	}
+
+	subscription, err := webhookRepository.GetSubscription(r.Context(), objectID)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving webhook subscription", err)
+		return
+	}
+	if subscription == nil {
+		handleError(w, r, http.StatusNotFound, "Webhook not found", fmt.Errorf("webhook %s not found", id))
+		return
+	}
+
+	deliveries, err := webhookRepository.GetDeliveries(r.Context(), objectID, WEBHOOK_DELIVERIES_LIMIT)
+	if err != nil {
+		handleError(w, r, http.StatusInternalServerError, "Error retrieving webhook deliveries", err)
+		return
+	}
+	responseObj := models.WebhookDeliveriesResponse{
+		Status: string(models.Success),
+		Data:   deliveries,
+	}
+	handleSuccess(w, http.StatusOK, responseObj)
+}
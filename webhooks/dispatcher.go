@@ -0,0 +1,256 @@
+package webhooks
+
+import (
+	"alibazlamit/feed-provider/apiv1"
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/events"
+	"alibazlamit/feed-provider/logging"
+	"alibazlamit/feed-provider/models"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MAX_ATTEMPTS     = 5
+	BASE_BACKOFF     = 2 * time.Second
+	SIGNATURE_HEADER = "X-Webhook-Signature"
+	EVENT_HEADER     = "X-Webhook-Event"
+	EVENT_ID_HEADER  = "X-Webhook-Event-ID"
+	QUEUE_SIZE       = 1000
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Dispatcher delivers article events to every matching webhook subscription,
+// retrying failed deliveries with exponential backoff and logging each attempt.
+// Each subscription has one worker, so its events arrive in order and a slow
+// subscriber holds up nobody else
+type Dispatcher struct {
+	db          database.WebhookRepository
+	logger      *slog.Logger
+	httpClient  HTTPClient
+	maxAttempts int
+	baseBackoff time.Duration
+	queueSize   int
+	queuesMu    sync.Mutex
+	queues      map[primitive.ObjectID]chan queuedEvent
+}
+
+// queuedEvent is an event waiting in a subscription's queue
+type queuedEvent struct {
+	ctx          context.Context
+	subscription models.WebhookSubscription
+	event        models.ArticleEvent
+}
+
+func NewDispatcher(db database.WebhookRepository, logger *slog.Logger, httpClient HTTPClient) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		logger:      logger,
+		httpClient:  httpClient,
+		maxAttempts: MAX_ATTEMPTS,
+		baseBackoff: BASE_BACKOFF,
+		queueSize:   QUEUE_SIZE,
+		queues:      map[primitive.ObjectID]chan queuedEvent{},
+	}
+}
+
+// Run consumes the broker's events until ctx is done, resubscribing from the
+// last seen event whenever the broker drops it for falling behind
+func (d *Dispatcher) Run(ctx context.Context, broker *events.Broker) {
+	var lastEventID uint64
+	for {
+		replay, subscription, cancel := broker.Subscribe(lastEventID)
+		for _, event := range replay {
+			d.Dispatch(ctx, event)
+			lastEventID = event.ID
+		}
+	consume:
+		for {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case event, ok := <-subscription:
+				if !ok {
+					break consume
+				}
+				d.Dispatch(ctx, event)
+				lastEventID = event.ID
+			}
+		}
+	}
+}
+
+// Dispatch queues the event for every subscription that wants it. When a
+// subscription's queue is full the event is logged as a failed delivery
+// instead of stalling the other subscriptions
+func (d *Dispatcher) Dispatch(ctx context.Context, event models.ArticleEvent) {
+	ctx = logging.WithAttrs(ctx, "event_id", event.ID)
+	subscriptions, err := d.db.GetSubscriptions(ctx)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "error loading webhook subscriptions", "error", err)
+		return
+	}
+
+	d.queuesMu.Lock()
+	defer d.queuesMu.Unlock()
+	d.dropDeletedQueues(subscriptions)
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(&event) {
+			continue
+		}
+		select {
+		case d.queue(subscription.ID) <- queuedEvent{ctx: ctx, subscription: subscription, event: event}:
+		default:
+			d.dropDelivery(ctx, subscription, event)
+		}
+	}
+}
+
+// queue returns the subscription's queue, starting its worker on first use,
+// expects queuesMu to be held
+func (d *Dispatcher) queue(subscriptionID primitive.ObjectID) chan queuedEvent {
+	queue, ok := d.queues[subscriptionID]
+	if !ok {
+		queue = make(chan queuedEvent, d.queueSize)
+		d.queues[subscriptionID] = queue
+		go d.work(queue)
+	}
+	return queue
+}
+
+// dropDeletedQueues stops the workers of subscriptions that no longer exist once
+// they have delivered what was queued, expects queuesMu to be held
+func (d *Dispatcher) dropDeletedQueues(subscriptions []models.WebhookSubscription) {
+	current := make(map[primitive.ObjectID]bool, len(subscriptions))
+	for _, subscription := range subscriptions {
+		current[subscription.ID] = true
+	}
+	for id, queue := range d.queues {
+		if !current[id] {
+			close(queue)
+			delete(d.queues, id)
+		}
+	}
+}
+
+// work delivers a subscription's events one after the other, the next event
+// waits until the previous one is delivered or given up
+func (d *Dispatcher) work(queue chan queuedEvent) {
+	for next := range queue {
+		if next.ctx.Err() != nil {
+			continue
+		}
+		d.deliver(next.ctx, next.subscription, next.event)
+	}
+}
+
+func (d *Dispatcher) dropDelivery(ctx context.Context, subscription models.WebhookSubscription, event models.ArticleEvent) {
+	ctx = logging.WithAttrs(ctx, "subscription_id", subscription.ID.Hex())
+	d.logger.WarnContext(ctx, "webhook queue full, dropping event", "url", subscription.URL, "queued", d.queueSize)
+	err := d.db.AddDelivery(ctx, &models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Error:          "delivery queue full",
+		DeliveredAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		d.logger.ErrorContext(ctx, "error logging webhook delivery", "url", subscription.URL, "error", err)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, subscription models.WebhookSubscription, event models.ArticleEvent) bool {
+	ctx = logging.WithAttrs(ctx, "subscription_id", subscription.ID.Hex())
+	body, err := json.Marshal(apiv1.FromEvent(&event))
+	if err != nil {
+		d.logger.ErrorContext(ctx, "error encoding event", "error", err)
+		return false
+	}
+
+	backoff := d.baseBackoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		delivery, retry := d.attempt(ctx, &subscription, &event, body)
+		delivery.Attempt = attempt
+		if err := d.db.AddDelivery(ctx, delivery); err != nil {
+			d.logger.ErrorContext(ctx, "error logging webhook delivery", "url", subscription.URL, "error", err)
+		}
+		if delivery.Success {
+			return true
+		}
+		if !retry || attempt == d.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	d.logger.WarnContext(ctx, "giving up webhook delivery", "url", subscription.URL, "attempts", d.maxAttempts)
+	return false
+}
+
+// attempt posts the event once and reports whether a failure is worth retrying
+func (d *Dispatcher) attempt(ctx context.Context, subscription *models.WebhookSubscription, event *models.ArticleEvent, body []byte) (*models.WebhookDelivery, bool) {
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		DeliveredAt:    time.Now().UTC(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EVENT_HEADER, string(event.Type))
+	req.Header.Set(EVENT_ID_HEADER, strconv.FormatUint(event.ID, 10))
+	req.Header.Set(SIGNATURE_HEADER, Sign(subscription.Secret, body))
+
+	response, err := d.httpClient.Do(req)
+	delivery.DurationMs = time.Since(delivery.DeliveredAt).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, true
+	}
+	defer response.Body.Close()
+
+	delivery.StatusCode = response.StatusCode
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		delivery.Success = true
+		return delivery, false
+	}
+	delivery.Error = fmt.Sprintf("unexpected status %s", response.Status)
+	retry := response.StatusCode >= 500 ||
+		response.StatusCode == http.StatusRequestTimeout ||
+		response.StatusCode == http.StatusTooManyRequests
+	return delivery, retry
+}
+
+// Sign returns the signature header value for a payload, receivers recompute the
+// HMAC-SHA256 of the raw body with their secret and compare
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
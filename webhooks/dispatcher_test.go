@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"alibazlamit/feed-provider/database"
+	"alibazlamit/feed-provider/models"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliverRetriesAndSigns(t *testing.T) {
+	calls := 0
+	var signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		signature = r.Header.Get(SIGNATURE_HEADER)
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockRepo := database.NewMockWebhookRepository()
+	subscription := models.WebhookSubscription{URL: server.URL, Secret: "0123456789abcdef"}
+	mockRepo.AddSubscription(context.Background(), &subscription)
+
+	dispatcher := NewDispatcher(mockRepo, slog.New(slog.DiscardHandler), server.Client())
+	dispatcher.baseBackoff = time.Millisecond
+
+	event := models.ArticleEvent{ID: 7, Type: models.ArticleUpdated, Article: models.NewsArticleInformationMongoDB{Title: "TEST"}}
+	delivered := dispatcher.deliver(context.Background(), subscription, event)
+
+	assert.True(t, delivered)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, Sign(subscription.Secret, body), signature)
+	assert.Equal(t, 2, len(mockRepo.Deliveries))
+	assert.Equal(t, http.StatusServiceUnavailable, mockRepo.Deliveries[0].StatusCode)
+	assert.True(t, mockRepo.Deliveries[1].Success)
+	assert.Equal(t, 2, mockRepo.Deliveries[1].Attempt)
+}
+
+func TestDeliverDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	mockRepo := database.NewMockWebhookRepository()
+	dispatcher := NewDispatcher(mockRepo, slog.New(slog.DiscardHandler), server.Client())
+	dispatcher.baseBackoff = time.Millisecond
+
+	subscription := models.WebhookSubscription{URL: server.URL, Secret: "0123456789abcdef"}
+	delivered := dispatcher.deliver(context.Background(), subscription, models.ArticleEvent{ID: 1, Type: models.ArticleInserted})
+
+	assert.False(t, delivered)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDispatchKeepsOrderPerSubscription(t *testing.T) {
+	var mu sync.Mutex
+	received := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// the first attempt at the insert fails, the update must still wait for its retry
+		if r.Header.Get(EVENT_HEADER) == string(models.ArticleInserted) && len(received) == 0 {
+			received = append(received, "failed")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, r.Header.Get(EVENT_HEADER))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockRepo := database.NewMockWebhookRepository()
+	mockRepo.AddSubscription(context.Background(), &models.WebhookSubscription{URL: server.URL, Secret: "0123456789abcdef"})
+	dispatcher := NewDispatcher(mockRepo, slog.New(slog.DiscardHandler), server.Client())
+	dispatcher.baseBackoff = 20 * time.Millisecond
+
+	dispatcher.Dispatch(context.Background(), models.ArticleEvent{ID: 1, Type: models.ArticleInserted})
+	dispatcher.Dispatch(context.Background(), models.ArticleEvent{ID: 2, Type: models.ArticleUpdated})
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	}, 2*time.Second, 5*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"failed", string(models.ArticleInserted), string(models.ArticleUpdated)}, received)
+}
+
+func TestDispatchDropsEventsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	mockRepo := database.NewMockWebhookRepository()
+	mockRepo.AddSubscription(context.Background(), &models.WebhookSubscription{URL: server.URL, Secret: "0123456789abcdef"})
+	dispatcher := NewDispatcher(mockRepo, slog.New(slog.DiscardHandler), server.Client())
+	dispatcher.queueSize = 1
+
+	// the worker holds the first event, the second waits in the queue, the third doesn't fit
+	dispatcher.Dispatch(context.Background(), models.ArticleEvent{ID: 1, Type: models.ArticleInserted})
+	assert.Eventually(t, func() bool {
+		return len(dispatcher.queues[mockRepo.Subscriptions[0].ID]) == 0
+	}, time.Second, 5*time.Millisecond)
+	dispatcher.Dispatch(context.Background(), models.ArticleEvent{ID: 2, Type: models.ArticleUpdated})
+	dispatcher.Dispatch(context.Background(), models.ArticleEvent{ID: 3, Type: models.ArticleUpdated})
+
+	deliveries, _ := mockRepo.GetDeliveries(context.Background(), mockRepo.Subscriptions[0].ID, 10)
+	if assert.Equal(t, 1, len(deliveries)) {
+		assert.Equal(t, uint64(3), deliveries[0].EventID)
+		assert.False(t, deliveries[0].Success)
+	}
+}
+
+func TestSubscriptionWants(t *testing.T) {
+	subscription := models.WebhookSubscription{
+		Events: []models.SyncOutcome{models.ArticleUnpublished},
+		Club:   "TEST CITY",
+	}
+	unpublished := models.ArticleEvent{Type: models.ArticleUnpublished, Article: models.NewsArticleInformationMongoDB{ClubName: "TEST CITY"}}
+	updated := models.ArticleEvent{Type: models.ArticleUpdated, Article: models.NewsArticleInformationMongoDB{ClubName: "TEST CITY"}}
+	otherClub := models.ArticleEvent{Type: models.ArticleUnpublished, Article: models.NewsArticleInformationMongoDB{ClubName: "OTHER TOWN"}}
+
+	assert.True(t, subscription.Wants(&unpublished))
+	assert.False(t, subscription.Wants(&updated))
+	assert.False(t, subscription.Wants(&otherClub))
+}